@@ -0,0 +1,140 @@
+// Package cache is a read-through cache for songs. Songs are read far more
+// often than they change, so GET /songs/{id} is served from the cache and
+// every write to a song invalidates it.
+//
+// The cache stores encoded songs in a Backend: an in-process LRU, or Redis
+// if several instances of the API should share one cache.
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/davidkuda/lyricsapi/models"
+)
+
+// Backend stores encoded values with a time to live.
+type Backend interface {
+	// Get returns the value of key and false if there is none.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+// Stats are the counters of a cache since it was created.
+type Stats struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+	// Loads is the number of calls to the loader; it is lower than Misses
+	// if concurrent misses for the same song were collapsed.
+	Loads int64 `json:"loads"`
+	// Errors counts failed calls to the backend. The cache degrades to the
+	// loader when the backend fails.
+	Errors int64 `json:"errors"`
+}
+
+// Songs caches songs by their id.
+type Songs struct {
+	backend Backend
+	ttl     time.Duration
+	group   singleflight.Group
+
+	// generation is incremented by every invalidation. A load that started
+	// before an invalidation must not store its (possibly stale) result.
+	generation atomic.Int64
+
+	hits, misses, loads, errors atomic.Int64
+}
+
+func NewSongs(backend Backend, ttl time.Duration) *Songs {
+	return &Songs{backend: backend, ttl: ttl}
+}
+
+// Get returns the song with the given id from the cache, or calls load and
+// caches its result. Concurrent calls for the same id share one load.
+// Errors of load are returned, but not cached.
+//
+// Since the load is shared, it must not be aborted because the caller that
+// happened to start it went away. load therefore gets a context that has
+// the values of ctx, but is never cancelled; it must set its own deadline.
+func (c *Songs) Get(ctx context.Context, id string, load func(ctx context.Context) (models.Song, error)) (models.Song, error) {
+	key := songKey(id)
+
+	if data, ok, err := c.backend.Get(ctx, key); err != nil {
+		c.errors.Add(1)
+	} else if ok {
+		var s models.Song
+		if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&s); err == nil {
+			c.hits.Add(1)
+			return s, nil
+		}
+		c.errors.Add(1)
+	}
+	c.misses.Add(1)
+
+	ch := c.group.DoChan(key, func() (any, error) {
+		c.loads.Add(1)
+		generation := c.generation.Load()
+
+		s, err := load(detached{ctx})
+		if err != nil {
+			return s, err
+		}
+
+		if c.generation.Load() == generation {
+			var buf bytes.Buffer
+			if err := gob.NewEncoder(&buf).Encode(s); err == nil {
+				if err := c.backend.Set(detached{ctx}, key, buf.Bytes(), c.ttl); err != nil {
+					c.errors.Add(1)
+				}
+			}
+		}
+		return s, nil
+	})
+
+	// callers that go away stop waiting, the load goes on for the others
+	select {
+	case res := <-ch:
+		return res.Val.(models.Song), res.Err
+	case <-ctx.Done():
+		return models.Song{}, ctx.Err()
+	}
+}
+
+// detached is a context that keeps the values of its parent but is never
+// cancelled and has no deadline.
+type detached struct{ parent context.Context }
+
+func (d detached) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (d detached) Done() <-chan struct{}       { return nil }
+func (d detached) Err() error                  { return nil }
+func (d detached) Value(key any) any           { return d.parent.Value(key) }
+
+// Invalidate removes the song with the given id from the cache. It must be
+// called after every write to a song.
+func (c *Songs) Invalidate(ctx context.Context, id string) {
+	key := songKey(id)
+	c.generation.Add(1)
+	c.group.Forget(key)
+	if err := c.backend.Delete(ctx, key); err != nil {
+		c.errors.Add(1)
+	}
+}
+
+func (c *Songs) Stats() Stats {
+	return Stats{
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+		Loads:  c.loads.Load(),
+		Errors: c.errors.Load(),
+	}
+}
+
+func songKey(id string) string {
+	return "lyricsapi:song:" + id
+}
@@ -0,0 +1,160 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/davidkuda/lyricsapi/models"
+)
+
+func TestSongsReadThrough(t *testing.T) {
+	ctx := context.Background()
+	c := NewSongs(NewLRU(10), time.Minute)
+
+	var loads int
+	load := func(context.Context) (models.Song, error) {
+		loads++
+		return models.Song{ID: "hold-on", Name: "Hold On", Version: 7}, nil
+	}
+
+	for i := 0; i < 3; i++ {
+		s, err := c.Get(ctx, "hold-on", load)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.Name != "Hold On" || s.Version != 7 {
+			t.Errorf("got %+v", s)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("loaded %d times, want 1", loads)
+	}
+
+	c.Invalidate(ctx, "hold-on")
+	c.Get(ctx, "hold-on", load)
+	if loads != 2 {
+		t.Errorf("loaded %d times after invalidation, want 2", loads)
+	}
+
+	want := Stats{Hits: 2, Misses: 2, Loads: 2}
+	if got := c.Stats(); got != want {
+		t.Errorf("stats = %+v, want %+v", got, want)
+	}
+}
+
+func TestSongsDoesNotCacheErrors(t *testing.T) {
+	ctx := context.Background()
+	c := NewSongs(NewLRU(10), time.Minute)
+	errNotFound := errors.New("not found")
+
+	for i := 0; i < 2; i++ {
+		_, err := c.Get(ctx, "nope", func(context.Context) (models.Song, error) {
+			return models.Song{}, errNotFound
+		})
+		if err != errNotFound {
+			t.Errorf("err = %v, want %v", err, errNotFound)
+		}
+	}
+	if loads := c.Stats().Loads; loads != 2 {
+		t.Errorf("loaded %d times, want 2", loads)
+	}
+}
+
+func TestSongsCollapsesConcurrentMisses(t *testing.T) {
+	ctx := context.Background()
+	c := NewSongs(NewLRU(10), time.Minute)
+
+	var loads atomic.Int64
+	release := make(chan struct{})
+	load := func(context.Context) (models.Song, error) {
+		loads.Add(1)
+		<-release
+		return models.Song{ID: "hold-on"}, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Get(ctx, "hold-on", load)
+		}()
+	}
+
+	// wait until all goroutines missed, then let the single load finish
+	for c.Stats().Misses < 10 {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if n := loads.Load(); n != 1 {
+		t.Errorf("loaded %d times, want 1", n)
+	}
+}
+
+func TestLRU(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2023, 5, 5, 0, 0, 0, 0, time.UTC)
+	c := NewLRU(2)
+	c.now = func() time.Time { return now }
+
+	c.Set(ctx, "a", []byte("a"), time.Minute)
+	c.Set(ctx, "b", []byte("b"), time.Minute)
+	c.Get(ctx, "a") // b is now the least recently used
+	c.Set(ctx, "c", []byte("c"), time.Minute)
+
+	if _, ok, _ := c.Get(ctx, "b"); ok {
+		t.Error("b should have been evicted")
+	}
+	if _, ok, _ := c.Get(ctx, "a"); !ok {
+		t.Error("a should still be cached")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, ok, _ := c.Get(ctx, "a"); ok {
+		t.Error("a should have expired")
+	}
+	if c.Len() != 1 {
+		t.Errorf("len = %d, want 1", c.Len())
+	}
+}
+
+func TestSongsLoadOutlivesCaller(t *testing.T) {
+	lru := NewLRU(10)
+	c := NewSongs(lru, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	release := make(chan struct{})
+	loaded := make(chan error, 1)
+
+	go func() {
+		_, err := c.Get(ctx, "hold-on", func(loadCtx context.Context) (models.Song, error) {
+			<-release
+			loaded <- loadCtx.Err()
+			return models.Song{ID: "hold-on"}, nil
+		})
+		if err != context.Canceled {
+			t.Errorf("caller got %v, want %v", err, context.Canceled)
+		}
+		close(release)
+	}()
+
+	cancel()
+	if err := <-loaded; err != nil {
+		t.Errorf("load context was cancelled with the caller: %v", err)
+	}
+
+	// the result of the load is cached for the next caller
+	for lru.Len() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	s, _ := c.Get(context.Background(), "hold-on", nil)
+	if s.ID != "hold-on" {
+		t.Errorf("got %+v, want the cached song", s)
+	}
+}
@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// LRU is an in-process Backend that holds at most a fixed number of
+// entries and evicts the least recently used one when it is full.
+type LRU struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List // front is the most recently used
+	now      func() time.Time
+}
+
+type lruEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+func NewLRU(capacity int) *LRU {
+	return &LRU{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+func (c *LRU) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	e := el.Value.(*lruEntry)
+	if c.now().After(e.expires) {
+		c.remove(el)
+		return nil, false, nil
+	}
+
+	c.order.MoveToFront(el)
+	return e.value, true, nil
+}
+
+func (c *LRU) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := c.now().Add(ttl)
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*lruEntry)
+		e.value, e.expires = value, expires
+		c.order.MoveToFront(el)
+		return nil
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, value: value, expires: expires})
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+	}
+	return nil
+}
+
+func (c *LRU) Delete(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+	return nil
+}
+
+// Len returns the number of entries, including expired ones that have not
+// been evicted yet.
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *LRU) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*lruEntry).key)
+}
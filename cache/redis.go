@@ -0,0 +1,38 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis is a Backend that shares the cache between all instances of the
+// API. Invalidations of one instance are seen by all others.
+type Redis struct {
+	client *redis.Client
+}
+
+func NewRedis(client *redis.Client) *Redis {
+	return &Redis{client: client}
+}
+
+func (c *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := c.client.Get(ctx, key).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (c *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (c *Redis) Delete(ctx context.Context, key string) error {
+	return c.client.Del(ctx, key).Err()
+}
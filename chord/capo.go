@@ -0,0 +1,101 @@
+package chord
+
+import (
+	"math"
+	"sort"
+)
+
+// Difficulty scores of voicings: one point per finger, more for barres,
+// stretches and playing high up the neck.
+const (
+	barreDifficulty   = 4
+	highDifficulty    = 2
+	unknownDifficulty = 10 // chords without a known voicing
+)
+
+// Difficulty scores how hard v is to play, from 0 for open strings only.
+// Open chords score 2 to 4, barre chords 6 or more.
+func Difficulty(v Voicing) int {
+	score := 0
+	low, high := 99, 0
+	for i, f := range v.Frets {
+		if f <= 0 {
+			continue
+		}
+		low, high = min(low, f), max(high, f)
+		if f != v.Barre || i < v.BarreFrom {
+			score++
+		}
+	}
+	if v.Barre > 0 {
+		score += barreDifficulty
+	}
+	if high > 0 && high-low > 2 {
+		score += high - low - 2
+	}
+	if high > shownFrets {
+		score += highDifficulty
+	}
+	return score
+}
+
+// Shape is a chord and the shape it is played with behind a capo.
+type Shape struct {
+	Chord      string `json:"chord"`
+	Shape      string `json:"shape"`
+	Difficulty int    `json:"difficulty"`
+}
+
+// CapoSuggestion is a way to play a song with a capo. Difficulty is the
+// average difficulty of the shapes, weighted by how often they're played.
+type CapoSuggestion struct {
+	Capo       int     `json:"capo"`
+	Difficulty float64 `json:"difficulty"`
+	Shapes     []Shape `json:"shapes"`
+}
+
+// SuggestCapo scores playing the chords with a capo on each fret from 0 to
+// maxCapo, easiest first. played lists the chords of a song as they are
+// played, with repeats. Chords that aren't chord symbols are ignored.
+func SuggestCapo(in *Instrument, played []string, maxCapo int) []CapoSuggestion {
+	var chords []Chord
+	count := map[string]int{}
+	total := 0
+	for _, name := range played {
+		c, err := Parse(name)
+		if err != nil {
+			continue
+		}
+		if count[name] == 0 {
+			chords = append(chords, c)
+		}
+		count[name]++
+		total++
+	}
+	if total == 0 {
+		return []CapoSuggestion{}
+	}
+
+	suggestions := make([]CapoSuggestion, 0, maxCapo+1)
+	for capo := 0; capo <= maxCapo; capo++ {
+		s := CapoSuggestion{Capo: capo, Shapes: make([]Shape, 0, len(chords))}
+		sum := 0
+		for _, c := range chords {
+			shape := c.Transpose(-capo, c.Flats())
+			difficulty := unknownDifficulty
+			if v, ok := in.Voicing(shape); ok {
+				difficulty = Difficulty(v)
+			}
+			s.Shapes = append(s.Shapes, Shape{Chord: c.String(), Shape: shape.String(), Difficulty: difficulty})
+			sum += difficulty * count[c.String()]
+		}
+		s.Difficulty = math.Round(float64(sum)/float64(total)*10) / 10
+		suggestions = append(suggestions, s)
+	}
+
+	// the lower capo wins a tie, it keeps the sound of the song
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].Difficulty < suggestions[j].Difficulty
+	})
+	return suggestions
+}
@@ -0,0 +1,56 @@
+package chord
+
+import "testing"
+
+func TestDifficulty(t *testing.T) {
+	tests := map[string]int{
+		"Em":   2,
+		"C":    3,
+		"G":    3,
+		"F":    7,
+		"Bm":   7,
+		"Ebm7": 7,
+	}
+	for symbol, want := range tests {
+		c, _ := Parse(symbol)
+		v, ok := Guitar.Voicing(c)
+		if !ok {
+			t.Fatalf("no voicing for %s", symbol)
+		}
+		if got := Difficulty(v); got != want {
+			t.Errorf("Difficulty(%s %v) = %d, want %d", symbol, v.Frets, got, want)
+		}
+	}
+}
+
+func TestSuggestCapo(t *testing.T) {
+	// in Bb, every chord is a barre chord without a capo
+	played := []string{"Bb", "Eb", "F", "Bb", "Gm", "Eb", "F", "Bb", "N.C."}
+	suggestions := SuggestCapo(Guitar, played, 7)
+
+	if len(suggestions) != 8 {
+		t.Fatalf("got %d suggestions, want 8", len(suggestions))
+	}
+	for i := 1; i < len(suggestions); i++ {
+		if suggestions[i].Difficulty < suggestions[i-1].Difficulty {
+			t.Errorf("suggestion %d is easier than suggestion %d", i, i-1)
+		}
+	}
+
+	// with the capo on the 3rd fret, Bb is played as G
+	best := suggestions[0]
+	if best.Capo != 3 {
+		t.Errorf("easiest capo = %d, want 3: %+v", best.Capo, suggestions)
+	}
+	want := []Shape{{"Bb", "G", 3}, {"Eb", "C", 3}, {"F", "D", 3}, {"Gm", "Em", 2}}
+	for i, s := range want {
+		if i >= len(best.Shapes) || best.Shapes[i] != s {
+			t.Errorf("shapes = %+v, want %+v", best.Shapes, want)
+			break
+		}
+	}
+
+	if got := SuggestCapo(Guitar, nil, 7); len(got) != 0 {
+		t.Errorf("SuggestCapo(no chords) = %+v, want none", got)
+	}
+}
@@ -0,0 +1,370 @@
+// Package chord parses chord symbols such as "F#m7b5" or "D/F#" and knows
+// how to play them on the guitar and the ukulele.
+package chord
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalid is returned for strings that are not chord symbols.
+var ErrInvalid = errors.New("invalid chord symbol")
+
+// Chord is a parsed chord symbol. Root and Bass are spelled as written,
+// Suffix is everything between them, e.g. "min7(b5)" for "Cmin7(b5)/Gb".
+type Chord struct {
+	Root   string
+	Suffix string
+	Bass   string // empty unless it is a slash chord
+
+	Third   int  // semitones above the root: 4, 3, 2 or 5 for sus2 and sus4, 0 for power chords
+	Fifth   int  // 7, 6 for a flat and 8 for a sharp fifth
+	Sixth   bool // a sixth instead of a seventh, e.g. "C6" and "C6/9"
+	Seventh int  // 10 for a minor, 11 for a major and 9 for a diminished seventh, or 0
+	Ext     int  // the highest extension: 7, 9, 11, 13, or 0 for triads
+
+	Adds   []int    // added tones without the seventh, e.g. 9 for "add9"
+	Alters []string // altered tensions, e.g. "b9" or "#11"
+}
+
+// notes are the pitch classes of the natural notes.
+var notes = map[byte]int{'C': 0, 'D': 2, 'E': 4, 'F': 5, 'G': 7, 'A': 9, 'B': 11}
+
+var (
+	sharpNames = []string{"C", "C#", "D", "D#", "E", "F", "F#", "G", "G#", "A", "A#", "B"}
+	flatNames  = []string{"C", "Db", "D", "Eb", "E", "F", "Gb", "G", "Ab", "A", "Bb", "B"}
+)
+
+// Parse parses a chord symbol. It understands the common spellings of
+// qualities ("m", "min", "-", "maj7", "M7", "Δ", "°", "ø", "+", "sus"),
+// extensions up to the 13th, added tones, alterations in parentheses or
+// not, and slash chords.
+func Parse(s string) (Chord, error) {
+	c := Chord{Third: 4, Fifth: 7}
+
+	root, rest := splitNote(s)
+	if root == "" {
+		return Chord{}, ErrInvalid
+	}
+	c.Root = root
+
+	// the bass follows the last slash, "C6/9" has none but "C6/9/E" has
+	if i := strings.LastIndex(rest, "/"); i >= 0 {
+		if note, tail := splitNote(rest[i+1:]); note != "" && tail == "" {
+			c.Bass, rest = note, rest[:i]
+		}
+	}
+	c.Suffix = rest
+
+	if err := c.parseSuffix(rest); err != nil {
+		return Chord{}, err
+	}
+	return c, nil
+}
+
+// splitNote splits a note name such as "F#" or "Bb" off the start of s.
+func splitNote(s string) (string, string) {
+	if s == "" {
+		return "", s
+	}
+	if _, ok := notes[s[0]]; !ok {
+		return "", s
+	}
+	n := 1
+	for _, acc := range []string{"#", "b", "♯", "♭"} {
+		if strings.HasPrefix(s[1:], acc) {
+			n += len(acc)
+			break
+		}
+	}
+	return s[:n], s[n:]
+}
+
+// PitchClass returns the pitch class of a note name, 0 for C to 11 for B,
+// or -1 if note is not a note name.
+func PitchClass(note string) int {
+	name, rest := splitNote(note)
+	if name == "" || rest != "" {
+		return -1
+	}
+	pc := notes[name[0]]
+	switch name[1:] {
+	case "#", "♯":
+		pc++
+	case "b", "♭":
+		pc--
+	}
+	return (pc + 12) % 12
+}
+
+// NoteName returns the name of pitch class pc, with flats or sharps.
+func NoteName(pc int, flats bool) string {
+	pc = (pc%12 + 12) % 12
+	if flats {
+		return flatNames[pc]
+	}
+	return sharpNames[pc]
+}
+
+// the tokens of suffixes, longest first where one is a prefix of another
+var suffixTokens = []string{
+	"maj", "Maj", "MA", "ma", "M", "Δ", "^",
+	"min", "mi", "m", "-",
+	"dim", "°", "o", "ø",
+	"aug", "+",
+	"sus2", "sus4", "sus",
+	"add2", "add9", "add4", "add11", "add13",
+	"6/9", "69", "13", "11", "9", "7", "6", "5",
+	"b5", "#5", "b9", "#9", "#11", "b13",
+	"♭5", "♯5", "♭9", "♯9", "♯11", "♭13",
+}
+
+func (c *Chord) parseSuffix(s string) error {
+	// parentheses and commas only group alterations, e.g. "7(b9,#11)"
+	s = strings.NewReplacer("(", "", ")", "", ",", "").Replace(s)
+
+	majorSeventh, dim := false, false
+	first := true
+	for s != "" {
+		token := ""
+		for _, t := range suffixTokens {
+			if strings.HasPrefix(s, t) {
+				token = t
+				break
+			}
+		}
+		// "madd9" is minor with an added ninth, not "ma", a major seventh
+		if strings.HasPrefix(s, "madd") {
+			token = "m"
+		}
+		if token == "" {
+			return ErrInvalid
+		}
+		s = s[len(token):]
+		token = strings.NewReplacer("♭", "b", "♯", "#").Replace(token)
+
+		switch token {
+		case "maj", "Maj", "MA", "ma", "M", "Δ", "^":
+			// "CM" is a major triad, but "CΔ" is a major seventh chord
+			majorSeventh = true
+			if (token == "Δ" || token == "^") && leadingNumber(s) == "" {
+				c.Seventh, c.Ext = 11, 7
+			}
+		case "min", "mi", "m":
+			c.Third = 3
+		case "-":
+			// a leading "-" means minor, later ones flatten, e.g. "7-9"
+			if first {
+				c.Third = 3
+			} else if next := leadingNumber(s); next == "5" || next == "9" || next == "13" {
+				s = "b" + s
+			} else {
+				return ErrInvalid
+			}
+		case "dim", "°", "o":
+			c.Third, c.Fifth = 3, 6
+			dim = true
+		case "ø":
+			c.Third, c.Fifth, c.Seventh, c.Ext = 3, 6, 10, 7
+		case "aug", "+":
+			if next := leadingNumber(s); !first && (next == "5" || next == "9" || next == "11") {
+				s = "#" + s
+			} else {
+				c.Fifth = 8
+			}
+		case "sus2":
+			c.Third = 2
+		case "sus4", "sus":
+			c.Third = 5
+		case "add2", "add9":
+			c.Adds = append(c.Adds, 9)
+		case "add4", "add11":
+			c.Adds = append(c.Adds, 11)
+		case "add13":
+			c.Adds = append(c.Adds, 13)
+		case "6/9", "69":
+			c.Sixth, c.Ext = true, 9
+		case "6":
+			c.Sixth = true
+		case "5":
+			// only "C5" is a power chord, in "Cm7b5" the 5 follows a "b"
+			if !first {
+				return ErrInvalid
+			}
+			c.Third = 0
+		case "7", "9", "11", "13":
+			n, _ := strconv.Atoi(token)
+			c.Ext = n
+			switch {
+			case majorSeventh:
+				c.Seventh = 11
+			case dim && token == "7":
+				// "dim7" and "°7" are diminished sevenths
+				c.Seventh = 9
+			case c.Seventh == 0:
+				c.Seventh = 10
+			}
+		case "b5":
+			c.Fifth = 6
+		case "#5":
+			c.Fifth = 8
+		default: // b9, #9, #11, b13
+			c.Alters = append(c.Alters, token)
+		}
+		first = false
+	}
+
+	if c.Third == 0 && (c.Seventh != 0 || c.Sixth || len(c.Adds) > 0) {
+		return ErrInvalid
+	}
+	return nil
+}
+
+// leadingNumber returns the number at the start of s.
+func leadingNumber(s string) string {
+	n := 0
+	for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+		n++
+	}
+	return s[:n]
+}
+
+// Quality returns the canonical suffix of c, e.g. "m7b5" for "ø" and
+// "min7(b5)", or "maj7" for "Δ". It doesn't include the bass.
+func (c Chord) Quality() string {
+	var b strings.Builder
+
+	dimSeventh := c.Seventh == 9
+	switch {
+	case c.Third == 0:
+		return "5"
+	case c.Third == 3 && c.Fifth == 6 && (c.Seventh == 0 || dimSeventh):
+		b.WriteString("dim")
+	case c.Third == 4 && c.Fifth == 8:
+		b.WriteString("aug")
+	case c.Third == 3:
+		b.WriteString("m")
+	}
+
+	switch {
+	case c.Sixth && c.Ext == 9:
+		b.WriteString("6/9")
+	case c.Sixth:
+		b.WriteString("6")
+	case dimSeventh:
+		b.WriteString("7")
+	case c.Seventh == 11:
+		b.WriteString("maj" + strconv.Itoa(c.Ext))
+	case c.Seventh == 10:
+		b.WriteString(strconv.Itoa(c.Ext))
+	}
+
+	switch c.Third {
+	case 2:
+		b.WriteString("sus2")
+	case 5:
+		b.WriteString("sus4")
+	}
+
+	switch {
+	case c.Fifth == 6 && !(c.Third == 3 && (c.Seventh == 0 || dimSeventh)):
+		b.WriteString("b5")
+	case c.Fifth == 8 && c.Third != 4:
+		b.WriteString("#5")
+	}
+
+	for _, add := range c.Adds {
+		b.WriteString("add" + strconv.Itoa(add))
+	}
+	for _, alter := range c.Alters {
+		b.WriteString(alter)
+	}
+	return b.String()
+}
+
+// Intervals returns the pitch classes of c relative to its root, in
+// ascending order and without the bass.
+func (c Chord) Intervals() []int {
+	set := map[int]bool{0: true, c.Fifth: true}
+	if c.Third != 0 {
+		set[c.Third] = true
+	}
+	if c.Sixth {
+		set[9] = true
+	}
+	if c.Seventh != 0 {
+		set[c.Seventh] = true
+	}
+	if c.Ext >= 9 {
+		set[2] = true
+	}
+	if c.Ext >= 11 {
+		set[5] = true
+	}
+	if c.Ext >= 13 {
+		set[9] = true
+	}
+	for _, add := range c.Adds {
+		set[map[int]int{9: 2, 11: 5, 13: 9}[add]] = true
+	}
+	for _, alter := range c.Alters {
+		interval := map[string]int{"b9": 1, "#9": 3, "#11": 6, "b13": 8}[alter]
+		// an altered tension replaces the natural one
+		delete(set, map[string]int{"b9": 2, "#9": 2, "#11": 5, "b13": 9}[alter])
+		set[interval] = true
+	}
+
+	intervals := make([]int, 0, len(set))
+	for i := range set {
+		intervals = append(intervals, i)
+	}
+	sort.Ints(intervals)
+	return intervals
+}
+
+// PitchClasses returns the pitch classes of the notes of c, including the
+// bass, in ascending order.
+func (c Chord) PitchClasses() []int {
+	root := PitchClass(c.Root)
+	set := map[int]bool{}
+	for _, i := range c.Intervals() {
+		set[(root+i)%12] = true
+	}
+	if c.Bass != "" {
+		set[PitchClass(c.Bass)] = true
+	}
+
+	pcs := make([]int, 0, len(set))
+	for pc := range set {
+		pcs = append(pcs, pc)
+	}
+	sort.Ints(pcs)
+	return pcs
+}
+
+// Flats reports whether c is spelled with flats.
+func (c Chord) Flats() bool {
+	return strings.ContainsAny(c.Root[1:]+c.Bass, "b♭")
+}
+
+// Transpose returns c moved by semitones, spelled with flats or sharps.
+// The suffix is kept as written.
+func (c Chord) Transpose(semitones int, flats bool) Chord {
+	t := c
+	t.Root = NoteName(PitchClass(c.Root)+semitones, flats)
+	if c.Bass != "" {
+		t.Bass = NoteName(PitchClass(c.Bass)+semitones, flats)
+	}
+	return t
+}
+
+// String returns the chord symbol of c, e.g. "D/F#".
+func (c Chord) String() string {
+	s := c.Root + c.Suffix
+	if c.Bass != "" {
+		s += "/" + c.Bass
+	}
+	return s
+}
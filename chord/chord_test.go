@@ -0,0 +1,105 @@
+package chord
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		symbol  string
+		root    string
+		quality string
+		bass    string
+	}{
+		{"C", "C", "", ""},
+		{"Am", "A", "m", ""},
+		{"F#m7b5", "F#", "m7b5", ""},
+		{"Bbø", "Bb", "m7b5", ""},
+		{"Cmin7(b5)", "C", "m7b5", ""},
+		{"EbΔ", "Eb", "maj7", ""},
+		{"CM", "C", "", ""},
+		{"CM7", "C", "maj7", ""},
+		{"Cmaj9", "C", "maj9", ""},
+		{"C-7", "C", "m7", ""},
+		{"CmM7", "C", "mmaj7", ""},
+		{"Cdim", "C", "dim", ""},
+		{"C°7", "C", "dim7", ""},
+		{"C+", "C", "aug", ""},
+		{"C7#5", "C", "aug7", ""},
+		{"C7+5", "C", "aug7", ""},
+		{"Csus", "C", "sus4", ""},
+		{"D7sus4", "D", "7sus4", ""},
+		{"Gadd9", "G", "add9", ""},
+		{"C6/9", "C", "6/9", ""},
+		{"C6/E", "C", "6", "E"},
+		{"E7(b9,#11)", "E", "7b9#11", ""},
+		{"E7-9", "E", "7b9", ""},
+		{"A5", "A", "5", ""},
+		{"D/F#", "D", "", "F#"},
+		{"Am7/G", "A", "m7", "G"},
+	}
+
+	for _, tt := range tests {
+		c, err := Parse(tt.symbol)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", tt.symbol, err)
+			continue
+		}
+		if c.Root != tt.root || c.Quality() != tt.quality || c.Bass != tt.bass {
+			t.Errorf("Parse(%q) = %s %q /%s, want %s %q /%s", tt.symbol, c.Root, c.Quality(), c.Bass, tt.root, tt.quality, tt.bass)
+		}
+		if c.String() != tt.symbol {
+			t.Errorf("Parse(%q).String() = %q", tt.symbol, c.String())
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, s := range []string{"", "H", "am", "Chorus", "Bridge", "C/", "C/X", "Cx", "C5add9", "Cm7/G/"} {
+		if c, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q) = %+v, want an error", s, c)
+		}
+	}
+}
+
+func TestIntervals(t *testing.T) {
+	tests := map[string][]int{
+		"C":        {0, 4, 7},
+		"Cm7b5":    {0, 3, 6, 10},
+		"Cdim7":    {0, 3, 6, 9},
+		"C9":       {0, 2, 4, 7, 10},
+		"C7b9":     {0, 1, 4, 7, 10},
+		"Csus2":    {0, 2, 7},
+		"C6/9":     {0, 2, 4, 7, 9},
+		"Cmaj7":    {0, 4, 7, 11},
+		"C13":      {0, 2, 4, 5, 7, 9, 10},
+		"Cadd9":    {0, 2, 4, 7},
+		"C5":       {0, 7},
+		"Caug":     {0, 4, 8},
+		"Cm(add9)": {0, 2, 3, 7},
+	}
+	for symbol, want := range tests {
+		c, err := Parse(symbol)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", symbol, err)
+		}
+		if got := c.Intervals(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s.Intervals() = %v, want %v", symbol, got, want)
+		}
+	}
+}
+
+func TestTranspose(t *testing.T) {
+	c, _ := Parse("D/F#")
+	if got := c.Transpose(3, true).String(); got != "F/A" {
+		t.Errorf("Transpose(3) = %q, want F/A", got)
+	}
+	c, _ = Parse("Bbm7")
+	if got := c.Transpose(-2, false).String(); got != "G#m7" {
+		t.Errorf("Transpose(-2) = %q, want G#m7", got)
+	}
+	if !c.Flats() {
+		t.Errorf("Bbm7.Flats() = false")
+	}
+}
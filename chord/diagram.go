@@ -0,0 +1,98 @@
+package chord
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// Dimensions of diagrams, in pixels.
+const (
+	stringGap   = 20
+	fretGap     = 24
+	diagramTop  = 56
+	diagramLeft = 32
+	shownFrets  = 5
+)
+
+// Diagram renders v as an SVG fretboard diagram titled name. The strings
+// run from the lowest on the left to the highest on the right. With a
+// capo, the frets of v count from the capo, which is drawn in place of the
+// nut.
+func (in *Instrument) Diagram(name string, v Voicing, capo int) string {
+	n := len(in.Strings)
+	width := diagramLeft*2 + (n-1)*stringGap
+	height := diagramTop + shownFrets*fretGap + 28
+	right := diagramLeft + (n-1)*stringGap
+	bottom := diagramTop + shownFrets*fretGap
+
+	// chords high up the neck are shown from their lowest fret
+	first, high := 1, 0
+	for _, f := range v.Frets {
+		high = max(high, f)
+	}
+	if high > shownFrets {
+		first = 99
+		for _, f := range v.Frets {
+			if f > 0 {
+				first = min(first, f)
+			}
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif">`, width, height, width, height)
+	b.WriteString("\n")
+	fmt.Fprintf(&b, `<title>%s</title>`+"\n", html.EscapeString(name))
+	fmt.Fprintf(&b, `<text x="%d" y="18" font-size="16" text-anchor="middle">%s</text>`+"\n", width/2, html.EscapeString(name))
+
+	// frets, with the nut or the capo at the top
+	for i := 0; i <= shownFrets; i++ {
+		y := diagramTop + i*fretGap
+		fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="black"/>`+"\n", diagramLeft, y, right, y)
+	}
+	switch {
+	case first > 1:
+		fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="11" text-anchor="end">%dfr</text>`+"\n", diagramLeft-6, diagramTop+fretGap/2+4, first)
+	case capo > 0:
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="6" rx="3" fill="dimgray"/>`+"\n", diagramLeft-4, diagramTop-6, right-diagramLeft+8)
+	default:
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="4" fill="black"/>`+"\n", diagramLeft, diagramTop-4, right-diagramLeft)
+	}
+
+	// strings, with open and muted strings marked above them
+	for i, f := range v.Frets {
+		x := diagramLeft + i*stringGap
+		fmt.Fprintf(&b, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="black"/>`+"\n", x, diagramTop, x, bottom)
+		switch {
+		case f < 0:
+			fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="13" text-anchor="middle">×</text>`+"\n", x, diagramTop-10)
+		case f == 0:
+			fmt.Fprintf(&b, `<circle cx="%d" cy="%d" r="5" fill="none" stroke="black"/>`+"\n", x, diagramTop-14)
+		}
+		if capo == 0 {
+			fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="11" text-anchor="middle">%s</text>`+"\n", x, bottom+16, in.Strings[i])
+		}
+	}
+
+	if capo > 0 {
+		fmt.Fprintf(&b, `<text x="%d" y="%d" font-size="11">capo %d</text>`+"\n", diagramLeft-4, bottom+16, capo)
+	}
+
+	if v.Barre > 0 {
+		y := diagramTop + (v.Barre-first)*fretGap + fretGap/2
+		x := diagramLeft + v.BarreFrom*stringGap
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="12" rx="6" fill="black"/>`+"\n", x-6, y-6, right-x+12)
+	}
+	for i, f := range v.Frets {
+		if f <= 0 || f == v.Barre && i >= v.BarreFrom {
+			continue
+		}
+		x := diagramLeft + i*stringGap
+		y := diagramTop + (f-first)*fretGap + fretGap/2
+		fmt.Fprintf(&b, `<circle cx="%d" cy="%d" r="7" fill="black"/>`+"\n", x, y)
+	}
+
+	b.WriteString("</svg>\n")
+	return b.String()
+}
@@ -0,0 +1,191 @@
+package chord
+
+import (
+	"math"
+	"strings"
+)
+
+// Key is a major or minor key.
+type Key struct {
+	Tonic int // pitch class
+	Minor bool
+}
+
+// the usual spelling of the tonics of major and minor keys
+var (
+	majorTonics = []string{"C", "Db", "D", "Eb", "E", "F", "F#", "G", "Ab", "A", "Bb", "B"}
+	minorTonics = []string{"C", "C#", "D", "Eb", "E", "F", "F#", "G", "G#", "A", "Bb", "B"}
+)
+
+// String returns the key in the notation of chords, e.g. "Eb" for E flat
+// major or "F#m" for F sharp minor. Enharmonic keys are spelled the same,
+// "A#" is written as "Bb".
+func (k Key) String() string {
+	if k.Minor {
+		return minorTonics[k.Tonic] + "m"
+	}
+	return majorTonics[k.Tonic]
+}
+
+// Flats reports whether the chords of k are spelled with flats, e.g. in F
+// major or D minor.
+func (k Key) Flats() bool {
+	if strings.Contains(k.String(), "b") {
+		return true
+	}
+	if k.Minor {
+		// D, G, C and F minor, the relatives of F, Bb, Eb and Ab major
+		return k.Tonic == 2 || k.Tonic == 7 || k.Tonic == 0 || k.Tonic == 5
+	}
+	return k.Tonic == 5
+}
+
+// Transpose returns k moved by semitones.
+func (k Key) Transpose(semitones int) Key {
+	k.Tonic = ((k.Tonic+semitones)%12 + 12) % 12
+	return k
+}
+
+// ParseKey parses a key written like a chord ("Am", "Bb") or with its mode
+// ("A minor", "Bb major").
+func ParseKey(s string) (Key, error) {
+	s = strings.TrimSpace(s)
+	minor := false
+	if tonic, mode, ok := strings.Cut(s, " "); ok {
+		switch strings.ToLower(strings.TrimSpace(mode)) {
+		case "major", "maj":
+		case "minor", "min":
+			minor = true
+		default:
+			return Key{}, ErrInvalid
+		}
+		s = tonic
+	}
+
+	c, err := Parse(s)
+	if err != nil || c.Bass != "" {
+		return Key{}, ErrInvalid
+	}
+	switch c.Quality() {
+	case "":
+	case "m":
+		if minor {
+			return Key{}, ErrInvalid
+		}
+		minor = true
+	default:
+		return Key{}, ErrInvalid
+	}
+	return Key{Tonic: PitchClass(c.Root), Minor: minor}, nil
+}
+
+// The key profiles of Krumhansl and Kessler: how well each pitch class,
+// from the tonic up, fits a major or minor key.
+var (
+	majorProfile = []float64{6.35, 2.23, 3.48, 2.33, 4.38, 4.09, 2.52, 5.19, 2.39, 3.66, 2.29, 2.88}
+	minorProfile = []float64{6.33, 2.68, 3.52, 5.38, 2.60, 3.53, 2.54, 4.75, 3.98, 2.69, 3.34, 3.17}
+)
+
+// Weights of the notes of a progression. Roots count more than the other
+// notes of a chord, and songs tend to start and end on the tonic.
+const (
+	rootWeight  = 1.0
+	firstWeight = 1.0
+	lastWeight  = 2.0
+	bassWeight  = 0.5
+
+	// keyTemperature spreads the confidence between similar keys
+	keyTemperature = 0.1
+	// fewer distinct chords than this give less confidence
+	keyEvidence = 4
+)
+
+// DetectKey infers the most likely key of a progression, played lists the
+// chords as they are played, with repeats. It correlates the notes of the
+// chords with the profile of each key and returns the best key with a
+// confidence from 0 to 1. Relative keys such as C and Am share their notes,
+// so they compete and lower the confidence, as do progressions of only a
+// few chords. It returns false if played holds no chords.
+func DetectKey(played []string) (Key, float64, bool) {
+	var hist [12]float64
+	var first, last Chord
+	n := 0
+	distinct := map[string]bool{}
+	for _, name := range played {
+		c, err := Parse(name)
+		if err != nil {
+			continue
+		}
+		distinct[name] = true
+		if n == 0 {
+			first = c
+		}
+		last = c
+		n++
+
+		root := PitchClass(c.Root)
+		for _, i := range c.Intervals() {
+			hist[(root+i)%12]++
+		}
+		hist[root] += rootWeight
+		if c.Bass != "" {
+			hist[PitchClass(c.Bass)] += bassWeight
+		}
+	}
+	if n == 0 {
+		return Key{}, 0, false
+	}
+	hist[PitchClass(first.Root)] += firstWeight
+	hist[PitchClass(last.Root)] += lastWeight
+
+	var best Key
+	bestR := math.Inf(-1)
+	var scores []float64
+	for tonic := 0; tonic < 12; tonic++ {
+		for _, minor := range []bool{false, true} {
+			profile := majorProfile
+			if minor {
+				profile = minorProfile
+			}
+			r := correlate(hist[:], profile, tonic)
+			scores = append(scores, r)
+			if r > bestR {
+				best, bestR = Key{Tonic: tonic, Minor: minor}, r
+			}
+		}
+	}
+
+	// the share of the best key in a softmax over all keys
+	sum := 0.0
+	for _, r := range scores {
+		sum += math.Exp((r - bestR) / keyTemperature)
+	}
+	confidence := 1 / sum
+	if len(distinct) < keyEvidence {
+		confidence *= float64(len(distinct)) / keyEvidence
+	}
+	return best, math.Round(confidence*100) / 100, true
+}
+
+// correlate returns the Pearson correlation of hist with profile rotated
+// to start at tonic.
+func correlate(hist, profile []float64, tonic int) float64 {
+	var meanH, meanP float64
+	for i := 0; i < 12; i++ {
+		meanH += hist[i] / 12
+		meanP += profile[i] / 12
+	}
+
+	var cov, varH, varP float64
+	for i := 0; i < 12; i++ {
+		h := hist[(tonic+i)%12] - meanH
+		p := profile[i] - meanP
+		cov += h * p
+		varH += h * h
+		varP += p * p
+	}
+	if varH == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varH*varP)
+}
@@ -0,0 +1,96 @@
+package chord
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseKey(t *testing.T) {
+	tests := map[string]string{
+		"C":        "C",
+		"Am":       "Am",
+		"A minor":  "Am",
+		"Bb major": "Bb",
+		"A#":       "Bb",
+		"Gb":       "F#",
+		"D#m":      "Ebm",
+		"Dbm":      "C#m",
+	}
+	for s, want := range tests {
+		k, err := ParseKey(s)
+		if err != nil {
+			t.Errorf("ParseKey(%q) failed: %v", s, err)
+			continue
+		}
+		if k.String() != want {
+			t.Errorf("ParseKey(%q) = %s, want %s", s, k, want)
+		}
+	}
+
+	for _, s := range []string{"", "H", "C7", "Am minor", "C dorian", "D/F#"} {
+		if _, err := ParseKey(s); err == nil {
+			t.Errorf("ParseKey(%q) succeeded, want an error", s)
+		}
+	}
+}
+
+func TestDetectKey(t *testing.T) {
+	tests := []struct {
+		progression string
+		key         string
+	}{
+		{"C G Am F C G F C", "C"},
+		{"G D Em C G D C G", "G"},
+		{"Am F C G Am F G Am", "Am"},
+		{"Em C G D Em C D Em", "Em"},
+		{"Bb Eb F Bb Gm Eb F Bb", "Bb"},
+		{"F#m D A E F#m D E F#m", "F#m"},
+		{"E A B7 E C#m A B7 E", "E"},
+		{"Dm Gm A7 Dm Bb Gm A7 Dm", "Dm"},
+	}
+	for _, tt := range tests {
+		k, confidence, ok := DetectKey(strings.Fields(tt.progression))
+		if !ok || k.String() != tt.key {
+			t.Errorf("DetectKey(%s) = %s, want %s", tt.progression, k, tt.key)
+		}
+		if confidence <= 0 || confidence > 1 {
+			t.Errorf("DetectKey(%s) has confidence %v", tt.progression, confidence)
+		}
+	}
+
+	// a single chord is weak evidence of a key
+	if _, confidence, _ := DetectKey([]string{"G", "G"}); confidence >= 0.5 {
+		t.Errorf("DetectKey(G G) has confidence %v, want less than 0.5", confidence)
+	}
+
+	if _, _, ok := DetectKey([]string{"N.C."}); ok {
+		t.Errorf("DetectKey(no chords) found a key")
+	}
+}
+
+func TestKeyTranspose(t *testing.T) {
+	tests := []struct {
+		key       string
+		semitones int
+		want      string
+		flats     bool
+	}{
+		{"C", 5, "F", true},
+		{"G", 2, "A", false},
+		{"Am", -7, "Dm", true},
+		{"E", 6, "Bb", true},
+		{"Bbm", 2, "Cm", true},
+		{"F#m", 1, "Gm", true},
+		{"Em", 0, "Em", false},
+	}
+	for _, tt := range tests {
+		k, err := ParseKey(tt.key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := k.Transpose(tt.semitones)
+		if got.String() != tt.want || got.Flats() != tt.flats {
+			t.Errorf("%s transposed by %d = %s (flats %v), want %s (flats %v)", tt.key, tt.semitones, got, got.Flats(), tt.want, tt.flats)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package chord
+
+import "strings"
+
+// degrees are the scale degrees of the intervals above a tonic, relative
+// to its major scale, as in the Nashville number system.
+var degrees = []string{"1", "b2", "2", "b3", "3", "4", "#4", "5", "b6", "6", "b7", "7"}
+
+var romanNumerals = map[string]string{
+	"1": "I", "2": "II", "3": "III", "4": "IV", "5": "V", "6": "VI", "7": "VII",
+}
+
+// degree returns the scale degree of note in k, e.g. "b7".
+func degree(note string, k Key) string {
+	return degrees[(PitchClass(note)-k.Tonic+12)%12]
+}
+
+// Nashville returns c in the Nashville number system relative to k: the
+// scale degree of the root followed by the quality, e.g. "1", "5m7" or
+// "b7". The bass of slash chords is a degree too, e.g. "2m/4". Minor keys
+// count from their own tonic, so "Am" is "1m" in A minor.
+func (c Chord) Nashville(k Key) string {
+	s := degree(c.Root, k) + c.Quality()
+	if c.Bass != "" {
+		s += "/" + degree(c.Bass, k)
+	}
+	return s
+}
+
+// figures are the figured bass of inversions, by the interval of the bass
+// above the root, for triads and for seventh chords.
+var (
+	triadFigures   = map[int]string{3: "6", 4: "6", 6: "64", 7: "64", 8: "64"}
+	seventhFigures = map[int]string{3: "65", 4: "65", 6: "43", 7: "43", 8: "43", 9: "42", 10: "42", 11: "42"}
+)
+
+// Roman returns c as a Roman numeral relative to k, e.g. "IV", "ii7",
+// "vii°" or "bVII". Minor and diminished chords are lower case. Inversions
+// are written with figured bass, e.g. "I6" for the first inversion of the
+// tonic or "V43" for a dominant seventh over its fifth; other slash chords
+// get the degree of their bass, e.g. "IV/5".
+func (c Chord) Roman(k Key) string {
+	d := degree(c.Root, k)
+	accidental := strings.TrimRight(d, "1234567")
+	numeral := romanNumerals[d[len(accidental):]]
+
+	q := c.Quality()
+	switch {
+	case q == "m7b5":
+		numeral, q = strings.ToLower(numeral), "ø7"
+	case strings.HasPrefix(q, "dim"):
+		numeral, q = strings.ToLower(numeral), "°"+strings.TrimPrefix(q, "dim")
+	case strings.HasPrefix(q, "aug"):
+		q = "+" + strings.TrimPrefix(q, "aug")
+	case strings.HasPrefix(q, "m") && !strings.HasPrefix(q, "maj"):
+		numeral, q = strings.ToLower(numeral), strings.TrimPrefix(q, "m")
+	}
+
+	if c.Bass != "" {
+		interval := (PitchClass(c.Bass) - PitchClass(c.Root) + 12) % 12
+		inChord := interval == c.Third || interval == c.Fifth || interval == c.Seventh
+		switch {
+		case inChord && c.Seventh == 0 && !c.Sixth && q == "":
+			return accidental + numeral + triadFigures[interval]
+		case inChord && strings.HasSuffix(q, "7") && interval != 0:
+			return accidental + numeral + strings.TrimSuffix(q, "7") + seventhFigures[interval]
+		}
+		return accidental + numeral + q + "/" + degree(c.Bass, k)
+	}
+	return accidental + numeral + q
+}
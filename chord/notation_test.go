@@ -0,0 +1,72 @@
+package chord
+
+import "testing"
+
+func TestNashville(t *testing.T) {
+	c, _ := ParseKey("C")
+	am, _ := ParseKey("Am")
+	tests := []struct {
+		symbol string
+		key    Key
+		want   string
+	}{
+		{"C", c, "1"},
+		{"F", c, "4"},
+		{"Gm", c, "5m"},
+		{"G7", c, "57"},
+		{"Bb", c, "b7"},
+		{"Dm/F", c, "2m/4"},
+		{"D/F#", c, "2/#4"},
+		{"Bm7b5", c, "7m7b5"},
+		{"Am", am, "1m"},
+		{"C", am, "b3"},
+		{"E7", am, "57"},
+	}
+	for _, tt := range tests {
+		ch, err := Parse(tt.symbol)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tt.symbol, err)
+		}
+		if got := ch.Nashville(tt.key); got != tt.want {
+			t.Errorf("%s.Nashville(%s) = %q, want %q", tt.symbol, tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestRoman(t *testing.T) {
+	c, _ := ParseKey("C")
+	am, _ := ParseKey("Am")
+	tests := []struct {
+		symbol string
+		key    Key
+		want   string
+	}{
+		{"C", c, "I"},
+		{"Dm", c, "ii"},
+		{"Dm7", c, "ii7"},
+		{"G7", c, "V7"},
+		{"Cmaj7", c, "Imaj7"},
+		{"Bdim", c, "vii°"},
+		{"Bm7b5", c, "viiø7"},
+		{"Bdim7", c, "vii°7"},
+		{"Bb", c, "bVII"},
+		{"Eaug", c, "III+"},
+		{"C/E", c, "I6"},
+		{"C/G", c, "I64"},
+		{"G7/F", c, "V42"},
+		{"G7/D", c, "V43"},
+		{"F/G", c, "IV/5"},
+		{"Am", am, "i"},
+		{"C", am, "bIII"},
+		{"E7", am, "V7"},
+	}
+	for _, tt := range tests {
+		ch, err := Parse(tt.symbol)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tt.symbol, err)
+		}
+		if got := ch.Roman(tt.key); got != tt.want {
+			t.Errorf("%s.Roman(%s) = %q, want %q", tt.symbol, tt.key, got, tt.want)
+		}
+	}
+}
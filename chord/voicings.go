@@ -0,0 +1,322 @@
+package chord
+
+// Instrument is a fretted instrument with its tuning and the shapes it
+// plays chords with.
+type Instrument struct {
+	Name    string
+	Strings []string // the open strings, from the lowest to the highest
+	Tuning  []int    // the pitch classes of the open strings
+
+	// shapes are the voicings of each quality at one root. Voicings for
+	// other roots move a shape up the neck.
+	shapes map[string][]shape
+}
+
+// shape is a voicing of a chord with the given root. Frets are listed
+// from the lowest string, -1 is a muted string and 0 an open one.
+type shape struct {
+	root  string
+	frets []int
+}
+
+// Voicing is a way to play a chord. Frets are listed from the lowest
+// string, -1 is a muted string and 0 an open one. If Barre is not 0, the
+// strings from BarreFrom up are held down at that fret with one finger.
+type Voicing struct {
+	Frets     []int
+	Barre     int
+	BarreFrom int
+}
+
+// Instruments are the instruments that voicings are known for.
+var Instruments = map[string]*Instrument{
+	"guitar":  Guitar,
+	"ukulele": Ukulele,
+}
+
+// Guitar is a six-string guitar in standard tuning.
+var Guitar = &Instrument{
+	Name:    "guitar",
+	Strings: []string{"E", "A", "D", "G", "B", "E"},
+	Tuning:  []int{4, 9, 2, 7, 11, 4},
+	shapes: map[string][]shape{
+		"": {
+			{"E", []int{0, 2, 2, 1, 0, 0}},
+			{"A", []int{-1, 0, 2, 2, 2, 0}},
+			{"C", []int{-1, 3, 2, 0, 1, 0}},
+			{"G", []int{3, 2, 0, 0, 0, 3}},
+			{"D", []int{-1, -1, 0, 2, 3, 2}},
+		},
+		"m": {
+			{"E", []int{0, 2, 2, 0, 0, 0}},
+			{"A", []int{-1, 0, 2, 2, 1, 0}},
+			{"D", []int{-1, -1, 0, 2, 3, 1}},
+		},
+		"5": {
+			{"E", []int{0, 2, 2, -1, -1, -1}},
+			{"A", []int{-1, 0, 2, 2, -1, -1}},
+		},
+		"7": {
+			{"E", []int{0, 2, 0, 1, 0, 0}},
+			{"A", []int{-1, 0, 2, 0, 2, 0}},
+			{"C", []int{-1, 3, 2, 3, 1, 0}},
+			{"G", []int{3, 2, 0, 0, 0, 1}},
+			{"D", []int{-1, -1, 0, 2, 1, 2}},
+			{"B", []int{-1, 2, 1, 2, 0, 2}},
+		},
+		"maj7": {
+			{"E", []int{0, 2, 1, 1, 0, 0}},
+			{"A", []int{-1, 0, 2, 1, 2, 0}},
+			{"C", []int{-1, 3, 2, 0, 0, 0}},
+			{"D", []int{-1, -1, 0, 2, 2, 2}},
+			{"F", []int{-1, -1, 3, 2, 1, 0}},
+		},
+		"m7": {
+			{"E", []int{0, 2, 0, 0, 0, 0}},
+			{"A", []int{-1, 0, 2, 0, 1, 0}},
+			{"D", []int{-1, -1, 0, 2, 1, 1}},
+		},
+		"mmaj7": {
+			{"E", []int{0, 2, 1, 0, 0, 0}},
+			{"A", []int{-1, 0, 2, 1, 1, 0}},
+		},
+		"6": {
+			{"E", []int{0, 2, 2, 1, 2, 0}},
+			{"A", []int{-1, 0, 2, 2, 2, 2}},
+			{"C", []int{-1, 3, 2, 2, 1, 0}},
+		},
+		"m6": {
+			{"E", []int{0, 2, 2, 0, 2, 0}},
+			{"A", []int{-1, 0, 2, 2, 1, 2}},
+		},
+		"9": {
+			{"E", []int{0, 2, 0, 1, 0, 2}},
+			{"C", []int{-1, 3, 2, 3, 3, 3}},
+		},
+		"add9": {
+			{"E", []int{0, 2, 2, 1, 0, 2}},
+			{"C", []int{-1, 3, 2, 0, 3, 0}},
+		},
+		"sus2": {
+			{"A", []int{-1, 0, 2, 2, 0, 0}},
+			{"D", []int{-1, -1, 0, 2, 3, 0}},
+		},
+		"sus4": {
+			{"E", []int{0, 2, 2, 2, 0, 0}},
+			{"A", []int{-1, 0, 2, 2, 3, 0}},
+			{"D", []int{-1, -1, 0, 2, 3, 3}},
+		},
+		"7sus4": {
+			{"E", []int{0, 2, 0, 2, 0, 0}},
+			{"A", []int{-1, 0, 2, 0, 3, 0}},
+		},
+		"dim": {
+			{"E", []int{0, 1, 2, 0, -1, -1}},
+			{"A", []int{-1, 0, 1, 2, 1, -1}},
+		},
+		"dim7": {
+			{"A", []int{-1, 0, 1, 2, 1, 2}},
+			{"D", []int{-1, -1, 0, 1, 0, 1}},
+		},
+		"m7b5": {
+			{"E", []int{0, 1, 2, 0, 3, 0}},
+			{"B", []int{-1, 2, 3, 2, 3, -1}},
+		},
+		"aug": {
+			{"E", []int{0, 3, 2, 1, 1, 0}},
+			{"C", []int{-1, 3, 2, 1, 1, 0}},
+		},
+	},
+}
+
+// Ukulele is a soprano, concert or tenor ukulele in the standard,
+// re-entrant GCEA tuning.
+var Ukulele = &Instrument{
+	Name:    "ukulele",
+	Strings: []string{"G", "C", "E", "A"},
+	Tuning:  []int{7, 0, 4, 9},
+	shapes: map[string][]shape{
+		"": {
+			{"C", []int{0, 0, 0, 3}},
+			{"F", []int{2, 0, 1, 0}},
+			{"G", []int{0, 2, 3, 2}},
+			{"A", []int{2, 1, 0, 0}},
+			{"D", []int{2, 2, 2, 0}},
+		},
+		"m": {
+			{"A", []int{2, 0, 0, 0}},
+			{"D", []int{2, 2, 1, 0}},
+			{"E", []int{0, 4, 3, 2}},
+			{"C", []int{0, 3, 3, 3}},
+			{"G", []int{0, 2, 3, 1}},
+		},
+		"5": {
+			{"C", []int{0, 0, 3, 3}},
+		},
+		"7": {
+			{"C", []int{0, 0, 0, 1}},
+			{"G", []int{0, 2, 1, 2}},
+			{"A", []int{0, 1, 0, 0}},
+			{"D", []int{2, 2, 2, 3}},
+			{"E", []int{1, 2, 0, 2}},
+		},
+		"maj7": {
+			{"C", []int{0, 0, 0, 2}},
+			{"F", []int{2, 4, 1, 3}},
+			{"G", []int{0, 2, 2, 2}},
+		},
+		"m7": {
+			{"A", []int{0, 0, 0, 0}},
+			{"D", []int{2, 2, 1, 3}},
+			{"E", []int{0, 2, 0, 2}},
+		},
+		"6": {
+			{"C", []int{0, 0, 0, 0}},
+			{"F", []int{2, 2, 1, 3}},
+		},
+		"m6": {
+			{"A", []int{2, 4, 2, 3}},
+			{"C", []int{2, 3, 3, 3}},
+		},
+		"add9": {
+			{"C", []int{0, 2, 0, 3}},
+		},
+		"sus2": {
+			{"C", []int{0, 2, 3, 3}},
+			{"D", []int{2, 2, 0, 0}},
+		},
+		"sus4": {
+			{"C", []int{0, 0, 1, 3}},
+			{"D", []int{0, 2, 3, 0}},
+			{"A", []int{2, 2, 0, 0}},
+		},
+		"7sus4": {
+			{"C", []int{0, 0, 1, 1}},
+			{"G", []int{0, 2, 1, 3}},
+		},
+		"dim": {
+			{"C", []int{5, 3, 2, 3}},
+		},
+		"dim7": {
+			{"C", []int{2, 3, 2, 3}},
+		},
+		"m7b5": {
+			{"B", []int{2, 2, 1, 2}},
+			{"C", []int{3, 3, 2, 3}},
+		},
+		"aug": {
+			{"C", []int{1, 0, 0, 3}},
+		},
+	},
+}
+
+// maxReach is how many frets apart the fingers of one hand can be.
+const maxReach = 3
+
+// Voicing returns the easiest known voicing of c on in: an open shape if
+// there is one, otherwise the shape that is played closest to the nut.
+// For slash chords, the bass is put on one of the two lowest strings if it
+// is in reach; otherwise the voicing of the chord without the bass is
+// returned. It returns false if no shape is known for the quality of c.
+func (in *Instrument) Voicing(c Chord) (Voicing, bool) {
+	root := PitchClass(c.Root)
+
+	var best Voicing
+	bestHigh := -1
+	for _, sh := range in.shapes[c.Quality()] {
+		shift := (root - PitchClass(sh.root) + 12) % 12
+
+		v := Voicing{Frets: make([]int, len(sh.frets))}
+		high := 0
+		for i, f := range sh.frets {
+			if f >= 0 {
+				f += shift
+			}
+			v.Frets[i] = f
+			high = max(high, f)
+		}
+		// a moved shape barres the strings that were open
+		for i, f := range sh.frets {
+			if f == 0 && shift > 0 {
+				v.Barre, v.BarreFrom = shift, i
+				break
+			}
+		}
+
+		if bestHigh < 0 || high < bestHigh {
+			best, bestHigh = v, high
+		}
+	}
+	if bestHigh < 0 {
+		return Voicing{}, false
+	}
+
+	if c.Bass != "" && len(in.Strings) == 6 {
+		best = in.withBass(best, PitchClass(c.Bass))
+	}
+	return best, true
+}
+
+// withBass returns v with pc as its lowest note, or v if that is out of
+// reach. The two lowest strings can take the bass.
+func (in *Instrument) withBass(v Voicing, pc int) Voicing {
+	low, high := 99, 0
+	for _, f := range v.Frets {
+		if f > 0 {
+			low, high = min(low, f), max(high, f)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		frets := append([]int(nil), v.Frets...)
+		for j := 0; j < i; j++ {
+			frets[j] = -1
+		}
+
+		// the string may already play the bass
+		if f := frets[i]; f >= 0 && (in.Tuning[i]+f)%12 == pc {
+			return Voicing{Frets: frets, Barre: v.Barre, BarreFrom: max(v.BarreFrom, i)}
+		}
+		for f := 0; f <= 12; f++ {
+			if (in.Tuning[i]+f)%12 != pc {
+				continue
+			}
+			if f > 0 && (max(high, f)-min(low, f) > maxReach) {
+				continue
+			}
+			// a fretted bass can't be played below a barre
+			if v.Barre != 0 && f != 0 && f < v.Barre {
+				continue
+			}
+			frets[i] = f
+			return Voicing{Frets: frets, Barre: v.Barre, BarreFrom: max(v.BarreFrom, i)}
+		}
+	}
+	return v
+}
+
+// Notes returns the pitch classes that v sounds on in, from the lowest
+// string up. Muted strings are left out.
+func (in *Instrument) Notes(v Voicing) []int {
+	var pcs []int
+	for i, f := range v.Frets {
+		if f >= 0 {
+			pcs = append(pcs, (in.Tuning[i]+f)%12)
+		}
+	}
+	return pcs
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,102 @@
+package chord
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestShapes checks that every shape plays its root and only notes of its
+// chord.
+func TestShapes(t *testing.T) {
+	for _, in := range Instruments {
+		for quality, shapes := range in.shapes {
+			for _, sh := range shapes {
+				c, err := Parse(sh.root + quality)
+				if err != nil {
+					t.Fatalf("%s: Parse(%q) failed: %v", in.Name, sh.root+quality, err)
+				}
+				if len(sh.frets) != len(in.Strings) {
+					t.Errorf("%s %s: %d frets for %d strings", in.Name, c, len(sh.frets), len(in.Strings))
+					continue
+				}
+
+				tones := map[int]bool{}
+				for _, pc := range c.PitchClasses() {
+					tones[pc] = true
+				}
+				notes := in.Notes(Voicing{Frets: sh.frets})
+				hasRoot := false
+				for _, pc := range notes {
+					if !tones[pc] {
+						t.Errorf("%s %s %v plays %s", in.Name, c, sh.frets, NoteName(pc, false))
+					}
+					hasRoot = hasRoot || pc == PitchClass(c.Root)
+				}
+				if !hasRoot {
+					t.Errorf("%s %s %v doesn't play the root", in.Name, c, sh.frets)
+				}
+			}
+		}
+	}
+}
+
+func TestVoicing(t *testing.T) {
+	tests := []struct {
+		in     *Instrument
+		symbol string
+		want   Voicing
+	}{
+		{Guitar, "C", Voicing{Frets: []int{-1, 3, 2, 0, 1, 0}}},
+		{Guitar, "F", Voicing{Frets: []int{1, 3, 3, 2, 1, 1}, Barre: 1}},
+		{Guitar, "Bm", Voicing{Frets: []int{-1, 2, 4, 4, 3, 2}, Barre: 2, BarreFrom: 1}},
+		{Guitar, "F#m7b5", Voicing{Frets: []int{2, 3, 4, 2, 5, 2}, Barre: 2}},
+		{Guitar, "D/F#", Voicing{Frets: []int{2, -1, 0, 2, 3, 2}}},
+		{Guitar, "G/B", Voicing{Frets: []int{-1, 2, 0, 0, 0, 3}, BarreFrom: 1}},
+		{Guitar, "C/E", Voicing{Frets: []int{0, 3, 2, 0, 1, 0}}},
+		{Ukulele, "F", Voicing{Frets: []int{2, 0, 1, 0}}},
+		{Ukulele, "Bb", Voicing{Frets: []int{3, 2, 1, 1}, Barre: 1, BarreFrom: 2}},
+	}
+
+	for _, tt := range tests {
+		c, err := Parse(tt.symbol)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tt.symbol, err)
+		}
+		got, ok := tt.in.Voicing(c)
+		if !ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s.Voicing(%s) = %+v, %v, want %+v", tt.in.Name, tt.symbol, got, ok, tt.want)
+		}
+	}
+
+	c, _ := Parse("C13b9")
+	if _, ok := Guitar.Voicing(c); ok {
+		t.Errorf("Voicing(C13b9) found a voicing, want none")
+	}
+}
+
+func TestDiagram(t *testing.T) {
+	c, _ := Parse("F#m7b5")
+	v, _ := Guitar.Voicing(c)
+	svg := Guitar.Diagram(c.String(), v, 0)
+
+	if !strings.HasPrefix(svg, "<svg ") || !strings.HasSuffix(svg, "</svg>\n") {
+		t.Errorf("Diagram() is not an SVG document:\n%s", svg)
+	}
+	if !strings.Contains(svg, "<title>F#m7b5</title>") {
+		t.Errorf("Diagram() has no title")
+	}
+	// the barre at the 2nd fret holds the strings the shape left open
+	if n := strings.Count(svg, `r="7"`); n != 3 {
+		t.Errorf("Diagram() has %d dots, want 3", n)
+	}
+	if n := strings.Count(svg, `rx="6"`); n != 1 {
+		t.Errorf("Diagram() has %d barres, want 1", n)
+	}
+
+	// with a capo, the string names make room for it
+	svg = Guitar.Diagram("D", Voicing{Frets: []int{-1, -1, 0, 2, 3, 2}}, 2)
+	if !strings.Contains(svg, "capo 2") {
+		t.Errorf("Diagram() with a capo doesn't show it:\n%s", svg)
+	}
+}
@@ -7,8 +7,11 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/davidkuda/lyricsapi/dbio"
+	"github.com/davidkuda/lyricsapi/models"
+	"github.com/davidkuda/lyricsapi/validator"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -18,12 +21,19 @@ func main() {
 
 	createUser := flag.String("create-user", "", "The name of the new user")
 	password := flag.String("password", "", "The password of the new user")
+	role := flag.String("role", models.RoleMember, "The role of the new user, or the new role of -set-role: "+strings.Join(models.Roles, ", "))
+	setRole := flag.String("set-role", "", "A user whose role should be changed to -role")
 	deleteUser := flag.String("delete-user", "", "A user that should be removed from the DB")
 	listUsers := flag.Bool("list-users", false, "Bool: List all registered users in DB")
 	flag.Parse()
 
 	if *createUser != "" && *password != "" {
-		create(*createUser, *password, conn)
+		create(*createUser, *password, *role, conn)
+		return
+	}
+
+	if *setRole != "" {
+		updateRole(*setRole, *role, conn)
 		return
 	}
 
@@ -88,9 +98,13 @@ func delete(email string, conn *sql.Conn) {
 	fmt.Println("Deleted user with email", email)
 }
 
-func create(userName, password string, conn *sql.Conn) {
-	if len(password) == 0 {
-		log.Fatal("Make sure to pass a password")
+func create(userName, password, role string, conn *sql.Conn) {
+	v := validator.New()
+	if models.ValidateUser(v, &models.User{Name: userName, Password: password, Role: role}); !v.Valid() {
+		for field, message := range v.Errors {
+			log.Printf("%s: %s", field, message)
+		}
+		log.Fatal("Invalid user, nothing was created")
 	}
 
 	encrPW, err := bcrypt.GenerateFromPassword([]byte(password), 14)
@@ -99,12 +113,12 @@ func create(userName, password string, conn *sql.Conn) {
 	}
 
 	query := `
-		INSERT INTO users (name, password)
-		VALUES ($1, $2)
+		INSERT INTO users (name, password, role)
+		VALUES ($1, $2, $3)
 	`
 
 	ctx := context.Background()
-	res, err := conn.ExecContext(ctx, query, userName, encrPW)
+	res, err := conn.ExecContext(ctx, query, userName, encrPW, role)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -120,18 +134,42 @@ func create(userName, password string, conn *sql.Conn) {
 	fmt.Println("Created user", userName)
 }
 
+func updateRole(userName, role string, conn *sql.Conn) {
+	if !validator.PermittedValue(role, models.Roles...) {
+		log.Fatalf("role must be one of %s", strings.Join(models.Roles, ", "))
+	}
+
+	query := "UPDATE users SET role = $2 WHERE name = $1;"
+
+	ctx := context.Background()
+	res, err := conn.ExecContext(ctx, query, userName, role)
+	if err != nil {
+		log.Fatal("conn.ExecContext: ", err)
+	}
+
+	nRows, err := res.RowsAffected()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if nRows != 1 {
+		log.Fatalf("expected 1 row to be updated, Got: %v", nRows)
+	}
+
+	fmt.Printf("Changed the role of %s to %s\n", userName, role)
+}
+
 func list(conn *sql.Conn) {
 	ctx := context.Background()
-	query := "SELECT name FROM users;"
+	query := "SELECT name, role FROM users;"
 	res, err := conn.QueryContext(ctx, query)
 	if err != nil {
 		log.Fatalf("conn.QueryContext: %v", err)
 	}
 	fmt.Println("")
 	fmt.Println("Currently Registered Users:")
-	var n string
+	var n, role string
 	for res.Next() {
-		res.Scan(&n)
-		fmt.Printf("  - %s\n", n)
+		res.Scan(&n, &role)
+		fmt.Printf("  - %s (%s)\n", n, role)
 	}
 }
@@ -0,0 +1,265 @@
+package dbio
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/davidkuda/lyricsapi/models"
+)
+
+// ErrArtistDoesNotExist is the cause of ErrNotFound errors for artists.
+var ErrArtistDoesNotExist = errors.New("Artist does not exist")
+
+// aliasesColumn selects the aliases of an artist other than its canonical
+// name, joined by newlines. Names never contain newlines, whitespace is
+// collapsed before they are stored.
+const aliasesColumn = `ARRAY_TO_STRING(ARRAY(
+	SELECT al.name FROM artist_aliases al
+	WHERE al.artist_id = a.id AND al.name <> a.name ORDER BY al.name), E'\n')`
+
+// resolveArtist returns the ID and canonical name of the artist with the
+// given name, i.e. with an alias of the same key. If there is none, an
+// artist with that name is created.
+func resolveArtist(ctx context.Context, tx *sql.Tx, name string) (int64, string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	key := models.ArtistKey(name)
+
+	query := `
+		SELECT a.id, a.name
+		FROM artist_aliases al
+		JOIN artists a ON a.id = al.artist_id
+		WHERE al.key = $1;`
+
+	var id int64
+	var canonical string
+	err := tx.QueryRowContext(ctx, query, key).Scan(&id, &canonical)
+	if err == nil {
+		return id, canonical, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, "", err
+	}
+
+	query = "INSERT INTO artists (name, sort_name) VALUES ($1, $2) RETURNING id;"
+	if err := tx.QueryRowContext(ctx, query, name, models.ArtistSortName(name)).Scan(&id); err != nil {
+		return 0, "", err
+	}
+
+	query = "INSERT INTO artist_aliases (key, artist_id, name) VALUES ($1, $2, $3) ON CONFLICT (key) DO NOTHING;"
+	res, err := tx.ExecContext(ctx, query, key, id, name)
+	if err != nil {
+		return 0, "", err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return 0, "", err
+	} else if n == 0 {
+		// a concurrent request created the artist first, use theirs
+		if _, err := tx.ExecContext(ctx, "DELETE FROM artists WHERE id = $1;", id); err != nil {
+			return 0, "", err
+		}
+		return resolveArtist(ctx, tx, name)
+	}
+
+	return id, name, nil
+}
+
+// LinkArtists links the songs that have no artist record yet to one,
+// creating the artists as needed. It returns the number of songs linked.
+// It is run when the API starts, for songs stored before artists existed.
+func LinkArtists(ctx context.Context, db *sql.DB) (int64, error) {
+	names, err := unlinkedArtistNames(ctx, db)
+	if err != nil {
+		return 0, wrap("LinkArtists", err)
+	}
+
+	var linked int64
+	for _, name := range names {
+		n, err := linkArtist(ctx, name, db)
+		if err != nil {
+			return linked, wrap("LinkArtists", err)
+		}
+		linked += n
+	}
+
+	return linked, nil
+}
+
+func unlinkedArtistNames(ctx context.Context, db *sql.DB) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, "SELECT DISTINCT artist FROM songs WHERE artist_id IS NULL ORDER BY artist;")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// linkArtist links the songs with the artist name to the artist record.
+// A song whose artist is spelled differently than the canonical name gets a
+// new version.
+func linkArtist(ctx context.Context, name string, db *sql.DB) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	id, canonical, err := resolveArtist(ctx, tx, name)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `
+		UPDATE songs SET
+			artist_id = $2,
+			artist = $3,
+			version = CASE WHEN artist = $3 THEN version ELSE nextval('song_version_seq') END,
+			updated_at = CASE WHEN artist = $3 THEN updated_at ELSE NOW() END
+		WHERE artist = $1 AND artist_id IS NULL;`
+
+	res, err := tx.ExecContext(ctx, query, name, id, canonical)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return n, tx.Commit()
+}
+
+// ListArtists returns all artists, sorted by their sort names.
+func ListArtists(ctx context.Context, db *sql.DB) ([]models.Artist, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	query := "SELECT a.id, a.name, a.sort_name, " + aliasesColumn + " FROM artists a ORDER BY a.sort_name ASC, a.id ASC;"
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, wrap("ListArtists", err)
+	}
+	defer rows.Close()
+
+	artists := []models.Artist{}
+	for rows.Next() {
+		var a models.Artist
+		if err := rows.Scan(&a.ID, &a.Name, &a.SortName, &stringList{&a.Aliases, "\n"}); err != nil {
+			return nil, wrap("ListArtists", err)
+		}
+		artists = append(artists, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrap("ListArtists", err)
+	}
+
+	return artists, nil
+}
+
+// GetArtist returns the artist with the given ID.
+func GetArtist(ctx context.Context, id int64, db *sql.DB) (models.Artist, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	query := "SELECT a.id, a.name, a.sort_name, " + aliasesColumn + " FROM artists a WHERE a.id = $1;"
+
+	var a models.Artist
+	if err := db.QueryRowContext(ctx, query, id).Scan(&a.ID, &a.Name, &a.SortName, &stringList{&a.Aliases, "\n"}); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Artist{}, notFound("GetArtist", ErrArtistDoesNotExist)
+		}
+		return models.Artist{}, wrap("GetArtist", err)
+	}
+
+	return a, nil
+}
+
+// MergeArtists merges the duplicates into the artist with the given ID:
+// their songs and aliases are moved to it and the duplicates are deleted.
+// It returns the IDs of the songs that were moved, including songs in the
+// trash. If the artist or one of the duplicates does not exist, nothing is
+// merged and an error of kind ErrNotFound is returned.
+func MergeArtists(ctx context.Context, id int64, duplicates []int64, db *sql.DB) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, wrap("MergeArtists", err)
+	}
+	defer tx.Rollback()
+
+	var name string
+	query := "SELECT name FROM artists WHERE id = $1 FOR UPDATE;"
+	if err := tx.QueryRowContext(ctx, query, id).Scan(&name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, notFound("MergeArtists", ErrArtistDoesNotExist)
+		}
+		return nil, wrap("MergeArtists", err)
+	}
+
+	query = `
+		UPDATE songs SET
+			artist_id = $1,
+			artist = $2,
+			version = nextval('song_version_seq'),
+			updated_at = NOW()
+		WHERE artist_id = ANY($3)
+		RETURNING id;`
+
+	rows, err := tx.QueryContext(ctx, query, id, name, duplicates)
+	if err != nil {
+		return nil, wrap("MergeArtists: songs", err)
+	}
+	var songIDs []string
+	for rows.Next() {
+		var songID string
+		if err := rows.Scan(&songID); err != nil {
+			rows.Close()
+			return nil, wrap("MergeArtists: songs", err)
+		}
+		songIDs = append(songIDs, songID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, wrap("MergeArtists: songs", err)
+	}
+
+	query = "UPDATE artist_aliases SET artist_id = $1 WHERE artist_id = ANY($2);"
+	if _, err := tx.ExecContext(ctx, query, id, duplicates); err != nil {
+		return nil, wrap("MergeArtists: aliases", err)
+	}
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM artists WHERE id = ANY($1);", duplicates)
+	if err != nil {
+		return nil, wrap("MergeArtists", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return nil, wrap("MergeArtists", err)
+	}
+	if n != int64(len(duplicates)) {
+		return nil, notFound("MergeArtists", ErrArtistDoesNotExist)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, wrap("MergeArtists", err)
+	}
+	return songIDs, nil
+}
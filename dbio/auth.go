@@ -6,7 +6,6 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"os"
 
 	"github.com/davidkuda/lyricsapi/models"
 
@@ -14,72 +13,69 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-func GetUserByName(name string, db *sql.DB, logger *log.Logger) (*models.User, error) {
-	ctx := context.Background()
-	conn, err := db.Conn(ctx)
-	if err != nil {
-		logger.Printf("sql.Open: %v\n", err)
-	}
-	defer conn.Close()
+// ErrUserDoesNotExist is the cause of ErrNotFound errors for users.
+var ErrUserDoesNotExist = errors.New("User does not exist")
+
+func GetUserByName(ctx context.Context, name string, db *sql.DB, logger *log.Logger) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
 
 	query := `
 	select
 		name,
-		password
+		password,
+		role
 	from users
 	where name = $1`
 
 	var user models.User
-	row := conn.QueryRowContext(context.Background(), query, name)
-
-	if row.Err(); err != nil {
-		logger.Println("conn.QueryRow", err)
-		return &user, errors.New("QueryNotSuccesful")
-	}
-
-	if err := row.Scan(
+	if err := db.QueryRowContext(ctx, query, name).Scan(
 		&user.Name,
 		&user.Password,
+		&user.Role,
 	); err != nil {
-		logger.Println("row.Scan:", err)
-		return nil, err
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, notFound("GetUserByName", ErrUserDoesNotExist)
+		}
+		return nil, wrap("GetUserByName", err)
 	}
 
 	return &user, nil
 }
 
-func CreateNewUser(u *models.User, db *sql.DB, l *log.Logger) error {
-	// TODO: Add a salt
-	// TODO: Check for length
+// CreateNewUser stores u with a hash of its password. It returns an error of
+// kind ErrConflict if the name is taken.
+func CreateNewUser(ctx context.Context, u *models.User, db *sql.DB, l *log.Logger) error {
+	// bcrypt salts the hash itself, the length is checked by models.ValidateUser
 	encrPW, err := bcrypt.GenerateFromPassword([]byte(u.Password), 14)
 	if err != nil {
-		return err
+		return &Error{Op: "CreateNewUser: bcrypt", Err: err}
 	}
 
-	ctx := context.Background()
-	conn, err := db.Conn(ctx)
-	if err != nil {
-		// TODO: implement error logger?
-		fmt.Fprintf(os.Stderr, "sql.Open: %v\n", err)
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	role := u.Role
+	if role == "" {
+		role = models.RoleMember
 	}
-	defer conn.Close()
 
 	query := `
-		INSERT INTO users (name, password)
-		VALUES ($1, $2)
+		INSERT INTO users (name, password, role)
+		VALUES ($1, $2, $3)
 	`
 
-	res, err := conn.ExecContext(ctx, query, u.Name, encrPW)
+	res, err := db.ExecContext(ctx, query, u.Name, encrPW, role)
 	if err != nil {
-		return err
+		return wrap("CreateNewUser", err)
 	}
 
 	nRows, err := res.RowsAffected()
 	if err != nil {
-		return err
+		return wrap("CreateNewUser", err)
 	}
 	if nRows != 1 {
-		return fmt.Errorf("expected 1 row to be inserted, Got: %v", nRows)
+		return &Error{Op: "CreateNewUser", Err: fmt.Errorf("expected 1 row to be inserted, Got: %v", nRows)}
 	}
 
 	return nil
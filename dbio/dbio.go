@@ -3,10 +3,16 @@ package dbio
 import (
 	"database/sql"
 	"net/url"
+	"time"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
+// QueryTimeout bounds every query. The context of the request is cancelled
+// when the client goes away, which aborts the query in Postgres, but a
+// client that waits must not hold a connection forever either.
+var QueryTimeout = 5 * time.Second
+
 // returns a pool of connections to the postgres db according to the args
 func GetDatabaseConn(dbAddr, dbName, dbUser, dbPassword string) (*sql.DB, error) {
 	// "data source name": string of the url to the database
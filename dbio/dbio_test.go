@@ -1,20 +1,51 @@
 package dbio
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
+	"log"
+	"os"
+	"strings"
 	"testing"
+
+	"github.com/davidkuda/lyricsapi/models"
 )
 
+// testDB connects to the database given by the DB_* env vars (see
+// scripts/setup_dev_env.sh) and skips the test if they are not set.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dbAddr := os.Getenv("DB_ADDR")
+	if dbAddr == "" {
+		t.Skip("DB_ADDR not set; skipping database test")
+	}
+
+	db, err := GetDatabaseConn(dbAddr, os.Getenv("DB_NAME"), os.Getenv("DB_USER"), os.Getenv("DB_PASSWORD"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
 func TestListSong(t *testing.T) {
-	songs := ListSongs()
+	db := testDB(t)
+
+	songs, err := ListSongs(context.Background(), db, models.SongSummaryFields, SongFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
 	expected := []string{
 		"Pink Floyd -- Wish You Were Here",
+		"The Rolling Stones -- Start Me Up", // sorted as "Rolling Stones, The"
 		"Sting -- Englishman In New York",
-		"The Rolling Stones -- Start Me Up",
 	}
 	same := true
 	for i, song := range songs {
-		if fmt.Sprintf("%s -- %s", song.Artist, song.SongName) != expected[i] {
+		if fmt.Sprintf("%s -- %s", song.Artist, song.Name) != expected[i] {
 			same = false
 			break
 		}
@@ -25,8 +56,81 @@ func TestListSong(t *testing.T) {
 }
 
 func TestGetSong(t *testing.T) {
-	song := GetSong("Start Me Up")
+	db := testDB(t)
+
+	song, err := GetSong(context.Background(), "start-me-up", models.SongFields, db, log.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
 	if song.Artist != "The Rolling Stones" {
-		t.Errorf("Failed to fetch the song \"Start Me Up\", received %v", song.SongName)
+		t.Errorf("Failed to fetch the song \"Start Me Up\", received %v", song.Name)
+	}
+}
+
+func TestSongFilterWhere(t *testing.T) {
+	filter := SongFilter{
+		Author:   "ann",
+		Tags:     []string{"campfire", "christmas"},
+		Genres:   []string{"folk", "celtic"},
+		Language: "de",
+		Key:      "Am",
+	}
+
+	where, args := filter.where()
+	for _, want := range []string{
+		"deleted_at IS NULL",
+		"(status = $1 OR created_by = $2)",
+		"t.name = ANY($3)",
+		"HAVING COUNT(*) = $4",
+		"genre = ANY($5)",
+		"language = $6",
+		"key = $7",
+	} {
+		if !strings.Contains(where, want) {
+			t.Errorf("where = %q, does not contain %q", where, want)
+		}
+	}
+	if len(args) != 7 || args[3] != 2 {
+		t.Errorf("args = %v", args)
+	}
+
+	where, args = SongFilter{AllStatuses: true}.where()
+	if where != "deleted_at IS NULL" || len(args) != 0 {
+		t.Errorf("where = %q, args = %v; want all songs that aren't deleted", where, args)
+	}
+}
+
+func TestStringListScan(t *testing.T) {
+	var tags []string
+	l := &stringList{&tags, ","}
+
+	if err := l.Scan("campfire,christmas"); err != nil || len(tags) != 2 || tags[1] != "christmas" {
+		t.Errorf("Scan = %v, tags = %v", err, tags)
+	}
+	if err := l.Scan(""); err != nil || tags != nil {
+		t.Errorf("Scan(\"\") = %v, tags = %v; want no tags", err, tags)
+	}
+}
+
+func TestSongBody(t *testing.T) {
+	in := models.Song{
+		Sections:    []models.Section{{Label: "C", Kind: models.SectionChorus, Lines: []models.Line{{Lyrics: "Start me up"}}}},
+		Arrangement: "C C",
+	}
+	value, err := bodyValue(&in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out models.Song
+	if err := (&songBody{&out}).Scan(value); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Sections) != 1 || out.Sections[0].Lines[0].Lyrics != "Start me up" || out.Arrangement != "C C" {
+		t.Errorf("got %+v after a round trip", out)
+	}
+
+	if value, _ := bodyValue(&models.Song{Text: "plain"}); value != nil {
+		t.Errorf("bodyValue of a song without sections = %v, want nil", value)
 	}
 }
@@ -0,0 +1,115 @@
+package dbio
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// The kinds of errors returned by this package. Callers check for them
+// with errors.Is, e.g. errors.Is(err, dbio.ErrNotFound), and map them to
+// HTTP statuses. Errors of other kinds are bugs or unexpected failures.
+var (
+	// ErrNotFound: the row does not exist
+	ErrNotFound = errors.New("not found")
+	// ErrConflict: the write collides with an existing row, e.g. a taken id
+	ErrConflict = errors.New("conflict")
+	// ErrUnavailable: the database can't be reached or did not answer in
+	// time; the request may succeed if it is retried later
+	ErrUnavailable = errors.New("database unavailable")
+)
+
+// Error is the error returned by the functions of this package. Kind is one
+// of the errors above, or nil for unexpected errors; Err is the cause.
+type Error struct {
+	Op   string // the function or query that failed, e.g. "GetSong"
+	Kind error
+	Err  error
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Op)
+	if e.Kind != nil {
+		b.WriteString(": ")
+		b.WriteString(e.Kind.Error())
+	}
+	if e.Err != nil && e.Err != e.Kind {
+		b.WriteString(": ")
+		b.WriteString(e.Err.Error())
+	}
+	return b.String()
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Is makes errors.Is match the kind as well as the cause.
+func (e *Error) Is(target error) bool {
+	return e.Kind != nil && target == e.Kind
+}
+
+// Postgres error codes, see
+// https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+	pgQueryCanceled       = "57014"
+	pgAdminShutdown       = "57P01"
+	pgCannotConnectNow    = "57P03"
+)
+
+// wrap returns err as an *Error with a kind derived from the cause. It
+// returns nil if err is nil and leaves errors of this package untouched.
+func wrap(op string, err error) error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+	return &Error{Op: op, Kind: kindOf(err), Err: err}
+}
+
+// notFound returns an error of kind ErrNotFound with the given cause, e.g.
+// ErrSongDoesNotExist.
+func notFound(op string, cause error) error {
+	return &Error{Op: op, Kind: ErrNotFound, Err: cause}
+}
+
+func kindOf(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch {
+		case pgErr.Code == pgUniqueViolation, pgErr.Code == pgForeignKeyViolation:
+			return ErrConflict
+		case pgErr.Code == pgQueryCanceled, pgErr.Code == pgAdminShutdown, pgErr.Code == pgCannotConnectNow:
+			return ErrUnavailable
+		case strings.HasPrefix(pgErr.Code, "08"), strings.HasPrefix(pgErr.Code, "53"):
+			// connection exceptions and insufficient resources
+			return ErrUnavailable
+		}
+		return nil
+	}
+
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, driver.ErrBadConn),
+		errors.Is(err, sql.ErrConnDone),
+		pgconn.Timeout(err),
+		errors.As(err, &netErr):
+		return ErrUnavailable
+	}
+
+	return nil
+}
@@ -0,0 +1,380 @@
+package dbio
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/davidkuda/lyricsapi/models"
+)
+
+// fakeResult is what the fake driver answers to a statement.
+type fakeResult struct {
+	columns  []string
+	rows     [][]driver.Value
+	affected int64
+	err      error
+}
+
+// fakeDB returns a database whose statements are answered by respond, so
+// that failures can be tested without Postgres.
+func fakeDB(t *testing.T, respond func(query string) fakeResult) *sql.DB {
+	t.Helper()
+	db := sql.OpenDB(fakeConnector{respond})
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+type fakeConnector struct{ respond func(string) fakeResult }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn(c), nil }
+func (c fakeConnector) Driver() driver.Driver                        { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return nil, errors.New("use the connector") }
+
+type fakeConn struct{ respond func(string) fakeResult }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c fakeConn) Close() error                        { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+
+func (c fakeConn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+// CheckNamedValue accepts any argument, e.g. the []int64 of versions.
+func (c fakeConn) CheckNamedValue(*driver.NamedValue) error { return nil }
+
+func (c fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	res := c.respond(query)
+	if res.err != nil {
+		return nil, res.err
+	}
+	return &fakeRows{columns: res.columns, rows: res.rows}, nil
+}
+
+func (c fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	res := c.respond(query)
+	if res.err != nil {
+		return nil, res.err
+	}
+	return driver.RowsAffected(res.affected), nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// artistRow answers the lookup of resolveArtist.
+func artistRow() fakeResult {
+	return fakeResult{columns: []string{"id", "name"}, rows: [][]driver.Value{{int64(1), "The Rolling Stones"}}}
+}
+
+// existsRow answers the SELECT EXISTS query of missingOrModified.
+func existsRow(exists bool) fakeResult {
+	return fakeResult{columns: []string{"exists"}, rows: [][]driver.Value{{exists}}}
+}
+
+func TestKindOf(t *testing.T) {
+	tests := []struct {
+		err  error
+		want error
+	}{
+		{sql.ErrNoRows, ErrNotFound},
+		{&pgconn.PgError{Code: "23505"}, ErrConflict},
+		{&pgconn.PgError{Code: "23503"}, ErrConflict},
+		{&pgconn.PgError{Code: "57014"}, ErrUnavailable},
+		{&pgconn.PgError{Code: "57P01"}, ErrUnavailable},
+		{&pgconn.PgError{Code: "08006"}, ErrUnavailable},
+		{&pgconn.PgError{Code: "53300"}, ErrUnavailable},
+		{&pgconn.PgError{Code: "42P01"}, nil},
+		{context.DeadlineExceeded, ErrUnavailable},
+		{driver.ErrBadConn, ErrUnavailable},
+		{sql.ErrConnDone, ErrUnavailable},
+		{errors.New("boom"), nil},
+	}
+	for _, tt := range tests {
+		if got := kindOf(tt.err); got != tt.want {
+			t.Errorf("kindOf(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	err := notFound("GetSong", ErrSongDoesNotExist)
+	if got, want := err.Error(), "GetSong: not found: Song does not exist"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+
+	err = &Error{Op: "DeleteSong", Kind: ErrPreconditionFailed, Err: ErrPreconditionFailed}
+	if got, want := err.Error(), "DeleteSong: Song has been modified"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestFailurePaths(t *testing.T) {
+	ctx := context.Background()
+	logger := log.New(io.Discard, "", 0)
+	song := &models.Song{ID: "start-me-up", Artist: "The Rolling Stones", Name: "Start Me Up"}
+
+	tests := []struct {
+		name    string
+		respond func(query string) fakeResult
+		call    func(db *sql.DB) error
+		want    []error // errors.Is must hold for each of them
+	}{
+		{
+			name:    "ListSongs unavailable",
+			respond: func(string) fakeResult { return fakeResult{err: &pgconn.PgError{Code: "57P01"}} },
+			call: func(db *sql.DB) error {
+				_, err := ListSongs(ctx, db, models.SongSummaryFields, SongFilter{})
+				return err
+			},
+			want: []error{ErrUnavailable},
+		},
+		{
+			name:    "ListSongs timeout",
+			respond: func(string) fakeResult { return fakeResult{err: context.DeadlineExceeded} },
+			call: func(db *sql.DB) error {
+				_, err := ListSongs(ctx, db, models.SongSummaryFields, SongFilter{})
+				return err
+			},
+			want: []error{ErrUnavailable, context.DeadlineExceeded},
+		},
+		{
+			name:    "GetSong not found",
+			respond: func(string) fakeResult { return fakeResult{columns: []string{"id", "version", "updated_at"}} },
+			call: func(db *sql.DB) error {
+				_, err := GetSong(ctx, "nope", models.SongFields, db, logger)
+				return err
+			},
+			want: []error{ErrNotFound, ErrSongDoesNotExist},
+		},
+		{
+			name: "CreateSong id taken",
+			respond: func(query string) fakeResult {
+				if strings.Contains(query, "FROM artist_aliases") {
+					return artistRow()
+				}
+				return fakeResult{err: &pgconn.PgError{Code: "23505"}}
+			},
+			call: func(db *sql.DB) error { return CreateSong(ctx, song, db, logger) },
+			want: []error{ErrConflict},
+		},
+		{
+			name:    "SongsWithoutKey unavailable",
+			respond: func(string) fakeResult { return fakeResult{err: &pgconn.PgError{Code: "57P01"}} },
+			call: func(db *sql.DB) error {
+				_, err := SongsWithoutKey(ctx, db)
+				return err
+			},
+			want: []error{ErrUnavailable},
+		},
+		{
+			name:    "SetSongKey timeout",
+			respond: func(string) fakeResult { return fakeResult{err: context.DeadlineExceeded} },
+			call: func(db *sql.DB) error {
+				_, err := SetSongKey(ctx, "start-me-up", "C", nil, db)
+				return err
+			},
+			want: []error{ErrUnavailable, context.DeadlineExceeded},
+		},
+		{
+			name: "DeleteSong not found",
+			respond: func(query string) fakeResult {
+				if strings.Contains(query, "EXISTS") {
+					return existsRow(false)
+				}
+				return fakeResult{}
+			},
+			call: func(db *sql.DB) error { return DeleteSong(ctx, "nope", []int64{1}, db, logger) },
+			want: []error{ErrNotFound, ErrSongDoesNotExist},
+		},
+		{
+			name: "DeleteSong modified",
+			respond: func(query string) fakeResult {
+				if strings.Contains(query, "EXISTS") {
+					return existsRow(true)
+				}
+				return fakeResult{}
+			},
+			call: func(db *sql.DB) error { return DeleteSong(ctx, "start-me-up", []int64{1}, db, logger) },
+			want: []error{ErrPreconditionFailed},
+		},
+		{
+			name: "UpdateSong modified",
+			respond: func(query string) fakeResult {
+				switch {
+				case strings.Contains(query, "EXISTS"):
+					return existsRow(true)
+				case strings.Contains(query, "FROM artist_aliases"):
+					return artistRow()
+				}
+				return fakeResult{columns: []string{"version", "updated_at"}}
+			},
+			call: func(db *sql.DB) error {
+				s := *song
+				return UpdateSong(ctx, "start-me-up", &s, []int64{1}, db, logger)
+			},
+			want: []error{ErrPreconditionFailed},
+		},
+		{
+			name: "UpdateSong rename to taken alias",
+			respond: func(query string) fakeResult {
+				switch {
+				case strings.Contains(query, "FROM artist_aliases"):
+					return artistRow()
+				case strings.Contains(query, "UPDATE songs"):
+					return fakeResult{
+						columns: []string{"version", "updated_at", "status"},
+						rows:    [][]driver.Value{{int64(2), time.Now(), "published"}},
+					}
+				case strings.Contains(query, "INSERT INTO song_aliases"):
+					return fakeResult{err: &pgconn.PgError{Code: "23505"}}
+				}
+				return fakeResult{affected: 1}
+			},
+			call: func(db *sql.DB) error {
+				s := *song
+				s.ID = "start-me-up-live"
+				return UpdateSong(ctx, "start-me-up", &s, nil, db, logger)
+			},
+			want: []error{ErrConflict},
+		},
+		{
+			name:    "RestoreSong not in trash",
+			respond: func(string) fakeResult { return fakeResult{columns: []string{"id"}} },
+			call:    func(db *sql.DB) error { return RestoreSong(ctx, "start-me-up", db, logger) },
+			want:    []error{ErrNotFound, ErrSongDoesNotExist},
+		},
+		{
+			name:    "PurgeDeletedSongs unavailable",
+			respond: func(string) fakeResult { return fakeResult{err: &pgconn.PgError{Code: "57P03"}} },
+			call: func(db *sql.DB) error {
+				_, err := PurgeDeletedSongs(ctx, time.Now(), db)
+				return err
+			},
+			want: []error{ErrUnavailable},
+		},
+		{
+			name:    "GetArtist not found",
+			respond: func(string) fakeResult { return fakeResult{columns: []string{"id", "name", "sort_name", "aliases"}} },
+			call: func(db *sql.DB) error {
+				_, err := GetArtist(ctx, 42, db)
+				return err
+			},
+			want: []error{ErrNotFound, ErrArtistDoesNotExist},
+		},
+		{
+			name: "MergeArtists duplicate not found",
+			respond: func(query string) fakeResult {
+				switch {
+				case strings.Contains(query, "FOR UPDATE"):
+					return fakeResult{columns: []string{"name"}, rows: [][]driver.Value{{"The Rolling Stones"}}}
+				case strings.Contains(query, "RETURNING id"):
+					return fakeResult{columns: []string{"id"}, rows: [][]driver.Value{{"start-me-up"}}}
+				}
+				return fakeResult{affected: 1}
+			},
+			call: func(db *sql.DB) error {
+				_, err := MergeArtists(ctx, 1, []int64{2, 3}, db)
+				return err
+			},
+			want: []error{ErrNotFound, ErrArtistDoesNotExist},
+		},
+		{
+			name:    "GetUserByName not found",
+			respond: func(string) fakeResult { return fakeResult{columns: []string{"name", "password", "role"}} },
+			call: func(db *sql.DB) error {
+				_, err := GetUserByName(ctx, "nobody", db, logger)
+				return err
+			},
+			want: []error{ErrNotFound, ErrUserDoesNotExist},
+		},
+		{
+			name:    "CreateNewSession unavailable",
+			respond: func(string) fakeResult { return fakeResult{err: &pgconn.PgError{Code: "08006"}} },
+			call: func(db *sql.DB) error {
+				return CreateNewSession(ctx, models.SessionToken{Token: "t", UserName: "u"}, db)
+			},
+			want: []error{ErrUnavailable},
+		},
+		{
+			name:    "GetSessionToken not found",
+			respond: func(string) fakeResult { return fakeResult{columns: []string{"token", "user_name", "expiry"}} },
+			call: func(db *sql.DB) error {
+				_, err := GetSessionToken(ctx, "t", db)
+				return err
+			},
+			want: []error{ErrNotFound, ErrNoTokenFound},
+		},
+		{
+			name:    "DeleteToken unexpected",
+			respond: func(string) fakeResult { return fakeResult{err: &pgconn.PgError{Code: "42P01"}} },
+			call:    func(db *sql.DB) error { return DeleteToken(ctx, "t", db) },
+			want:    nil,
+		},
+	}
+
+	kinds := []error{ErrNotFound, ErrConflict, ErrUnavailable, ErrPreconditionFailed}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call(fakeDB(t, tt.respond))
+			if err == nil {
+				t.Fatal("got no error")
+			}
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Fatalf("got %T (%v), want *Error", err, err)
+			}
+			for _, want := range tt.want {
+				if !errors.Is(err, want) {
+					t.Errorf("errors.Is(%v, %v) = false", err, want)
+				}
+			}
+			// the error must not claim a kind it doesn't have
+			for _, kind := range kinds {
+				if errors.Is(err, kind) && !containsErr(tt.want, kind) {
+					t.Errorf("errors.Is(%v, %v) = true", err, kind)
+				}
+			}
+		})
+	}
+}
+
+func containsErr(errs []error, target error) bool {
+	for _, err := range errs {
+		if err == target {
+			return true
+		}
+	}
+	return false
+}
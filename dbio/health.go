@@ -0,0 +1,31 @@
+package dbio
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// CheckTables returns an error listing every table in tables that does not
+// exist in the database.
+func CheckTables(ctx context.Context, db *sql.DB, tables []string) error {
+	query := "SELECT to_regclass($1) IS NOT NULL;"
+
+	var missing []string
+	for _, t := range tables {
+		var exists bool
+		if err := db.QueryRowContext(ctx, query, t).Scan(&exists); err != nil {
+			return wrap("CheckTables", err)
+		}
+		if !exists {
+			missing = append(missing, t)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("missing tables: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
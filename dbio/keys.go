@@ -0,0 +1,66 @@
+package dbio
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/davidkuda/lyricsapi/models"
+)
+
+// keyFields are the fields a key is detected from.
+var keyFields = []string{"lyrics", "chords", "sections", "arrangement"}
+
+// SongsWithoutKey returns the songs, including those in the trash, whose
+// key hasn't been detected yet, with the fields the key is detected from.
+func SongsWithoutKey(ctx context.Context, db *sql.DB) (models.Songs, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	var song models.Song
+	columns, dest := songColumns(&song, keyFields)
+
+	rows, err := db.QueryContext(ctx, "SELECT "+columns+" FROM songs WHERE key IS NULL;")
+	if err != nil {
+		return nil, wrap("SongsWithoutKey", err)
+	}
+	defer rows.Close()
+
+	var songs models.Songs
+	for rows.Next() {
+		song = models.Song{}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, wrap("SongsWithoutKey", err)
+		}
+		songs = append(songs, song)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrap("SongsWithoutKey", err)
+	}
+
+	return songs, nil
+}
+
+// SetSongKey stores the detected key of a song, unless a key has been
+// stored in the meantime. It returns false if the song wasn't changed.
+func SetSongKey(ctx context.Context, songID, key string, confidence *float64, db *sql.DB) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE songs SET
+			key = $2,
+			key_confidence = $3,
+			version = nextval('song_version_seq'),
+			updated_at = NOW()
+		WHERE id = $1 AND key IS NULL;`
+
+	res, err := db.ExecContext(ctx, query, songID, key, confidence)
+	if err != nil {
+		return false, wrap("SetSongKey", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, wrap("SetSongKey", err)
+	}
+	return n == 1, nil
+}
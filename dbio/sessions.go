@@ -5,50 +5,42 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/davidkuda/lyricsapi/models"
 )
 
+// ErrNoTokenFound is the cause of ErrNotFound errors for session tokens.
 var ErrNoTokenFound = errors.New("NoTokenFound")
 
-func CreateNewSession(t models.SessionToken, db *sql.DB) error {
-	ctx := context.Background()
-	conn, err := db.Conn(ctx)
-	if err != nil {
-		return fmt.Errorf("sql.Open: %v\n", err)
-	}
-	defer conn.Close()
+func CreateNewSession(ctx context.Context, t models.SessionToken, db *sql.DB) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
 
 	query := `
 		INSERT INTO sessions (token, user_name, expiry)
 		VALUES ($1, $2, $3)
 	`
 
-	res, err := conn.ExecContext(ctx, query, t.Token, t.UserName, t.Expiry)
+	res, err := db.ExecContext(ctx, query, t.Token, t.UserName, t.Expiry)
 	if err != nil {
-		return fmt.Errorf("conn.ExecContext: %v\n", err)
+		return wrap("CreateNewSession", err)
 	}
 
 	nRows, err := res.RowsAffected()
 	if err != nil {
-		return err
+		return wrap("CreateNewSession", err)
 	}
 	if nRows != 1 {
-		return fmt.Errorf("expected 1 row to be inserted, Got: %v", nRows)
+		return &Error{Op: "CreateNewSession", Err: fmt.Errorf("expected 1 row to be inserted, Got: %v", nRows)}
 	}
 
 	return nil
 }
 
-func GetSessionToken(token string, db *sql.DB) (models.SessionToken, error) {
-	t := models.SessionToken{}
-
-	ctx := context.Background()
-	conn, err := db.Conn(ctx)
-	if err != nil {
-		return t, fmt.Errorf("sql.Open: %v\n", err)
-	}
-	defer conn.Close()
+func GetSessionToken(ctx context.Context, token string, db *sql.DB) (models.SessionToken, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
 
 	query := `
 		SELECT token, user_name, expiry
@@ -56,55 +48,67 @@ func GetSessionToken(token string, db *sql.DB) (models.SessionToken, error) {
 		WHERE token = $1;
 	`
 
-	row := conn.QueryRowContext(ctx, query, token)
-
-	if err = row.Scan(&t.Token, &t.UserName, &t.Expiry); err != nil {
-		fmt.Println(err)
-		return t, fmt.Errorf("rows.Scan: %v\n", err)
-	}
-
-	if t.Token == "" {
-		return t, ErrNoTokenFound
+	t := models.SessionToken{}
+	if err := db.QueryRowContext(ctx, query, token).Scan(&t.Token, &t.UserName, &t.Expiry); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return t, notFound("GetSessionToken", ErrNoTokenFound)
+		}
+		return t, wrap("GetSessionToken", err)
 	}
 
 	return t, nil
 }
 
-func DeleteToken(t string, db *sql.DB) error {
-	ctx := context.Background()
-	conn, err := db.Conn(ctx)
-	if err != nil {
-		return fmt.Errorf("sql.Open: %v\n", err)
+// GetSessionUser returns the user of a session and the expiry of the
+// session. It returns an error of kind ErrNotFound if the token is unknown.
+func GetSessionUser(ctx context.Context, token string, db *sql.DB) (*models.User, time.Time, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT u.name, u.role, s.expiry
+		FROM sessions s
+		JOIN users u ON u.name = s.user_name
+		WHERE s.token = $1;
+	`
+
+	var user models.User
+	var expiry time.Time
+	if err := db.QueryRowContext(ctx, query, token).Scan(&user.Name, &user.Role, &expiry); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, time.Time{}, notFound("GetSessionUser", ErrNoTokenFound)
+		}
+		return nil, time.Time{}, wrap("GetSessionUser", err)
 	}
-	defer conn.Close()
+
+	return &user, expiry, nil
+}
+
+func DeleteToken(ctx context.Context, t string, db *sql.DB) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
 
 	query := "DELETE FROM sessions WHERE token = $1;"
 
-	_, err = conn.ExecContext(ctx, query, t)
-	if err != nil {
-		return fmt.Errorf("conn.ExecContext: %v\n", err)
+	if _, err := db.ExecContext(ctx, query, t); err != nil {
+		return wrap("DeleteToken", err)
 	}
 
 	return nil
 }
 
-func DeleteExpiredTokens(db *sql.DB) error {
-	ctx := context.Background()
-	conn, err := db.Conn(ctx)
-	if err != nil {
-		return fmt.Errorf("sql.Open: %v\n", err)
-	}
-	defer conn.Close()
+func DeleteExpiredTokens(ctx context.Context, db *sql.DB) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
 
 	query := `
 		DELETE FROM sessions
 		WHERE expiry < NOW();
 	`
 
-	_, err = conn.ExecContext(ctx, query)
-	if err != nil {
-		return fmt.Errorf("conn.ExecContext: %v\n", err)
+	if _, err := db.ExecContext(ctx, query); err != nil {
+		return wrap("DeleteExpiredTokens", err)
 	}
-	
+
 	return nil
 }
@@ -3,95 +3,294 @@ package dbio
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
-	"os"
+	"strconv"
+	"strings"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 
 	"github.com/davidkuda/lyricsapi/models"
 )
 
+// ErrSongDoesNotExist is the cause of ErrNotFound errors for songs.
 var ErrSongDoesNotExist = errors.New("Song does not exist")
 
-func ListSongs(db *sql.DB) models.Songs {
-	ctx := context.Background()
-	conn, err := db.Conn(ctx)
+// ErrPreconditionFailed is returned by writes with an expected version if
+// the song has been changed in the meantime.
+var ErrPreconditionFailed = errors.New("Song has been modified")
+
+// songColumns returns the columns for the given JSON fields of a song (see
+// models.SongFields) and the destinations to scan them into. The id,
+// version, modification time, status and author are always selected,
+// whether requested or not.
+func songColumns(s *models.Song, fields []string) (string, []any) {
+	columns := []string{"id", "version", "updated_at", "status", "COALESCE(created_by, '')"}
+	dest := []any{&s.ID, &s.Version, &s.UpdatedAt, &s.Status, &s.CreatedBy}
+	bodySelected := false
+
+	for _, f := range fields {
+		switch f {
+		case "artist":
+			columns, dest = append(columns, "artist"), append(dest, &s.Artist)
+		case "artistId":
+			columns, dest = append(columns, "COALESCE(artist_id, 0)"), append(dest, &s.ArtistID)
+		case "name":
+			columns, dest = append(columns, "name"), append(dest, &s.Name)
+		case "genre":
+			columns, dest = append(columns, "COALESCE(genre, '')"), append(dest, &s.Genre)
+		case "language":
+			columns, dest = append(columns, "COALESCE(language, '')"), append(dest, &s.Language)
+		case "key":
+			columns, dest = append(columns, "COALESCE(key, '')"), append(dest, &s.Key)
+		case "keyConfidence":
+			columns, dest = append(columns, "key_confidence"), append(dest, &s.KeyConfidence)
+		case "tags":
+			columns, dest = append(columns, tagsColumn), append(dest, &stringList{&s.Tags, ","})
+		case "lyrics":
+			columns, dest = append(columns, "text"), append(dest, &s.Text)
+		case "chords":
+			columns, dest = append(columns, "chords"), append(dest, &s.Chords)
+		case "copyright":
+			columns, dest = append(columns, "copyright"), append(dest, &s.Copyright)
+		case "ccli":
+			columns, dest = append(columns, "COALESCE(ccli, '')"), append(dest, &s.CCLI)
+		case "sections", "arrangement":
+			if !bodySelected {
+				columns, dest = append(columns, "body"), append(dest, &songBody{s})
+				bodySelected = true
+			}
+		}
+	}
+
+	return strings.Join(columns, ", "), dest
+}
+
+// tagsColumn selects the tags of a song as a comma separated list, sorted.
+// Tags are slugs, so they can't contain commas.
+const tagsColumn = `ARRAY_TO_STRING(ARRAY(
+	SELECT t.name FROM song_tags st JOIN tags t ON t.id = st.tag_id
+	WHERE st.song_id = songs.id ORDER BY t.name), ',')`
+
+// stringList scans a list joined by sep, e.g. the tagsColumn, into a
+// slice.
+type stringList struct {
+	list *[]string
+	sep  string
+}
+
+func (l *stringList) Scan(src any) error {
+	var s string
+	switch v := src.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	case nil:
+	default:
+		return fmt.Errorf("can't scan %T into a list", src)
+	}
+
+	*l.list = nil
+	if s != "" {
+		*l.list = strings.Split(s, l.sep)
+	}
+	return nil
+}
+
+// body is the JSON stored in the body column.
+type body struct {
+	Sections    []models.Section `json:"sections"`
+	Arrangement string           `json:"arrangement,omitempty"`
+}
+
+// songBody scans the body column into the sections and arrangement of a
+// song.
+type songBody struct{ s *models.Song }
+
+func (b *songBody) Scan(src any) error {
+	b.s.Sections, b.s.Arrangement = nil, ""
+
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("can't scan %T into a song body", src)
+	}
+
+	var v body
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	b.s.Sections, b.s.Arrangement = v.Sections, v.Arrangement
+	return nil
+}
+
+// bodyValue returns the value of the body column for s: JSON, or nil if s
+// has no sections.
+func bodyValue(s *models.Song) (any, error) {
+	if len(s.Sections) == 0 {
+		return nil, nil
+	}
+	js, err := json.Marshal(body{Sections: s.Sections, Arrangement: s.Arrangement})
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Unable to connect to database: %v\n", err)
-		os.Exit(1)
+		return nil, err
 	}
-	defer conn.Close()
+	return string(js), nil
+}
+
+// SongFilter restricts the songs listed by ListSongs. The zero value
+// matches all published songs.
+type SongFilter struct {
+	// AllStatuses matches songs of any status, e.g. for editors.
+	AllStatuses bool
+	// Author also matches the songs of any status created by this user.
+	Author string
+	// Status only matches songs with this status, if set.
+	Status string
+	// Tags only matches songs that carry all of these tags, if set.
+	Tags []string
+	// Genres only matches songs of one of these genres, if set, e.g. a
+	// genre and its subgenres (see models.GenreWithSubgenres).
+	Genres []string
+	// Language only matches songs in this language, if set.
+	Language string
+	// ArtistID only matches the songs of this artist, if set.
+	ArtistID int64
+	// Key only matches songs in this key, if set, spelled as by
+	// chord.Key.String.
+	Key string
+}
 
-	query := "SELECT artist, name, id FROM songs ORDER BY artist ASC;"
-	rows, err := conn.QueryContext(ctx, query)
+// where returns the conditions of f and their arguments.
+func (f SongFilter) where() (string, []any) {
+	conds := []string{"deleted_at IS NULL"}
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	switch {
+	case f.AllStatuses:
+	case f.Author != "":
+		conds = append(conds, "(status = "+arg(models.StatusPublished)+" OR created_by = "+arg(f.Author)+")")
+	default:
+		conds = append(conds, "status = "+arg(models.StatusPublished))
+	}
+	if f.Status != "" {
+		conds = append(conds, "status = "+arg(f.Status))
+	}
+	if len(f.Tags) > 0 {
+		conds = append(conds, `songs.id IN (
+			SELECT st.song_id FROM song_tags st JOIN tags t ON t.id = st.tag_id
+			WHERE t.name = ANY(`+arg(f.Tags)+`)
+			GROUP BY st.song_id HAVING COUNT(*) = `+arg(len(f.Tags))+`)`)
+	}
+	if len(f.Genres) > 0 {
+		conds = append(conds, "genre = ANY("+arg(f.Genres)+")")
+	}
+	if f.Language != "" {
+		conds = append(conds, "language = "+arg(f.Language))
+	}
+	if f.ArtistID != 0 {
+		conds = append(conds, "artist_id = "+arg(f.ArtistID))
+	}
+	if f.Key != "" {
+		conds = append(conds, "key = "+arg(f.Key))
+	}
+
+	return strings.Join(conds, " AND "), args
+}
+
+// ListSongs returns the songs that match filter and are not in the trash,
+// sorted by the sort name of the artist and the name of the song. Only the
+// given fields are selected, so a listing of names doesn't pull all the
+// lyrics.
+func ListSongs(ctx context.Context, db *sql.DB, fields []string, filter SongFilter) (models.Songs, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	var song models.Song
+	columns, dest := songColumns(&song, fields)
+	where, args := filter.where()
+
+	query := "SELECT " + columns + " FROM songs WHERE " + where + `
+		ORDER BY COALESCE((SELECT a.sort_name FROM artists a WHERE a.id = songs.artist_id), songs.artist) ASC, songs.name ASC;`
+	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Query failed: %v\n", err)
-		os.Exit(1)
+		return nil, wrap("ListSongs", err)
 	}
+	defer rows.Close()
 
 	var songs models.Songs
 	for rows.Next() {
-		song := models.Song{}
-		rows.Scan(&song.Artist, &song.Name, &song.ID)
+		song = models.Song{}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, wrap("ListSongs", err)
+		}
 		songs = append(songs, song)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, wrap("ListSongs", err)
+	}
 
-	return songs
+	return songs, nil
 }
 
-func GetSong(songID string, db *sql.DB, l *log.Logger) (models.Song, error) {
-	ctx := context.Background()
-	conn, err := db.Conn(ctx)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "sql.Open: %v\n", err)
-	}
-	defer conn.Close()
+// GetSong returns the song with the given id. Only the given fields are
+// selected. Songs in the trash are not found.
+func GetSong(ctx context.Context, songID string, fields []string, db *sql.DB, l *log.Logger) (models.Song, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
 
 	song := models.Song{}
+	columns, dest := songColumns(&song, fields)
 
-	query := `
-		SELECT
-			id,
-			artist,
-			name,
-			text,
-			chords,
-			copyright
-		FROM songs
-		WHERE id = $1`
-
-	row := conn.QueryRowContext(context.Background(), query, songID)
+	query := "SELECT " + columns + " FROM songs WHERE id = $1 AND deleted_at IS NULL;"
 
-	if row.Err(); err != nil {
-		l.Println("conn.QueryRow", err)
-		return song, errors.New("QueryNotSuccesful")
+	if err := db.QueryRowContext(ctx, query, songID).Scan(dest...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Song{}, notFound("GetSong", ErrSongDoesNotExist)
+		}
+		return models.Song{}, wrap("GetSong", err)
 	}
 
-	row.Scan(
-		&song.ID,
-		&song.Artist,
-		&song.Name,
-		&song.Text,
-		&song.Chords,
-		&song.Copyright,
-	)
+	return song, nil
+}
+
+// CreateSong inserts s, as a draft if s.Status is empty. The artist is
+// looked up by name and created if it doesn't exist yet; s.Artist is set to
+// its canonical name. It returns an error of kind ErrConflict if the id is
+// taken.
+func CreateSong(ctx context.Context, s *models.Song, db *sql.DB, l *log.Logger) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
 
-	if len(song.Name) == 0 {
-		return song, ErrSongDoesNotExist
+	if s.Status == "" {
+		s.Status = models.StatusDraft
 	}
 
-	return song, nil
-}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return wrap("CreateSong", err)
+	}
+	defer tx.Rollback()
 
-func CreateSong(s *models.Song, db *sql.DB, l *log.Logger) error {
-	ctx := context.Background()
-	conn, err := db.Conn(ctx)
+	if s.ArtistID, s.Artist, err = resolveArtist(ctx, tx, s.Artist); err != nil {
+		return wrap("CreateSong: artist", err)
+	}
+	body, err := bodyValue(s)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "sql.Open: %v\n", err)
+		return wrap("CreateSong: body", err)
 	}
-	defer conn.Close()
 
 	query := `
 		INSERT INTO songs (
@@ -100,43 +299,215 @@ func CreateSong(s *models.Song, db *sql.DB, l *log.Logger) error {
 			name,
 			text,
 			chords,
-			copyright
-		) VALUES ($1, $2, $3, $4, $5, $6);`
+			copyright,
+			status,
+			created_by,
+			genre,
+			language,
+			artist_id,
+			body,
+			key,
+			key_confidence,
+			ccli
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, ''), NULLIF($10, ''), $11, $12::jsonb, $13, $14, NULLIF($15, ''));`
 
-	if _, err := conn.ExecContext(
-		ctx, query, s.ID, s.Artist, s.Name, s.Text, s.Chords, s.Copyright,
+	if _, err := tx.ExecContext(
+		ctx, query, s.ID, s.Artist, s.Name, s.Text, s.Chords, s.Copyright, s.Status, s.CreatedBy, s.Genre, s.Language, s.ArtistID, body,
+		s.Key, s.KeyConfidence, s.CCLI,
 	); err != nil {
-		l.Println("conn.ExecContext:", err)
-		return err
+		return wrap("CreateSong", err)
 	}
 
-	return nil
+	if err := setSongTags(ctx, tx, s.ID, s.Tags); err != nil {
+		return wrap("CreateSong", err)
+	}
+
+	return wrap("CreateSong", tx.Commit())
 }
 
-func DeleteSong(songID string, db *sql.DB, l *log.Logger) error {
-	ctx := context.Background()
-	conn, err := db.Conn(ctx)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "sql.Open: %v\n", err)
+// setSongTags replaces the tags of a song with tags, creating the tags
+// that don't exist yet.
+func setSongTags(ctx context.Context, tx *sql.Tx, songID string, tags []string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM song_tags WHERE song_id = $1;", songID); err != nil {
+		return err
 	}
-	defer conn.Close()
+	if len(tags) == 0 {
+		return nil
+	}
+
+	query := "INSERT INTO tags (name) SELECT unnest($1::text[]) ON CONFLICT (name) DO NOTHING;"
+	if _, err := tx.ExecContext(ctx, query, tags); err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO song_tags (song_id, tag_id)
+		SELECT $1, id FROM tags WHERE name = ANY($2);`
+	_, err := tx.ExecContext(ctx, query, songID, tags)
+	return err
+}
+
+// DeleteSong moves the song with the given id to the trash, see
+// RestoreSong and PurgeDeletedSongs. If versions is not empty, the song is
+// only deleted if its current version is one of them, otherwise
+// ErrPreconditionFailed is returned.
+func DeleteSong(ctx context.Context, songID string, versions []int64, db *sql.DB, l *log.Logger) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
 
-	query := "DELETE FROM songs WHERE song_id = $1;"
+	// the new version and modification time tell caches that the song is gone
+	query := `
+		UPDATE songs SET
+			deleted_at = NOW(),
+			version = nextval('song_version_seq'),
+			updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL AND ($2::bigint[] IS NULL OR version = ANY($2));`
 
-	res, err := conn.ExecContext(ctx, query, songID)
+	res, err := db.ExecContext(ctx, query, songID, versions)
 	if err != nil {
-		l.Println("conn.ExecContext:", err)
-		return err
+		return wrap("DeleteSong", err)
 	}
 
 	n, err := res.RowsAffected()
 	if err != nil {
-		l.Println("res.RowsAffected:", err)
-		return err
+		return wrap("DeleteSong", err)
 	}
 	if n == 0 {
-		return errors.New("Delete failed: Did not find song with id " + songID)
+		return missingOrModified(ctx, "DeleteSong", songID, db)
 	}
 
 	return nil
 }
+
+// missingOrModified tells why a conditional write to a song affected no
+// rows: the song either does not exist or has a different version.
+func missingOrModified(ctx context.Context, op, songID string, db *sql.DB) error {
+	var exists bool
+	query := "SELECT EXISTS (SELECT 1 FROM songs WHERE id = $1 AND deleted_at IS NULL);"
+	if err := db.QueryRowContext(ctx, query, songID).Scan(&exists); err != nil {
+		return wrap(op, err)
+	}
+	if exists {
+		return &Error{Op: op, Kind: ErrPreconditionFailed, Err: ErrPreconditionFailed}
+	}
+	return notFound(op, ErrSongDoesNotExist)
+}
+
+// SongIDTaken reports whether id is used by a song, including songs in the
+// trash, or by an alias of a renamed song.
+func SongIDTaken(ctx context.Context, id string, db *sql.DB) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT
+			EXISTS (SELECT 1 FROM songs WHERE id = $1)
+			OR EXISTS (SELECT 1 FROM song_aliases WHERE alias = $1);`
+
+	var taken bool
+	if err := db.QueryRowContext(ctx, query, id).Scan(&taken); err != nil {
+		return false, wrap("SongIDTaken", err)
+	}
+
+	return taken, nil
+}
+
+// ResolveSongAlias returns the current ID of a song that was renamed from
+// alias. It returns ErrSongDoesNotExist if alias is not a known alias or if
+// the song is in the trash.
+func ResolveSongAlias(ctx context.Context, alias string, db *sql.DB) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	query := `
+		SELECT a.song_id
+		FROM song_aliases a
+		JOIN songs s ON s.id = a.song_id
+		WHERE a.alias = $1 AND s.deleted_at IS NULL;`
+
+	var songID string
+	err := db.QueryRowContext(ctx, query, alias).Scan(&songID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", notFound("ResolveSongAlias", ErrSongDoesNotExist)
+		}
+		return "", wrap("ResolveSongAlias", err)
+	}
+
+	return songID, nil
+}
+
+// UpdateSong overwrites the song with the given id with s. If s.ID differs
+// from id, the song is renamed and id is kept as an alias of s.ID, so that
+// old links keep working. If versions is not empty, the song is only
+// updated if its current version is one of them, otherwise
+// ErrPreconditionFailed is returned. An empty s.Status keeps the status.
+// The artist is resolved as in CreateSong. The new version, modification
+// time and status are set on s.
+func UpdateSong(ctx context.Context, id string, s *models.Song, versions []int64, db *sql.DB, l *log.Logger) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return wrap("UpdateSong", err)
+	}
+	defer tx.Rollback()
+
+	if s.ArtistID, s.Artist, err = resolveArtist(ctx, tx, s.Artist); err != nil {
+		return wrap("UpdateSong: artist", err)
+	}
+	body, err := bodyValue(s)
+	if err != nil {
+		return wrap("UpdateSong: body", err)
+	}
+
+	query := `
+		UPDATE songs SET
+			id = $2,
+			artist = $3,
+			name = $4,
+			text = $5,
+			chords = $6,
+			copyright = $7,
+			status = COALESCE(NULLIF($9, ''), status),
+			genre = NULLIF($10, ''),
+			language = NULLIF($11, ''),
+			artist_id = $12,
+			body = $13::jsonb,
+			key = $14,
+			key_confidence = $15,
+			ccli = NULLIF($16, ''),
+			version = nextval('song_version_seq'),
+			updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NULL AND ($8::bigint[] IS NULL OR version = ANY($8))
+		RETURNING version, updated_at, status;`
+
+	err = tx.QueryRowContext(
+		ctx, query, id, s.ID, s.Artist, s.Name, s.Text, s.Chords, s.Copyright, versions, s.Status, s.Genre, s.Language, s.ArtistID, body,
+		s.Key, s.KeyConfidence, s.CCLI,
+	).Scan(&s.Version, &s.UpdatedAt, &s.Status)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return missingOrModified(ctx, "UpdateSong", id, db)
+		}
+		return wrap("UpdateSong", err)
+	}
+
+	if s.ID != id {
+		// aliases of id now point to s.ID through ON UPDATE CASCADE. If the
+		// song is renamed back to one of its aliases, that alias is dropped.
+		if _, err := tx.ExecContext(ctx, "DELETE FROM song_aliases WHERE alias = $1;", s.ID); err != nil {
+			return wrap("UpdateSong: delete alias", err)
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO song_aliases (alias, song_id) VALUES ($1, $2);", id, s.ID); err != nil {
+			return wrap("UpdateSong: insert alias", err)
+		}
+	}
+
+	if err := setSongTags(ctx, tx, s.ID, s.Tags); err != nil {
+		return wrap("UpdateSong: tags", err)
+	}
+
+	return wrap("UpdateSong", tx.Commit())
+}
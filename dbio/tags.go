@@ -0,0 +1,47 @@
+package dbio
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/davidkuda/lyricsapi/models"
+)
+
+// ListTags returns the tags of the songs that match filter, with the number
+// of those songs that carry each tag, most used first. Tags of no matching
+// song are left out.
+func ListTags(ctx context.Context, db *sql.DB, filter SongFilter) ([]models.TagCount, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	where, args := filter.where()
+
+	query := `
+		SELECT t.name, COUNT(*)
+		FROM song_tags st
+		JOIN tags t ON t.id = st.tag_id
+		JOIN songs ON songs.id = st.song_id
+		WHERE ` + where + `
+		GROUP BY t.name
+		ORDER BY COUNT(*) DESC, t.name ASC;`
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, wrap("ListTags", err)
+	}
+	defer rows.Close()
+
+	tags := []models.TagCount{}
+	for rows.Next() {
+		var tc models.TagCount
+		if err := rows.Scan(&tc.Tag, &tc.Count); err != nil {
+			return nil, wrap("ListTags", err)
+		}
+		tags = append(tags, tc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrap("ListTags", err)
+	}
+
+	return tags, nil
+}
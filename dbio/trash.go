@@ -0,0 +1,102 @@
+package dbio
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/davidkuda/lyricsapi/models"
+)
+
+// SongsLastModified returns the time of the last write to any song. Songs
+// in the trash are included, so that deleting a song changes the time too.
+func SongsLastModified(ctx context.Context, db *sql.DB) (time.Time, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	var t sql.NullTime
+	if err := db.QueryRowContext(ctx, "SELECT MAX(updated_at) FROM songs;").Scan(&t); err != nil {
+		return time.Time{}, wrap("SongsLastModified", err)
+	}
+
+	return t.Time, nil
+}
+
+// ListDeletedSongs returns the summary of the songs in the trash, most
+// recently deleted first.
+func ListDeletedSongs(ctx context.Context, db *sql.DB) (models.Songs, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	var song models.Song
+	columns, dest := songColumns(&song, models.SongSummaryFields)
+	dest = append(dest, &song.DeletedAt)
+
+	query := "SELECT " + columns + ", deleted_at FROM songs WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC;"
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, wrap("ListDeletedSongs", err)
+	}
+	defer rows.Close()
+
+	var songs models.Songs
+	for rows.Next() {
+		song = models.Song{}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, wrap("ListDeletedSongs", err)
+		}
+		songs = append(songs, song)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrap("ListDeletedSongs", err)
+	}
+
+	return songs, nil
+}
+
+// RestoreSong takes the song with the given id out of the trash. It returns
+// an error of kind ErrNotFound if the song is not in the trash.
+func RestoreSong(ctx context.Context, songID string, db *sql.DB, l *log.Logger) error {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	query := `
+		UPDATE songs SET
+			deleted_at = NULL,
+			version = nextval('song_version_seq'),
+			updated_at = NOW()
+		WHERE id = $1 AND deleted_at IS NOT NULL
+		RETURNING id;`
+
+	var id string
+	if err := db.QueryRowContext(ctx, query, songID).Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return notFound("RestoreSong", ErrSongDoesNotExist)
+		}
+		return wrap("RestoreSong", err)
+	}
+
+	return nil
+}
+
+// PurgeDeletedSongs permanently deletes the songs that were moved to the
+// trash before the given time, along with their aliases. It returns the
+// number of songs deleted.
+func PurgeDeletedSongs(ctx context.Context, before time.Time, db *sql.DB) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeout)
+	defer cancel()
+
+	res, err := db.ExecContext(ctx, "DELETE FROM songs WHERE deleted_at < $1;", before)
+	if err != nil {
+		return 0, wrap("PurgeDeletedSongs", err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, wrap("PurgeDeletedSongs", err)
+	}
+
+	return n, nil
+}
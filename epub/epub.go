@@ -0,0 +1,221 @@
+// Package epub writes EPUB 3 books: a cover, a navigation document with
+// the table of contents and one XHTML page per chapter. An NCX table of
+// contents is included for readers that only know EPUB 2.
+package epub
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strings"
+	"time"
+)
+
+// Book is an EPUB book.
+type Book struct {
+	// Identifier is the unique id of the book, e.g. "urn:uuid:…".
+	Identifier string
+	Title      string
+	// Language is the BCP 47 tag of the language of the book, e.g. "en".
+	Language string
+	Creators []string
+	// Modified is when the book was last changed. It is also the time
+	// of the files in the archive, so that equal books are equal bytes.
+	Modified time.Time
+
+	// Cover is an SVG image. Books without one have no cover page.
+	Cover []byte
+	// Stylesheet is the CSS of all pages.
+	Stylesheet string
+	Chapters   []Chapter
+}
+
+// Chapter is a page of the book.
+type Chapter struct {
+	Title string
+	// Language is the BCP 47 tag of the chapter if it differs from the
+	// book's.
+	Language string
+	// Body is the XHTML content of the body element.
+	Body string
+}
+
+// MediaType is the media type of EPUB files.
+const MediaType = "application/epub+zip"
+
+// Write writes b as an EPUB file to w.
+func (b *Book) Write(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	modified := b.Modified.UTC()
+
+	add := func(name, content string) error {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(f, content)
+		return err
+	}
+
+	type file struct{ name, content string }
+	files := []file{
+		{"META-INF/container.xml", containerXML},
+		{"OEBPS/content.opf", b.packageDocument()},
+		{"OEBPS/nav.xhtml", b.navDocument()},
+		{"OEBPS/toc.ncx", b.ncx()},
+		{"OEBPS/style.css", b.Stylesheet},
+	}
+	if len(b.Cover) > 0 {
+		files = append(files,
+			file{"OEBPS/cover.svg", string(b.Cover)},
+			file{"OEBPS/cover.xhtml", b.page(b.Title, "", `<div class="cover"><img src="cover.svg" alt="`+Escape(b.Title)+`"/></div>`)},
+		)
+	}
+	for i, c := range b.Chapters {
+		files = append(files, file{"OEBPS/" + chapterFile(i), b.page(c.Title, c.Language, c.Body)})
+	}
+
+	// the mimetype must come first, uncompressed and without extra fields,
+	// so that its media type is at a fixed offset of the file
+	mimetype, err := zw.CreateRaw(&zip.FileHeader{
+		Name:               "mimetype",
+		Method:             zip.Store,
+		CRC32:              crc32.ChecksumIEEE([]byte(MediaType)),
+		CompressedSize64:   uint64(len(MediaType)),
+		UncompressedSize64: uint64(len(MediaType)),
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(mimetype, MediaType); err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := add(f.name, f.content); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+const containerXML = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+`
+
+func chapterFile(i int) string {
+	return fmt.Sprintf("chapter-%03d.xhtml", i+1)
+}
+
+// packageDocument returns the metadata of the book and the list and order
+// of its files.
+func (b *Book) packageDocument() string {
+	var s strings.Builder
+	s.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	fmt.Fprintf(&s, `<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id" xml:lang="%s">`+"\n", Escape(b.Language))
+
+	s.WriteString(`  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">` + "\n")
+	fmt.Fprintf(&s, "    <dc:identifier id=\"book-id\">%s</dc:identifier>\n", Escape(b.Identifier))
+	fmt.Fprintf(&s, "    <dc:title>%s</dc:title>\n", Escape(b.Title))
+	fmt.Fprintf(&s, "    <dc:language>%s</dc:language>\n", Escape(b.Language))
+	for _, c := range b.Creators {
+		fmt.Fprintf(&s, "    <dc:creator>%s</dc:creator>\n", Escape(c))
+	}
+	fmt.Fprintf(&s, "    <meta property=\"dcterms:modified\">%s</meta>\n", b.Modified.UTC().Format("2006-01-02T15:04:05Z"))
+	if len(b.Cover) > 0 {
+		// EPUB 2 readers find the cover this way
+		s.WriteString(`    <meta name="cover" content="cover-image"/>` + "\n")
+	}
+	s.WriteString("  </metadata>\n")
+
+	s.WriteString("  <manifest>\n")
+	s.WriteString(`    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>` + "\n")
+	s.WriteString(`    <item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>` + "\n")
+	s.WriteString(`    <item id="style" href="style.css" media-type="text/css"/>` + "\n")
+	if len(b.Cover) > 0 {
+		s.WriteString(`    <item id="cover-image" href="cover.svg" media-type="image/svg+xml" properties="cover-image"/>` + "\n")
+		s.WriteString(`    <item id="cover" href="cover.xhtml" media-type="application/xhtml+xml"/>` + "\n")
+	}
+	for i := range b.Chapters {
+		fmt.Fprintf(&s, "    <item id=\"chapter-%03d\" href=\"%s\" media-type=\"application/xhtml+xml\"/>\n", i+1, chapterFile(i))
+	}
+	s.WriteString("  </manifest>\n")
+
+	s.WriteString(`  <spine toc="ncx">` + "\n")
+	if len(b.Cover) > 0 {
+		s.WriteString(`    <itemref idref="cover"/>` + "\n")
+	}
+	s.WriteString(`    <itemref idref="nav"/>` + "\n")
+	for i := range b.Chapters {
+		fmt.Fprintf(&s, "    <itemref idref=\"chapter-%03d\"/>\n", i+1)
+	}
+	s.WriteString("  </spine>\n")
+	s.WriteString("</package>\n")
+	return s.String()
+}
+
+// navDocument returns the table of contents of EPUB 3.
+func (b *Book) navDocument() string {
+	var s strings.Builder
+	s.WriteString(`<nav epub:type="toc" id="toc">` + "\n")
+	s.WriteString("<h1>Contents</h1>\n<ol>\n")
+	for i, c := range b.Chapters {
+		fmt.Fprintf(&s, "<li><a href=\"%s\">%s</a></li>\n", chapterFile(i), Escape(c.Title))
+	}
+	s.WriteString("</ol>\n</nav>")
+	return b.page("Contents", "", s.String())
+}
+
+// ncx returns the table of contents of EPUB 2.
+func (b *Book) ncx() string {
+	var s strings.Builder
+	s.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	s.WriteString(`<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1">` + "\n")
+	s.WriteString("  <head>\n")
+	fmt.Fprintf(&s, "    <meta name=\"dtb:uid\" content=\"%s\"/>\n", Escape(b.Identifier))
+	s.WriteString(`    <meta name="dtb:depth" content="1"/>` + "\n")
+	s.WriteString(`    <meta name="dtb:totalPageCount" content="0"/>` + "\n")
+	s.WriteString(`    <meta name="dtb:maxPageNumber" content="0"/>` + "\n")
+	s.WriteString("  </head>\n")
+	fmt.Fprintf(&s, "  <docTitle><text>%s</text></docTitle>\n", Escape(b.Title))
+	s.WriteString("  <navMap>\n")
+	for i, c := range b.Chapters {
+		fmt.Fprintf(&s, "    <navPoint id=\"nav-%d\" playOrder=\"%d\"><navLabel><text>%s</text></navLabel><content src=\"%s\"/></navPoint>\n",
+			i+1, i+1, Escape(c.Title), chapterFile(i))
+	}
+	s.WriteString("  </navMap>\n</ncx>\n")
+	return s.String()
+}
+
+// page returns an XHTML page with body.
+func (b *Book) page(title, lang, body string) string {
+	if lang == "" {
+		lang = b.Language
+	}
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" xml:lang="` + Escape(lang) + `" lang="` + Escape(lang) + `">
+<head>
+<meta charset="UTF-8"/>
+<title>` + Escape(title) + `</title>
+<link rel="stylesheet" type="text/css" href="style.css"/>
+</head>
+<body>
+` + body + `
+</body>
+</html>
+`
+}
+
+// Escape escapes s for XML text and attributes, e.g. in the bodies of
+// chapters.
+func Escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,121 @@
+package epub
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testBook() *Book {
+	return &Book{
+		Identifier: "urn:uuid:0f8f2a5e-2a41-5b3c-9a52-4d7f3c1e2b10",
+		Title:      "Campfire & Friends",
+		Language:   "en",
+		Creators:   []string{"The Rolling Stones"},
+		Modified:   time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		Cover:      []byte(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 600 800"/>`),
+		Stylesheet: "body { margin: 0 }",
+		Chapters: []Chapter{
+			{Title: "Start Me Up", Body: "<h1>Start Me Up</h1>"},
+			{Title: "Für Elise", Language: "de", Body: "<h1>Für Elise</h1>"},
+		},
+	}
+}
+
+func TestWrite(t *testing.T) {
+	var b bytes.Buffer
+	if err := testBook().Write(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := zr.File[0]
+	if first.Name != "mimetype" || first.Method != zip.Store {
+		t.Fatalf("first file is %q (method %d), want a stored mimetype", first.Name, first.Method)
+	}
+	// readers check the bytes at offset 30 for the media type
+	if !bytes.HasPrefix(b.Bytes()[30:], []byte("mimetype"+MediaType)) {
+		t.Errorf("mimetype is not at the start of the archive")
+	}
+
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(content)
+	}
+
+	if !strings.Contains(files["META-INF/container.xml"], `full-path="OEBPS/content.opf"`) {
+		t.Errorf("container does not point at the package document")
+	}
+
+	opf := files["OEBPS/content.opf"]
+	for _, want := range []string{`unique-identifier="book-id"`, `<dc:title>Campfire &amp; Friends</dc:title>`, `<meta property="dcterms:modified">2024-03-01T12:00:00Z</meta>`, `properties="nav"`, `properties="cover-image"`} {
+		if !strings.Contains(opf, want) {
+			t.Errorf("package document does not contain %q", want)
+		}
+	}
+	for _, m := range regexp.MustCompile(`href="([^"]+)"`).FindAllStringSubmatch(opf, -1) {
+		if _, ok := files["OEBPS/"+m[1]]; !ok {
+			t.Errorf("manifest item %s is missing", m[1])
+		}
+	}
+
+	for name, content := range files {
+		if !strings.HasSuffix(name, ".xhtml") && !strings.HasSuffix(name, ".opf") && !strings.HasSuffix(name, ".ncx") && !strings.HasSuffix(name, ".xml") {
+			continue
+		}
+		d := xml.NewDecoder(strings.NewReader(content))
+		for {
+			_, err := d.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Errorf("%s is not well-formed: %v", name, err)
+				break
+			}
+		}
+	}
+
+	if nav := files["OEBPS/nav.xhtml"]; !strings.Contains(nav, `epub:type="toc"`) || !strings.Contains(nav, `<a href="chapter-002.xhtml">Für Elise</a>`) {
+		t.Errorf("nav document has no table of contents:\n%s", nav)
+	}
+	if !strings.Contains(files["OEBPS/chapter-002.xhtml"], `xml:lang="de"`) {
+		t.Errorf("chapter in German is not marked as German")
+	}
+
+	// the same book gives the same bytes
+	var again bytes.Buffer
+	testBook().Write(&again)
+	if !bytes.Equal(b.Bytes(), again.Bytes()) {
+		t.Errorf("writing the book twice gave different files")
+	}
+}
+
+func TestWriteWithoutCover(t *testing.T) {
+	book := testBook()
+	book.Cover = nil
+	var b bytes.Buffer
+	if err := book.Write(&b); err != nil {
+		t.Fatal(err)
+	}
+	zr, _ := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	for _, f := range zr.File {
+		if strings.Contains(f.Name, "cover") {
+			t.Errorf("book without a cover has %s", f.Name)
+		}
+	}
+}
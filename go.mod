@@ -3,12 +3,17 @@ module github.com/davidkuda/lyricsapi
 go 1.19
 
 require (
+	github.com/andybalholm/brotli v1.1.0
 	github.com/jackc/pgx/v5 v5.2.0
+	github.com/redis/go-redis/v9 v9.0.2
 	golang.org/x/crypto v0.5.0
+	golang.org/x/sync v0.1.0
+	golang.org/x/text v0.6.0
 )
 
 require (
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
 	github.com/jackc/pgpassfile v1.0.0 // indirect
 	github.com/jackc/pgservicefile v0.0.0-20200714003250-2b9c44734f2b // indirect
-	golang.org/x/text v0.6.0 // indirect
 )
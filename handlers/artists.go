@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/davidkuda/lyricsapi/dbio"
+	"github.com/davidkuda/lyricsapi/models"
+	"github.com/davidkuda/lyricsapi/router"
+	"github.com/davidkuda/lyricsapi/validator"
+)
+
+// maxMergeArtists limits the duplicates merged by one request.
+const maxMergeArtists = 100
+
+// HandleListArtists handles GET /artists. Artists are sorted by their sort
+// names, e.g. "The Rolling Stones" under R.
+func (app *Application) HandleListArtists(w http.ResponseWriter, r *http.Request) {
+	artists, err := dbio.ListArtists(r.Context(), app.DB)
+	if err != nil {
+		app.dbErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, envelope{"artists": artists}, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// HandleShowArtist handles GET /artists/{id}. It sends the artist and the
+// summaries of its songs that the user may see. The songs can be filtered
+// like those of GET /songs.
+func (app *Application) HandleShowArtist(w http.ResponseWriter, r *http.Request) {
+	id, err := router.IntParam(r, "id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+	filter := app.readSongFilter(r.URL.Query(), app.contextGetUser(r), v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	filter.ArtistID = int64(id)
+
+	artist, err := dbio.GetArtist(r.Context(), int64(id), app.DB)
+	if err != nil {
+		app.dbErrorResponse(w, r, err)
+		return
+	}
+
+	songs, err := dbio.ListSongs(r.Context(), app.DB, models.SongSummaryFields, filter)
+	if err != nil {
+		app.dbErrorResponse(w, r, err)
+		return
+	}
+
+	summaries := make([]map[string]any, 0, len(songs))
+	for i := range songs {
+		summaries = append(summaries, songs[i].Select(models.SongSummaryFields))
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, envelope{"artist": artist, "songs": summaries}, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// HandleMergeArtists handles POST /artists/{id}/merge. The artists listed
+// in the body are duplicates of the artist {id}: their songs and aliases
+// are moved to it and they are deleted, e.g.
+//
+//	{"duplicates": [12, 31]}
+func (app *Application) HandleMergeArtists(w http.ResponseWriter, r *http.Request) {
+	id, err := router.IntParam(r, "id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Duplicates []int64 `json:"duplicates"`
+	}
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(len(input.Duplicates) > 0, "duplicates", "must contain at least one artist")
+	v.Check(len(input.Duplicates) <= maxMergeArtists, "duplicates", fmt.Sprintf("must not contain more than %d artists", maxMergeArtists))
+	seen := map[int64]bool{}
+	for _, dup := range input.Duplicates {
+		v.Check(dup != int64(id), "duplicates", "must not contain the artist that is merged into")
+		v.Check(!seen[dup], "duplicates", "must not contain an artist twice")
+		seen[dup] = true
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	songIDs, err := dbio.MergeArtists(r.Context(), int64(id), input.Duplicates, app.DB)
+	if err != nil {
+		app.dbErrorResponse(w, r, err)
+		return
+	}
+	for _, songID := range songIDs {
+		app.SongCache.Invalidate(r.Context(), songID)
+	}
+
+	artist, err := dbio.GetArtist(r.Context(), int64(id), app.DB)
+	if err != nil {
+		app.dbErrorResponse(w, r, err)
+		return
+	}
+
+	env := envelope{"artist": artist, "movedSongs": len(songIDs)}
+	if err := app.writeJSON(w, http.StatusOK, env, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -3,15 +3,13 @@ package handlers
 import (
 	"crypto/rand"
 	"encoding/base32"
-	"encoding/json"
 	"errors"
-	"io"
-	"log"
 	"net/http"
 	"time"
 
 	"github.com/davidkuda/lyricsapi/dbio"
 	"github.com/davidkuda/lyricsapi/models"
+	"github.com/davidkuda/lyricsapi/validator"
 )
 
 func (app *Application) Authenticate(w http.ResponseWriter, r *http.Request) {
@@ -27,11 +25,20 @@ func (app *Application) Authenticate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// TODO: validate email and password
+	v := validator.New()
+	if models.ValidateCredentials(v, input.UserName, input.Password); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
 
-	user, err := dbio.GetUserByName(input.UserName, app.DB, app.Logger)
+	user, err := dbio.GetUserByName(r.Context(), input.UserName, app.DB, app.Logger)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		// an unknown user gets the same response as a wrong password
+		if errors.Is(err, dbio.ErrNotFound) {
+			app.invalidCredentialsResponse(w, r)
+			return
+		}
+		app.dbErrorResponse(w, r, err)
 		return
 	}
 
@@ -63,8 +70,9 @@ func (app *Application) Authenticate(w http.ResponseWriter, r *http.Request) {
 	}
 	t.Token = token
 
-	if err := dbio.CreateNewSession(t, app.DB); err != nil {
-		app.Logger.Println(err)
+	if err := dbio.CreateNewSession(r.Context(), t, app.DB); err != nil {
+		app.dbErrorResponse(w, r, err)
+		return
 	}
 
 	// send session token as cookie
@@ -86,17 +94,20 @@ func (app *Application) SignOut(w http.ResponseWriter, r *http.Request) {
 	c, err := r.Cookie("session")
 	if err != nil {
 		if err == http.ErrNoCookie {
-			app.errorJSON(w, errors.New("Not Authenticated"), http.StatusUnauthorized)
+			app.authenticationRequiredResponse(w, r)
 			return
 		}
-		w.WriteHeader(http.StatusBadRequest)
+		app.badRequestResponse(w, r, err)
 		return
 	}
 
 	sessionToken := c.Value
 
 	// delete cookie in database
-	dbio.DeleteToken(sessionToken, app.DB)
+	if err := dbio.DeleteToken(r.Context(), sessionToken, app.DB); err != nil {
+		app.dbErrorResponse(w, r, err)
+		return
+	}
 
 	// delete cookie from browser
 	http.SetCookie(w, &http.Cookie{
@@ -114,34 +125,37 @@ func (app *Application) SignOut(w http.ResponseWriter, r *http.Request) {
 
 func (app *Application) Signup(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		app.methodNotAllowedResponse(w, r)
 		return
 	}
 
-	data, err := io.ReadAll(r.Body)
-	defer r.Body.Close()
+	newUser := models.User{}
 
-	if err != nil {
-		status := http.StatusInternalServerError
-		log.Printf("%s %s: Error: %d %s", r.URL, r.Method, status, err)
-		http.Error(w, http.StatusText(status), status)
+	// read E-Mail and Password from payload of the request
+	if err := app.readJSON(w, r, &newUser); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
 	}
 
-	newUser := models.User{}
-
-	// unmarshal E-Mail and Password from payload of the request
-	json.Unmarshal(data, &newUser)
+	v := validator.New()
+	if models.ValidateUser(v, &newUser); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
 
-	// TODO: check if password is hashable, pw + salt should not exceed max length of bcrypt
-	if err := dbio.CreateNewUser(&newUser, app.DB, app.Logger); err != nil {
-		status := http.StatusInternalServerError
-		log.Printf("%s %s: Error: %d %s", r.URL, r.Method, status, err)
-		http.Error(w, http.StatusText(status), status)
+	if err := dbio.CreateNewUser(r.Context(), &newUser, app.DB, app.Logger); err != nil {
+		if errors.Is(err, dbio.ErrConflict) {
+			app.conflictResponse(w, r, "a user with this name already exists")
+			return
+		}
+		app.dbErrorResponse(w, r, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Success: User Created"))
+	env := envelope{"status": "Success: User Created"}
+	if err := app.writeJSON(w, http.StatusOK, env, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
 }
 
 func generateToken() (string, error) {
@@ -171,47 +185,53 @@ func generateToken() (string, error) {
 }
 
 func (app *Application) HasActiveSession(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		app.errorJSON(w, errors.New("Method not allowed"), http.StatusMethodNotAllowed)
-		return
-	}
-
-	ok, userName := app.hasValidSessionCookie(w, r)
+	user, ok := app.hasValidSessionCookie(w, r)
 	if !ok {
 		return
 	}
 
-	app.writeJSON(w, http.StatusOK, envelope{"session": userName}, nil)
+	app.writeJSON(w, http.StatusOK, envelope{"session": user.Name}, nil)
 }
 
-func (app *Application) hasValidSessionCookie(w http.ResponseWriter, r *http.Request) (validSession bool, sessionContext string) {
+var (
+	errNoSession      = errors.New("no session cookie")
+	errSessionExpired = errors.New("session expired")
+)
+
+// sessionUser returns the user of the session cookie of r. It returns
+// errNoSession, errSessionExpired or an error of the dbio package.
+func (app *Application) sessionUser(r *http.Request) (*models.User, error) {
 	c, err := r.Cookie("session")
 	if err != nil {
-		if err == http.ErrNoCookie {
-			app.errorJSON(w, errors.New("Not Authenticated"), http.StatusUnauthorized)
-			return false, ""
-		}
-		w.WriteHeader(http.StatusBadRequest)
-		return false, ""
+		return nil, errNoSession
 	}
 
-	clientToken := c.Value
-
-	t, err := dbio.GetSessionToken(clientToken, app.DB)
+	user, expiry, err := dbio.GetSessionUser(r.Context(), c.Value, app.DB)
 	if err != nil {
-		app.Logger.Println("dbio.GetSessionToken:", err)
-		if err == dbio.ErrNoTokenFound {
-			app.errorJSON(w, errors.New("Invalid Session Token"), http.StatusUnauthorized)
-			return false, ""
-		}
-		app.errorJSON(w, errors.New("sum ting wong"), http.StatusInternalServerError)
-		return false, ""
+		return nil, err
 	}
-
-	if t.Expiry.Before(time.Now()) {
-		app.errorJSON(w, errors.New("Session Expired"), http.StatusUnauthorized)
-		return false, ""
+	if expiry.Before(time.Now()) {
+		return nil, errSessionExpired
 	}
 
-	return true, t.UserName
+	return user, nil
+}
+
+// hasValidSessionCookie returns the user of the session cookie of r. If
+// there is no valid session, it responds with an error and returns false.
+func (app *Application) hasValidSessionCookie(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	user, err := app.sessionUser(r)
+	switch {
+	case err == nil:
+		return user, true
+	case errors.Is(err, errNoSession):
+		app.authenticationRequiredResponse(w, r)
+	case errors.Is(err, errSessionExpired):
+		app.sessionExpiredResponse(w, r)
+	case errors.Is(err, dbio.ErrNotFound):
+		app.invalidSessionResponse(w, r)
+	default:
+		app.dbErrorResponse(w, r, err)
+	}
+	return nil, false
 }
@@ -0,0 +1,15 @@
+package handlers
+
+import (
+	"net/http"
+)
+
+// HandleCacheStats reports the hit and miss counters of the song cache.
+func (app *Application) HandleCacheStats(w http.ResponseWriter, r *http.Request) {
+	env := envelope{"songs": app.SongCache.Stats()}
+
+	err := app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
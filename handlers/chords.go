@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/davidkuda/lyricsapi/chord"
+	"github.com/davidkuda/lyricsapi/router"
+	"github.com/davidkuda/lyricsapi/sheet"
+	"github.com/davidkuda/lyricsapi/validator"
+)
+
+// maxCapo is the highest fret a capo can be put on.
+const maxCapo = 11
+
+// HandleChordDiagram handles GET /chords/{name}/diagram.svg. It draws how
+// to play the chord on ?instrument=guitar (the default) or ukulele. With
+// ?capo=, the diagram shows the shape to play behind the capo. Slash chords
+// need their slash escaped, e.g. /chords/D%2FF%23/diagram.svg.
+func (app *Application) HandleChordDiagram(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	in := readInstrument(r.URL.Query(), v)
+	capo := readCapo(r.URL.Query(), v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	c, err := chord.Parse(router.Param(r, "name"))
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+	shape := c.Transpose(-capo, c.Flats())
+	voicing, ok := in.Voicing(shape)
+	if !ok {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	svg := in.Diagram(c.String(), voicing, capo)
+	if checkNotModified(w, r, bodyETag([]byte(svg)), time.Time{}) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, svg)
+}
+
+// songChord is a chord of a song. Shape is the chord to play behind a capo
+// and Diagram the URL of its diagram, if the chord has a known voicing.
+type songChord struct {
+	Name    string `json:"name"`
+	Shape   string `json:"shape,omitempty"`
+	Diagram string `json:"diagram,omitempty"`
+}
+
+// HandleListSongChords handles GET /songs/{id}/chords. It lists the
+// distinct chords of the song in the order they are first played, each
+// with the URL of its diagram. ?instrument= and ?capo= are passed on to
+// the diagrams.
+func (app *Application) HandleListSongChords(w http.ResponseWriter, r *http.Request) {
+	id := router.Param(r, "id")
+
+	v := validator.New()
+	in := readInstrument(r.URL.Query(), v)
+	capo := readCapo(r.URL.Query(), v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	song, ok := app.visibleSong(w, r, id)
+	if !ok {
+		return
+	}
+
+	// the diagrams are asked for with the same options
+	qs := url.Values{}
+	if in != chord.Guitar {
+		qs.Set("instrument", in.Name)
+	}
+	if capo > 0 {
+		qs.Set("capo", strconv.Itoa(capo))
+	}
+
+	chords := []songChord{}
+	for _, name := range sheet.Chords(&song) {
+		sc := songChord{Name: name}
+		c, _ := chord.Parse(name)
+		shape := c.Transpose(-capo, c.Flats())
+		if capo > 0 {
+			sc.Shape = shape.String()
+		}
+		if _, ok := in.Voicing(shape); ok {
+			sc.Diagram = "/v1/chords/" + url.PathEscape(name) + "/diagram.svg"
+			if len(qs) > 0 {
+				sc.Diagram += "?" + qs.Encode()
+			}
+		}
+		chords = append(chords, sc)
+	}
+
+	js, err := json.Marshal(envelope{"chords": chords})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if checkNotModified(w, r, bodyETag(js), song.UpdatedAt) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(js)
+}
+
+// maxCapoSuggestion is the highest capo that is suggested. Higher up, the
+// strings are too short to sound like the song.
+const maxCapoSuggestion = 7
+
+// HandleCapoSuggestions handles GET /songs/{id}/capo-suggestions. For each
+// capo from 0 to 7, it lists the shapes to play the chords of the song
+// with and how hard they are on ?instrument=, easiest first. See
+// chord.Difficulty for the scores.
+func (app *Application) HandleCapoSuggestions(w http.ResponseWriter, r *http.Request) {
+	id := router.Param(r, "id")
+
+	v := validator.New()
+	in := readInstrument(r.URL.Query(), v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	song, ok := app.visibleSong(w, r, id)
+	if !ok {
+		return
+	}
+
+	suggestions := chord.SuggestCapo(in, sheet.PlayedChords(&song), maxCapoSuggestion)
+	js, err := json.Marshal(envelope{"suggestions": suggestions})
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if checkNotModified(w, r, bodyETag(js), song.UpdatedAt) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(js)
+}
+
+// readInstrument parses ?instrument=. It returns the guitar if the
+// parameter is missing.
+func readInstrument(qs url.Values, v *validator.Validator) *chord.Instrument {
+	name := qs.Get("instrument")
+	if name == "" {
+		return chord.Guitar
+	}
+
+	in := chord.Instruments[name]
+	if in == nil {
+		var names []string
+		for n := range chord.Instruments {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		v.AddError("instrument", "must be one of "+strings.Join(names, ", "))
+		return chord.Guitar
+	}
+	return in
+}
+
+// readCapo parses ?capo=, the fret of the capo. It returns 0 if the
+// parameter is missing.
+func readCapo(qs url.Values, v *validator.Validator) int {
+	raw := qs.Get("capo")
+	if raw == "" {
+		return 0
+	}
+	capo, err := strconv.Atoi(raw)
+	if err != nil || capo < 0 || capo > maxCapo {
+		v.AddError("capo", fmt.Sprintf("must be a fret from 0 to %d", maxCapo))
+		return 0
+	}
+	return capo
+}
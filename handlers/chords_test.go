@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/davidkuda/lyricsapi/router"
+)
+
+func TestHandleChordDiagram(t *testing.T) {
+	app := &Application{}
+	rt := router.New()
+	rt.HandleFunc(http.MethodGet, "/v1/chords/{name}/diagram.svg", app.HandleChordDiagram)
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/v1/chords/F%23m7b5/diagram.svg", http.StatusOK, "<title>F#m7b5</title>"},
+		{"/v1/chords/D%2FF%23/diagram.svg", http.StatusOK, "<title>D/F#</title>"},
+		{"/v1/chords/G/diagram.svg?instrument=ukulele&capo=2", http.StatusOK, "capo 2"},
+		{"/v1/chords/Chorus/diagram.svg", http.StatusNotFound, ""},
+		{"/v1/chords/C13b9/diagram.svg", http.StatusNotFound, ""},
+		{"/v1/chords/C/diagram.svg?instrument=banjo", http.StatusUnprocessableEntity, "instrument"},
+		{"/v1/chords/C/diagram.svg?capo=12", http.StatusUnprocessableEntity, "capo"},
+	}
+
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		rt.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rr.Code != tt.status {
+			t.Errorf("GET %s: status %d, want %d", tt.path, rr.Code, tt.status)
+			continue
+		}
+		if !strings.Contains(rr.Body.String(), tt.body) {
+			t.Errorf("GET %s: body doesn't contain %q:\n%s", tt.path, tt.body, rr.Body)
+		}
+	}
+}
+
+func TestRenamedSongPath(t *testing.T) {
+	tests := map[string]string{
+		"/v1/songs/old":        "/v1/songs/new",
+		"/v1/songs/old/chords": "/v1/songs/new/chords",
+		"/songs/old":           "/songs/new",
+		"/v1/songs/old.pdf":    "/v1/songs/new.pdf",
+		"/v1/songs/older":      "/v1/songs/older",
+	}
+	for p, want := range tests {
+		if got := renamedSongPath(p, "old", "new"); got != want {
+			t.Errorf("renamedSongPath(%q) = %q, want %q", p, got, want)
+		}
+	}
+}
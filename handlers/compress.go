@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// compressMinSize is the smallest body that is compressed. Below it, the
+// overhead of the encoding isn't worth it.
+const compressMinSize = 1024
+
+// Content types that are compressed already, compressing them again only
+// costs CPU.
+var precompressedTypes = []string{
+	"image/",
+	"audio/",
+	"video/",
+	"font/woff",
+	"application/zip",
+	"application/gzip",
+	"application/epub+zip",
+	"application/pdf",
+}
+
+var (
+	gzipPool = sync.Pool{New: func() any {
+		return gzip.NewWriter(io.Discard)
+	}}
+	brotliPool = sync.Pool{New: func() any {
+		return brotli.NewWriterLevel(io.Discard, 5)
+	}}
+)
+
+// Compress compresses response bodies with brotli or gzip, whichever the
+// client prefers. Small bodies, bodies that are compressed already and
+// streamed responses (i.e. handlers that flush) are sent as they are.
+//
+// A compressed response is a different representation than the plain one,
+// so its ETag gets a suffix, e.g. "42+br". The suffix is removed from the
+// If-None-Match and If-Match headers of all requests before the handler
+// sees them.
+func (app *Application) Compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		addVary(w.Header(), "Accept-Encoding")
+
+		// clients may send a tag they got compressed with a request that
+		// isn't, e.g. a HEAD or a PUT without Accept-Encoding
+		suffix := stripETagSuffixes(r, "If-None-Match")
+		stripETagSuffixes(r, "If-Match")
+
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{
+			ResponseWriter: w,
+			encoding:       encoding,
+			inmSuffix:      suffix,
+		}
+		defer cw.Close()
+
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// negotiateEncoding returns "br", "gzip" or "" (no compression) for the
+// Accept-Encoding header of a request. Brotli wins a tie.
+func negotiateEncoding(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		q := 1.0
+		if params = strings.TrimSpace(params); strings.HasPrefix(params, "q=") {
+			parsed, err := strconv.ParseFloat(strings.TrimPrefix(params, "q="), 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		if name == "*" {
+			name = "br"
+		}
+		if (name != "br" && name != "gzip") || q <= 0 {
+			continue
+		}
+		if q > bestQ || (q == bestQ && name == "br") {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// addVary adds value to the Vary header, unless it is listed already.
+func addVary(h http.Header, value string) {
+	for _, line := range h.Values("Vary") {
+		for _, v := range strings.Split(line, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), value) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", value)
+}
+
+// etagSuffixes maps an encoding to the suffix of the ETags of its
+// representations.
+var etagSuffixes = map[string]string{"br": "+br", "gzip": "+gzip"}
+
+// stripETagSuffixes removes encoding suffixes from the entity tags in the
+// request header and returns the last suffix it removed.
+func stripETagSuffixes(r *http.Request, header string) string {
+	value := r.Header.Get(header)
+	if value == "" {
+		return ""
+	}
+
+	var removed string
+	tags := strings.Split(value, ",")
+	for i, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		for _, suffix := range etagSuffixes {
+			if strings.HasSuffix(tag, suffix+`"`) {
+				tag = strings.TrimSuffix(tag, suffix+`"`) + `"`
+				removed = suffix
+			}
+		}
+		tags[i] = tag
+	}
+	r.Header.Set(header, strings.Join(tags, ", "))
+	return removed
+}
+
+// compressWriter buffers the start of the body until it knows whether the
+// response is worth compressing, then either sets up an encoder or writes
+// through.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding  string
+	inmSuffix string // suffix the client sent in If-None-Match
+
+	status  int
+	buf     []byte
+	decided bool
+	encoder io.WriteCloser // nil if the body is written as it is
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if status < 200 {
+		// informational responses are sent right away, the final one follows
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if cw.status != 0 {
+		return
+	}
+	cw.status = status
+
+	// a 304 refers to the representation the client has, which had a suffix
+	if status == http.StatusNotModified && cw.inmSuffix != "" {
+		if etag := cw.Header().Get("ETag"); etag != "" {
+			cw.Header().Set("ETag", strings.TrimSuffix(etag, `"`)+cw.inmSuffix+`"`)
+		}
+	}
+
+	if !bodyAllowed(status) || !cw.compressible() {
+		cw.start(false)
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.decided {
+		if cw.encoder != nil {
+			return cw.encoder.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) >= compressMinSize {
+		if err := cw.start(true); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush is called by streaming handlers. Streamed responses are not
+// compressed, since the encoder would hold back data.
+func (cw *compressWriter) Flush() {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if !cw.decided {
+		cw.start(false)
+	}
+	if f, ok := cw.encoder.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack lets websockets and the like bypass the compression.
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := cw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	return h.Hijack()
+}
+
+// Close writes a body that was too small to compress and finishes the
+// encoder.
+func (cw *compressWriter) Close() error {
+	if !cw.decided {
+		if cw.status == 0 {
+			// the handler wrote nothing at all
+			return nil
+		}
+		if err := cw.start(false); err != nil {
+			return err
+		}
+	}
+	if cw.encoder == nil {
+		return nil
+	}
+
+	err := cw.encoder.Close()
+	switch e := cw.encoder.(type) {
+	case *gzip.Writer:
+		gzipPool.Put(e)
+	case *brotli.Writer:
+		brotliPool.Put(e)
+	}
+	cw.encoder = nil
+	return err
+}
+
+// compressible reports whether the headers set by the handler allow
+// compression.
+func (cw *compressWriter) compressible() bool {
+	h := cw.Header()
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+
+	contentType := h.Get("Content-Type")
+	if strings.HasPrefix(contentType, "text/event-stream") {
+		return false
+	}
+	for _, t := range precompressedTypes {
+		if strings.HasPrefix(contentType, t) {
+			return false
+		}
+	}
+	return true
+}
+
+// start sends the header and the buffered body, compressed or not.
+func (cw *compressWriter) start(compress bool) error {
+	cw.decided = true
+
+	if compress {
+		h := cw.Header()
+		// without it, net/http would sniff the type of the compressed bytes
+		if h.Get("Content-Type") == "" {
+			h.Set("Content-Type", http.DetectContentType(cw.buf))
+		}
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		if etag := h.Get("ETag"); etag != "" {
+			h.Set("ETag", strings.TrimSuffix(etag, `"`)+etagSuffixes[cw.encoding]+`"`)
+		}
+
+		switch cw.encoding {
+		case "br":
+			bw := brotliPool.Get().(*brotli.Writer)
+			bw.Reset(cw.ResponseWriter)
+			cw.encoder = bw
+		case "gzip":
+			gw := gzipPool.Get().(*gzip.Writer)
+			gw.Reset(cw.ResponseWriter)
+			cw.encoder = gw
+		}
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	if len(cw.buf) == 0 {
+		return nil
+	}
+	buf := cw.buf
+	cw.buf = nil
+	if cw.encoder != nil {
+		_, err := cw.encoder.Write(buf)
+		return err
+	}
+	_, err := cw.ResponseWriter.Write(buf)
+	return err
+}
+
+func bodyAllowed(status int) bool {
+	return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
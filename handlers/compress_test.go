@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := map[string]string{
+		"":                         "",
+		"identity":                 "",
+		"gzip":                     "gzip",
+		"gzip, deflate, br":        "br",
+		"br;q=0.5, gzip":           "gzip",
+		"br;q=0, gzip;q=0":         "",
+		"*":                        "br",
+		"deflate, GZIP;q=0.8":      "gzip",
+		"gzip;q=0.8, br;q=invalid": "gzip",
+	}
+	for header, want := range tests {
+		if got := negotiateEncoding(header); got != want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+	app := &Application{}
+	long := strings.Repeat("Hold on to me, ", 200)
+
+	handler := func(body, contentType string) http.Handler {
+		return app.Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Origin")
+			w.Header().Set("ETag", `"42"`)
+			if contentType != "" {
+				w.Header().Set("Content-Type", contentType)
+			}
+			w.Write([]byte(body))
+		}))
+	}
+
+	tests := []struct {
+		name, acceptEncoding, body, contentType string
+		wantEncoding, wantETag                  string
+	}{
+		{"brotli", "gzip, br", long, "application/json", "br", `"42+br"`},
+		{"gzip", "gzip", long, "text/plain", "gzip", `"42+gzip"`},
+		{"too small", "gzip, br", "short", "application/json", "", `"42"`},
+		{"not accepted", "", long, "application/json", "", `"42"`},
+		{"already compressed", "gzip", long, "application/pdf", "", `"42"`},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/v1/songs", nil)
+		r.Header.Set("Accept-Encoding", tt.acceptEncoding)
+		w := httptest.NewRecorder()
+		handler(tt.body, tt.contentType).ServeHTTP(w, r)
+
+		if got := w.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+			t.Errorf("%s: Content-Encoding = %q, want %q", tt.name, got, tt.wantEncoding)
+		}
+		if got := w.Header().Get("ETag"); got != tt.wantETag {
+			t.Errorf("%s: ETag = %q, want %q", tt.name, got, tt.wantETag)
+		}
+		if got := w.Header().Values("Vary"); len(got) != 2 {
+			t.Errorf("%s: Vary = %q, want Accept-Encoding and Origin", tt.name, got)
+		}
+
+		var body io.Reader = w.Body
+		switch tt.wantEncoding {
+		case "gzip":
+			gr, err := gzip.NewReader(w.Body)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			body = gr
+		case "br":
+			body = brotli.NewReader(w.Body)
+		}
+		decoded, err := io.ReadAll(body)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if string(decoded) != tt.body {
+			t.Errorf("%s: body was not restored", tt.name)
+		}
+	}
+}
+
+func TestCompressNotModified(t *testing.T) {
+	app := &Application{}
+	var seen string
+	h := app.Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.Header.Get("If-None-Match")
+		checkNotModified(w, r, `"42"`, time.Time{})
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/v1/songs/hold-on", nil)
+	r.Header.Set("Accept-Encoding", "br")
+	r.Header.Set("If-None-Match", `"42+br"`)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if seen != `"42"` {
+		t.Errorf("handler saw If-None-Match %s, want the suffix removed", seen)
+	}
+	if w.Code != http.StatusNotModified || w.Header().Get("ETag") != `"42+br"` {
+		t.Errorf("got %d with ETag %s, want 304 with \"42+br\"", w.Code, w.Header().Get("ETag"))
+	}
+}
+
+func TestCompressStripsSuffixesWithoutEncoding(t *testing.T) {
+	app := &Application{}
+	var ifMatch []int64
+	h := app.Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ifMatch = ifMatchVersions(r)
+		checkNotModified(w, r, `"42"`, time.Time{})
+	}))
+
+	for _, method := range []string{http.MethodGet, http.MethodHead} {
+		r := httptest.NewRequest(method, "/v1/songs/hold-on", nil)
+		r.Header.Set("If-None-Match", `"42+gzip"`)
+		r.Header.Set("If-Match", `"42+gzip"`)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if len(ifMatch) != 1 || ifMatch[0] != 42 {
+			t.Errorf("%s: If-Match versions = %v, want [42]", method, ifMatch)
+		}
+		if w.Code != http.StatusNotModified {
+			t.Errorf("%s: got %d, want 304", method, w.Code)
+		}
+	}
+}
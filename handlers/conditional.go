@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/davidkuda/lyricsapi/models"
+)
+
+// Conditional requests (RFC 9110, section 13). Reads send an ETag and a
+// Last-Modified header and answer If-None-Match and If-Modified-Since with
+// 304 Not Modified. Writes honour If-Match, so that an editor can't
+// overwrite changes they haven't seen.
+//
+// The ETag of a song is its version, e.g. "42". If only some fields are
+// requested, a hash of the field list is appended, e.g. "42-1a2b3c4d", since
+// that representation differs from the full song. Plain text sheets append
+// "text" and their width, e.g. "42-text80", and chords in another notation
+// the notation, e.g. "42-nashville". PDFs append "pdf", the number of
+// columns and the transposition, e.g. "42-pdf2+3". XML files append their
+// format, e.g. "42-opensong".
+
+// songETag returns the strong ETag of the representation of s with fields.
+func songETag(s *models.Song, fields []string) string {
+	// the order of the fields does not change the JSON object
+	var canonical []string
+	for _, f := range models.SongFields {
+		if contains(fields, f) {
+			canonical = append(canonical, f)
+		}
+	}
+
+	tag := strconv.FormatInt(s.Version, 10)
+	if len(canonical) != len(models.SongFields) {
+		sum := sha256.Sum256([]byte(strings.Join(canonical, ",")))
+		tag += "-" + hex.EncodeToString(sum[:4])
+	}
+	return `"` + tag + `"`
+}
+
+// songTextETag returns the strong ETag of the plain text sheet of s with
+// lines of width, e.g. "42-text80".
+func songTextETag(s *models.Song, width int) string {
+	return `"` + strconv.FormatInt(s.Version, 10) + "-text" + strconv.Itoa(width) + `"`
+}
+
+// songPDFETag returns the strong ETag of the PDF of s in columns, with its
+// chords moved by transpose semitones, e.g. "42-pdf1+0".
+func songPDFETag(s *models.Song, columns, transpose int) string {
+	return fmt.Sprintf(`"%d-pdf%d%+d"`, s.Version, columns, transpose)
+}
+
+// notationETag returns etag for the representation with chords written in
+// notation, e.g. "42-roman".
+func notationETag(etag, notation string) string {
+	return strings.TrimSuffix(etag, `"`) + "-" + notation + `"`
+}
+
+// bodyETag returns a strong ETag derived from the bytes of a response body.
+// It is used for collections, which have no version of their own.
+func bodyETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// checkNotModified sets the ETag and Last-Modified headers. If the request
+// is conditional and the client's copy is still current, it responds with
+// 304 and returns true; the caller must not write a body then.
+func checkNotModified(w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time) bool {
+	w.Header().Set("ETag", etag)
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	// If-Modified-Since is ignored if If-None-Match is present
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if etagListContains(inm, etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		t, err := http.ParseTime(ims)
+		// Last-Modified only has a precision of seconds
+		if err == nil && !lastModified.Truncate(time.Second).After(t) {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+
+	return false
+}
+
+// ifMatchVersions returns the song versions listed in the If-Match header.
+// It returns nil if the header is missing or "*", i.e. if any version may
+// be overwritten. If the header only contains tags that can't be versions,
+// the returned slice is empty but not nil, so the write fails with 412.
+func ifMatchVersions(r *http.Request) []int64 {
+	header := r.Header.Get("If-Match")
+	if header == "" || strings.TrimSpace(header) == "*" {
+		return nil
+	}
+
+	versions := []int64{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		// If-Match uses the strong comparison, weak tags never match
+		if strings.HasPrefix(tag, "W/") {
+			continue
+		}
+		tag = strings.Trim(tag, `"`)
+		tag, _, _ = strings.Cut(tag, "-")
+		if v, err := strconv.ParseInt(tag, 10, 64); err == nil {
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
+
+// etagListContains reports whether the comma separated list of entity tags
+// contains etag or "*". It uses the weak comparison, which ignores the W/
+// prefix, as required for If-None-Match.
+func etagListContains(list, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(list, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/davidkuda/lyricsapi/models"
+)
+
+func TestSongETag(t *testing.T) {
+	s := &models.Song{Version: 42}
+
+	if got := songETag(s, models.SongFields); got != `"42"` {
+		t.Errorf("full song: got %s, want \"42\"", got)
+	}
+
+	a := songETag(s, []string{"id", "name"})
+	b := songETag(s, []string{"name", "id"})
+	if a != b {
+		t.Errorf("order of fields changed the etag: %s != %s", a, b)
+	}
+	if a == `"42"` {
+		t.Errorf("sparse fieldset got the etag of the full song")
+	}
+
+	if got := notationETag(songTextETag(s, 80), "roman"); got != `"42-text80-roman"` {
+		t.Errorf("text in roman numerals: got %s, want \"42-text80-roman\"", got)
+	}
+}
+
+func TestCheckNotModified(t *testing.T) {
+	modified := time.Date(2023, 5, 5, 12, 0, 0, 500, time.UTC)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    bool
+	}{
+		{"unconditional", nil, false},
+		{"matching etag", map[string]string{"If-None-Match": `"1", "42"`}, true},
+		{"weak etag", map[string]string{"If-None-Match": `W/"42"`}, true},
+		{"star", map[string]string{"If-None-Match": `*`}, true},
+		{"other etag", map[string]string{"If-None-Match": `"41"`}, false},
+		{"not modified since", map[string]string{"If-Modified-Since": "Fri, 05 May 2023 12:00:00 GMT"}, true},
+		{"modified since", map[string]string{"If-Modified-Since": "Fri, 05 May 2023 11:59:59 GMT"}, false},
+		{"etag wins over date", map[string]string{
+			"If-None-Match":     `"41"`,
+			"If-Modified-Since": "Fri, 05 May 2023 12:00:00 GMT",
+		}, false},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/v1/songs/hold-on", nil)
+		for k, v := range tt.headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+
+		got := checkNotModified(w, r, `"42"`, modified)
+		if got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+		if got && w.Code != http.StatusNotModified {
+			t.Errorf("%s: status %d, want 304", tt.name, w.Code)
+		}
+		if w.Header().Get("ETag") != `"42"` || w.Header().Get("Last-Modified") != "Fri, 05 May 2023 12:00:00 GMT" {
+			t.Errorf("%s: missing validators: %v", tt.name, w.Header())
+		}
+	}
+}
+
+func TestIfMatchVersions(t *testing.T) {
+	tests := map[string][]int64{
+		"":                   nil,
+		"*":                  nil,
+		`"42"`:               {42},
+		`"42-1a2b3c4d", "7"`: {42, 7},
+		`W/"42"`:             {},
+		`"not-a-version"`:    {},
+	}
+	for header, want := range tests {
+		r := httptest.NewRequest(http.MethodPut, "/v1/songs/hold-on", nil)
+		if header != "" {
+			r.Header.Set("If-Match", header)
+		}
+		if got := ifMatchVersions(r); !reflect.DeepEqual(got, want) {
+			t.Errorf("If-Match %s: got %#v, want %#v", header, got, want)
+		}
+	}
+}
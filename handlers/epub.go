@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/davidkuda/lyricsapi/dbio"
+	"github.com/davidkuda/lyricsapi/epub"
+	"github.com/davidkuda/lyricsapi/models"
+	"github.com/davidkuda/lyricsapi/sheet"
+	"github.com/davidkuda/lyricsapi/slug"
+	"github.com/davidkuda/lyricsapi/validator"
+)
+
+// HandleSongbookEPUB handles GET /songbook.epub, an EPUB of the songs
+// ?ids=a,b,c in this order, or of the songs of ?artist=, the id of an
+// artist. The songs of an artist can be narrowed down with the filters of
+// GET /songs. ?title= is the title of the book, by default the name of the
+// artist.
+func (app *Application) HandleSongbookEPUB(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	user := app.contextGetUser(r)
+
+	v := validator.New()
+	filter := app.readSongFilter(qs, user, v)
+	title := qs.Get("title")
+	v.Check(validator.MaxChars(title, maxBundleTitleChars), "title", fmt.Sprintf("must not be more than %d characters long", maxBundleTitleChars))
+
+	var ids []string
+	for _, id := range strings.Split(qs.Get("ids"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			v.Check(validator.Matches(id, validator.SlugRX), "ids", fmt.Sprintf("%q is not the id of a song", id))
+			ids = append(ids, id)
+		}
+	}
+	v.Check(len(ids) <= maxBundleSongs, "ids", fmt.Sprintf("must not contain more than %d songs", maxBundleSongs))
+
+	var artistID int64
+	if raw := qs.Get("artist"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		v.Check(err == nil && id > 0, "artist", "must be the id of an artist, see GET /v1/artists")
+		v.Check(len(ids) == 0, "artist", "must not be combined with ids")
+		artistID = id
+	} else if len(ids) == 0 {
+		v.AddError("ids", "must be provided unless artist is")
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	var songs []models.Song
+	if artistID != 0 {
+		artist, err := dbio.GetArtist(r.Context(), artistID, app.DB)
+		if err != nil {
+			app.dbErrorResponse(w, r, err)
+			return
+		}
+		filter.ArtistID = artistID
+		songs, err = dbio.ListSongs(r.Context(), app.DB, models.SongFields, filter)
+		if err != nil {
+			app.dbErrorResponse(w, r, err)
+			return
+		}
+		v.Check(len(songs) > 0, "artist", "has no songs that match the filters")
+		v.Check(len(songs) <= maxBundleSongs, "artist", fmt.Sprintf("has more than %d songs, narrow them down with the filters of GET /v1/songs", maxBundleSongs))
+		if title == "" {
+			title = artist.Name
+		}
+	}
+
+	for _, id := range ids {
+		song, err := app.bundleSong(r.Context(), id)
+		if err != nil && !errors.Is(err, dbio.ErrNotFound) {
+			app.dbErrorResponse(w, r, err)
+			return
+		}
+		if err != nil || !song.VisibleTo(user) {
+			v.AddError("ids", fmt.Sprintf("refers to the unknown song %q", id))
+			continue
+		}
+		songs = append(songs, song)
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if title == "" {
+		title = "Songbook"
+	}
+	var b bytes.Buffer
+	if err := sheet.EPUB(&b, songs, title); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var lastModified time.Time
+	for _, s := range songs {
+		if s.UpdatedAt.After(lastModified) {
+			lastModified = s.UpdatedAt
+		}
+	}
+	if checkNotModified(w, r, bodyETag(b.Bytes()), lastModified) {
+		return
+	}
+
+	filename := slug.Make(title)
+	if filename == "" {
+		filename = "songbook"
+	}
+	w.Header().Set("Content-Type", epub.MediaType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".epub"))
+	w.Header().Set("Content-Length", strconv.Itoa(b.Len()))
+	w.WriteHeader(http.StatusOK)
+	w.Write(b.Bytes())
+}
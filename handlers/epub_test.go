@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/davidkuda/lyricsapi/models"
+)
+
+func TestHandleSongbookEPUBValidation(t *testing.T) {
+	app := &Application{Logger: log.New(io.Discard, "", 0)}
+
+	tests := map[string]string{
+		"":                        "ids",
+		"?ids=angie,Not%20A+Slug": "ids",
+		"?artist=stones":          "artist",
+		"?artist=3&ids=angie":     "artist",
+	}
+	for query, field := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/v1/songbook.epub"+query, nil)
+		req = app.contextSetUser(req, models.AnonymousUser)
+		rec := httptest.NewRecorder()
+		app.HandleSongbookEPUB(rec, req)
+
+		var p problem
+		json.NewDecoder(rec.Body).Decode(&p)
+		if rec.Code != http.StatusUnprocessableEntity || len(p.Errors) != 1 || p.Errors[0].Field != field {
+			t.Errorf("%q: status %d, errors %+v, want 422 for %s", query, rec.Code, p.Errors, field)
+		}
+	}
+}
@@ -1,43 +1,169 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"sort"
+
+	"github.com/davidkuda/lyricsapi/dbio"
+	"github.com/davidkuda/lyricsapi/sheet"
 )
 
-func (app *Application) errorResponse(w http.ResponseWriter, r *http.Request, status int, message any) {
-	env := envelope{"error": message}
+// All errors are sent as RFC 7807 problem details. Code is a stable,
+// machine-readable identifier; clients should switch on it rather than on
+// Title or Detail, which are meant for humans.
+
+// problemTypeBase is the prefix of the type URI of every problem; the code
+// of the problem is appended to it.
+const problemTypeBase = "https://lyricsapi.kuda.ai/problems/"
+
+type problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []fieldError `json:"errors,omitempty"`
+
+	Diagnostics []sheet.Diagnostic `json:"diagnostics,omitempty"`
+}
+
+// fieldError describes why the value of a single field was rejected.
+type fieldError struct {
+	Field  string `json:"field"`
+	Detail string `json:"detail"`
+}
+
+func (app *Application) errorResponse(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	app.writeProblem(w, r, problem{
+		Type:   problemTypeBase + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	})
+}
+
+func (app *Application) writeProblem(w http.ResponseWriter, r *http.Request, p problem) {
+	p.Instance = r.URL.Path
 
-	err := app.writeJSON(w, status, env, nil)
+	js, err := json.MarshalIndent(p, "", "  ")
 	if err != nil {
 		app.Logger.Println(err)
-		w.WriteHeader(500)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
 	}
+	js = append(js, '\n')
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	w.Write(js)
 }
 
 func (app *Application) serverErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
-	app.Logger.Println(err)
+	app.Logger.Printf("%s %s: %v", r.Method, r.URL.Path, err)
 	message := "the server encountered a problem and could not process your request"
-	app.errorResponse(w, r, http.StatusInternalServerError, message)
+	app.errorResponse(w, r, http.StatusInternalServerError, "internal_error", message)
 }
 
 func (app *Application) notFoundResponse(w http.ResponseWriter, r *http.Request) {
 	message := "the requested resource could not be found"
-	app.errorResponse(w, r, http.StatusNotFound, message)
+	app.errorResponse(w, r, http.StatusNotFound, "not_found", message)
 }
 
 func (app *Application) methodNotAllowedResponse(w http.ResponseWriter, r *http.Request) {
 	message := fmt.Sprintf("the %s method is not supported for this resource", r.Method)
-	app.errorResponse(w, r, http.StatusMethodNotAllowed, message)
+	app.errorResponse(w, r, http.StatusMethodNotAllowed, "method_not_allowed", message)
 }
 
 func (app *Application) badRequestResponse(w http.ResponseWriter, r *http.Request, err error) {
-	app.errorResponse(w, r, http.StatusBadRequest, err.Error())
+	app.errorResponse(w, r, http.StatusBadRequest, "bad_request", err.Error())
+}
+
+// failedValidationResponse reports all invalid fields at once. errs maps
+// the name of a field to the reason it was rejected.
+func (app *Application) failedValidationResponse(w http.ResponseWriter, r *http.Request, errs map[string]string) {
+	app.writeProblem(w, r, problem{
+		Type:   problemTypeBase + "validation_failed",
+		Title:  http.StatusText(http.StatusUnprocessableEntity),
+		Status: http.StatusUnprocessableEntity,
+		Detail: "the request contains invalid fields",
+		Code:   "validation_failed",
+		Errors: fieldErrors(errs),
+	})
+}
+
+// fieldErrors returns errs sorted by field.
+func fieldErrors(errs map[string]string) []fieldError {
+	fields := make([]string, 0, len(errs))
+	for field := range errs {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var fes []fieldError
+	for _, field := range fields {
+		fes = append(fes, fieldError{Field: field, Detail: errs[field]})
+	}
+	return fes
+}
+
+// invalidChordSheetResponse reports the diagnostics of a chord sheet with
+// errors. The warnings are included, so they can be fixed in one go.
+func (app *Application) invalidChordSheetResponse(w http.ResponseWriter, r *http.Request, diagnostics []sheet.Diagnostic) {
+	app.writeProblem(w, r, problem{
+		Type:        problemTypeBase + "invalid_chord_sheet",
+		Title:       http.StatusText(http.StatusUnprocessableEntity),
+		Status:      http.StatusUnprocessableEntity,
+		Detail:      "the chord sheet contains errors",
+		Code:        "invalid_chord_sheet",
+		Diagnostics: diagnostics,
+	})
+}
+
+// dbErrorResponse responds to an error of the dbio package with the status
+// that matches its kind.
+func (app *Application) dbErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, dbio.ErrNotFound):
+		app.notFoundResponse(w, r)
+	case errors.Is(err, dbio.ErrConflict):
+		app.conflictResponse(w, r, "the request conflicts with an existing resource")
+	case errors.Is(err, dbio.ErrPreconditionFailed):
+		app.preconditionFailedResponse(w, r)
+	case errors.Is(err, dbio.ErrUnavailable):
+		app.serviceUnavailableResponse(w, r, err)
+	case errors.Is(err, context.Canceled):
+		// the client went away, there is no one to respond to
+		app.Logger.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+	default:
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *Application) serviceUnavailableResponse(w http.ResponseWriter, r *http.Request, err error) {
+	app.Logger.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+	w.Header().Set("Retry-After", "5")
+	message := "the service is temporarily unavailable, please try again later"
+	app.errorResponse(w, r, http.StatusServiceUnavailable, "unavailable", message)
+}
+
+func (app *Application) conflictResponse(w http.ResponseWriter, r *http.Request, message string) {
+	app.errorResponse(w, r, http.StatusConflict, "conflict", message)
+}
+
+func (app *Application) preconditionFailedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the resource has been modified since you last fetched it, fetch it again and reapply your changes"
+	app.errorResponse(w, r, http.StatusPreconditionFailed, "precondition_failed", message)
 }
 
 func (app *Application) invalidCredentialsResponse(w http.ResponseWriter, r *http.Request) {
 	message := "invalid authentication credentials"
-	app.errorResponse(w, r, http.StatusUnauthorized, message)
+	app.errorResponse(w, r, http.StatusUnauthorized, "invalid_credentials", message)
 }
 
 // used in middleware Authenticate
@@ -45,10 +171,36 @@ func (app *Application) invalidAuthenticationTokenResponse(w http.ResponseWriter
 	w.Header().Set("WWW-Authenticate", "Bearer")
 
 	message := "invalid or missing authentication token"
-	app.errorResponse(w, r, http.StatusUnauthorized, message)
+	app.errorResponse(w, r, http.StatusUnauthorized, "invalid_token", message)
 }
 
 func (app *Application) authenticationRequiredResponse(w http.ResponseWriter, r *http.Request) {
 	message := "you must be authenticated to access this resource"
-	app.errorResponse(w, r, http.StatusUnauthorized, message)
+	app.errorResponse(w, r, http.StatusUnauthorized, "authentication_required", message)
+}
+
+func (app *Application) notPermittedResponse(w http.ResponseWriter, r *http.Request) {
+	message := "your user account doesn't have the necessary permissions to access this resource"
+	app.errorResponse(w, r, http.StatusForbidden, "not_permitted", message)
+}
+
+func (app *Application) invalidSessionResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the session is invalid, please sign in again"
+	app.errorResponse(w, r, http.StatusUnauthorized, "invalid_session", message)
+}
+
+func (app *Application) sessionExpiredResponse(w http.ResponseWriter, r *http.Request) {
+	message := "the session has expired, please sign in again"
+	app.errorResponse(w, r, http.StatusUnauthorized, "session_expired", message)
+}
+
+// HandleNotFound is used by the router if no route matches.
+func (app *Application) HandleNotFound(w http.ResponseWriter, r *http.Request) {
+	app.notFoundResponse(w, r)
+}
+
+// HandleMethodNotAllowed is used by the router if a route matches the path
+// but not the method.
+func (app *Application) HandleMethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	app.methodNotAllowedResponse(w, r)
 }
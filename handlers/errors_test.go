@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/davidkuda/lyricsapi/dbio"
+	"github.com/davidkuda/lyricsapi/models"
+)
+
+func TestFailedValidationResponse(t *testing.T) {
+	app := &Application{Logger: log.New(io.Discard, "", 0)}
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/songs", nil)
+	app.failedValidationResponse(rec, req, map[string]string{
+		"name":   "must be provided",
+		"artist": "must be provided",
+	})
+
+	if got := rec.Header().Get("Content-Type"); got != "application/problem+json" {
+		t.Errorf("Content-Type = %q", got)
+	}
+
+	var p problem
+	if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != http.StatusUnprocessableEntity || rec.Code != p.Status {
+		t.Errorf("status = %d (body %d), want 422", rec.Code, p.Status)
+	}
+	if p.Code != "validation_failed" || p.Type != problemTypeBase+"validation_failed" {
+		t.Errorf("code = %q, type = %q", p.Code, p.Type)
+	}
+	if p.Instance != "/v1/songs" {
+		t.Errorf("instance = %q", p.Instance)
+	}
+	if len(p.Errors) != 2 || p.Errors[0].Field != "artist" || p.Errors[1].Field != "name" {
+		t.Errorf("errors = %+v, want artist and name, sorted", p.Errors)
+	}
+}
+
+func TestDBErrorResponse(t *testing.T) {
+	app := &Application{Logger: log.New(io.Discard, "", 0)}
+
+	tests := []struct {
+		err      error
+		wantCode int
+		wantType string
+	}{
+		{&dbio.Error{Op: "GetSong", Kind: dbio.ErrNotFound, Err: dbio.ErrSongDoesNotExist}, http.StatusNotFound, "not_found"},
+		{&dbio.Error{Op: "CreateSong", Kind: dbio.ErrConflict}, http.StatusConflict, "conflict"},
+		{&dbio.Error{Op: "DeleteSong", Kind: dbio.ErrPreconditionFailed}, http.StatusPreconditionFailed, "precondition_failed"},
+		{&dbio.Error{Op: "ListSongs", Kind: dbio.ErrUnavailable}, http.StatusServiceUnavailable, "unavailable"},
+		{&dbio.Error{Op: "ListSongs", Err: errors.New("boom")}, http.StatusInternalServerError, "internal_error"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/v1/songs", nil)
+		app.dbErrorResponse(rec, req, tt.err)
+
+		var p problem
+		if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+			t.Fatal(err)
+		}
+		if rec.Code != tt.wantCode || p.Code != tt.wantType {
+			t.Errorf("%v: got %d %q, want %d %q", tt.err, rec.Code, p.Code, tt.wantCode, tt.wantType)
+		}
+		if tt.wantCode == http.StatusServiceUnavailable && rec.Header().Get("Retry-After") == "" {
+			t.Errorf("%v: no Retry-After header", tt.err)
+		}
+	}
+
+	// nothing is written for a client that went away
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/songs", nil)
+	app.dbErrorResponse(rec, req, &dbio.Error{Op: "ListSongs", Err: context.Canceled})
+	if rec.Body.Len() != 0 {
+		t.Errorf("canceled: wrote %q", rec.Body.String())
+	}
+}
+
+func TestCheckStatusTransition(t *testing.T) {
+	app := &Application{Logger: log.New(io.Discard, "", 0)}
+	member := &models.User{Name: "ann", Role: models.RoleMember}
+
+	tests := []struct {
+		from, to string
+		wantCode int // 0 if the transition is allowed
+	}{
+		{models.StatusDraft, models.StatusInReview, 0},
+		{models.StatusDraft, models.StatusArchived, http.StatusUnprocessableEntity},
+		{models.StatusInReview, models.StatusPublished, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/v1/songs/start-me-up", nil)
+		ok := app.checkStatusTransition(rec, req, member, tt.from, tt.to)
+		if ok != (tt.wantCode == 0) || (!ok && rec.Code != tt.wantCode) {
+			t.Errorf("%s -> %s: ok = %v, status = %d, want %d", tt.from, tt.to, ok, rec.Code, tt.wantCode)
+		}
+	}
+}
+
+func TestCheckMayEdit(t *testing.T) {
+	app := &Application{Logger: log.New(io.Discard, "", 0)}
+	member := &models.User{Name: "ann", Role: models.RoleMember}
+	editor := &models.User{Name: "eve", Role: models.RoleEditor}
+
+	tests := []struct {
+		name     string
+		user     *models.User
+		song     models.Song
+		wantCode int // 0 if the song may be edited
+	}{
+		{"own draft", member, models.Song{Status: models.StatusDraft, CreatedBy: "ann"}, 0},
+		{"own published", member, models.Song{Status: models.StatusPublished, CreatedBy: "ann"}, http.StatusForbidden},
+		{"other's published", member, models.Song{Status: models.StatusPublished, CreatedBy: "bob"}, http.StatusForbidden},
+		{"other's draft", member, models.Song{Status: models.StatusDraft, CreatedBy: "bob"}, http.StatusNotFound},
+		{"editor on published", editor, models.Song{Status: models.StatusPublished, CreatedBy: "bob"}, 0},
+	}
+	for _, tt := range tests {
+		for _, method := range []string{http.MethodPut, http.MethodDelete} {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(method, "/v1/songs/start-me-up", nil)
+			ok := app.checkMayEdit(rec, req, tt.user, &tt.song)
+			if ok != (tt.wantCode == 0) || (!ok && rec.Code != tt.wantCode) {
+				t.Errorf("%s %s: ok = %v, status = %d, want %d", method, tt.name, ok, rec.Code, tt.wantCode)
+			}
+		}
+	}
+}
+
+func TestResolveKey(t *testing.T) {
+	app := &Application{Logger: log.New(io.Discard, "", 0)}
+	member := &models.User{Name: "ann", Role: models.RoleMember}
+	editor := &models.User{Name: "eve", Role: models.RoleEditor}
+	confidence := 0.9
+	detected := &models.Song{Key: "C", KeyConfidence: &confidence}
+	overridden := &models.Song{Key: "Am"}
+
+	tests := []struct {
+		name     string
+		user     *models.User
+		key      string
+		current  *models.Song
+		wantCode int // 0 if the key may be set
+		wantKey  string
+		detected bool
+	}{
+		{"detected", member, "", nil, 0, "G", true},
+		// the detected key "C" is sent back, the new chords are in G
+		{"detected sent back", member, "C", detected, 0, "G", true},
+		{"detected sent back by editor", editor, "C", detected, 0, "G", true},
+		{"kept override", member, "A minor", overridden, 0, "Am", false},
+		{"omitted override", member, "", overridden, 0, "Am", false},
+		{"omitted detected", member, "", detected, 0, "G", true},
+		{"new key", member, "D", detected, http.StatusForbidden, "", false},
+		{"override", editor, "A#", detected, 0, "Bb", false},
+		{"override on create", editor, "Em", nil, 0, "Em", false},
+	}
+	for _, tt := range tests {
+		s := models.Song{Key: tt.key, Text: "[G]Start me [D]up\n[Em]I'll never [C]stop [G]"}
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPut, "/v1/songs/start-me-up", nil)
+		ok := app.resolveKey(rec, req, tt.user, &s, tt.current)
+		if ok != (tt.wantCode == 0) || (!ok && rec.Code != tt.wantCode) {
+			t.Errorf("%s: ok = %v, status = %d, want %d", tt.name, ok, rec.Code, tt.wantCode)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if s.Key != tt.wantKey || (s.KeyConfidence != nil) != tt.detected {
+			t.Errorf("%s: key = %q, confidence = %v; want %q, detected %v", tt.name, s.Key, s.KeyConfidence, tt.wantKey, tt.detected)
+		}
+	}
+}
+
+func TestCreateSongWithUnknownChord(t *testing.T) {
+	app := &Application{Logger: log.New(io.Discard, "", 0)}
+
+	body := `{"artist": "The Rolling Stones", "name": "Start Me Up", "lyrics": "If you start me up", "chords": "Cmja7  F"}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/songs", strings.NewReader(body))
+	req = app.contextSetUser(req, &models.User{Name: "mick", Role: models.RoleEditor})
+	rec := httptest.NewRecorder()
+	app.HandleCreateSong(rec, req)
+
+	var p problem
+	if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusUnprocessableEntity || p.Code != "invalid_chord_sheet" {
+		t.Fatalf("status = %d, code = %q, want 422 invalid_chord_sheet", rec.Code, p.Code)
+	}
+	if len(p.Diagnostics) != 1 || p.Diagnostics[0].Code != "unknown_chord" || p.Diagnostics[0].Column != 1 {
+		t.Errorf("diagnostics = %+v", p.Diagnostics)
+	}
+}
@@ -3,6 +3,8 @@ package handlers
 import (
 	"database/sql"
 	"log"
+
+	"github.com/davidkuda/lyricsapi/cache"
 )
 
 type Application struct {
@@ -12,6 +14,16 @@ type Application struct {
 	CORS   struct {
 		TrustedOrigins []string
 	}
+	// SongCache serves reads of single songs, writes must invalidate it
+	SongCache *cache.Songs
+	// Env is the environment the app runs in, e.g. "development" or "production"
+	Env string
+	// Build holds information that is injected at build time, see main.go
+	Build struct {
+		Version string
+		Commit  string
+		Time    string
+	}
 }
 
 // func (app Application) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -1,23 +1,32 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
+	"time"
+
+	"github.com/davidkuda/lyricsapi/dbio"
 )
 
-func (app *Application) HandleHealthCheck(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
+// readinessTimeout bounds the time a single readiness check may take.
+const readinessTimeout = 2 * time.Second
 
-	w.Header().Set("Content-Type", "application/json")
+// expectedTables are the tables the API needs in order to serve requests.
+var expectedTables = []string{"songs", "song_aliases", "users", "sessions", "tags", "song_tags", "artists", "artist_aliases"}
+
+type check struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Duration string `json:"duration"`
+	Error    string `json:"error,omitempty"`
+}
 
+// HandleLiveness reports whether the process is up. It does not touch
+// any dependencies: if it can answer, it is alive.
+func (app *Application) HandleLiveness(w http.ResponseWriter, r *http.Request) {
 	env := envelope{
-		"status": "available",
-		"system_information": map[string]string{
-			"environment": "development",
-			"version":     "0.0.1",
-		},
+		"status":             "alive",
+		"system_information": app.systemInformation(),
 	}
 
 	err := app.writeJSON(w, http.StatusOK, env, nil)
@@ -25,3 +34,63 @@ func (app *Application) HandleHealthCheck(w http.ResponseWriter, r *http.Request
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+// HandleReadiness reports whether the app can serve traffic, i.e. whether
+// the database is reachable and has the expected schema.
+func (app *Application) HandleReadiness(w http.ResponseWriter, r *http.Request) {
+	checks := []check{
+		app.runCheck(r.Context(), "database", func(ctx context.Context) error {
+			return app.DB.PingContext(ctx)
+		}),
+		app.runCheck(r.Context(), "tables", func(ctx context.Context) error {
+			return dbio.CheckTables(ctx, app.DB, expectedTables)
+		}),
+	}
+
+	status, code := "ready", http.StatusOK
+	for _, c := range checks {
+		if c.Status != "up" {
+			status, code = "unavailable", http.StatusServiceUnavailable
+			break
+		}
+	}
+
+	env := envelope{
+		"status":             status,
+		"checks":             checks,
+		"system_information": app.systemInformation(),
+	}
+
+	err := app.writeJSON(w, code, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *Application) runCheck(ctx context.Context, name string, fn func(context.Context) error) check {
+	ctx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := fn(ctx)
+	c := check{
+		Name:     name,
+		Status:   "up",
+		Duration: time.Since(start).String(),
+	}
+	if err != nil {
+		app.Logger.Printf("readiness check %s: %v", name, err)
+		c.Status = "down"
+		c.Error = err.Error()
+	}
+	return c
+}
+
+func (app *Application) systemInformation() map[string]string {
+	return map[string]string{
+		"environment": app.Env,
+		"version":     app.Build.Version,
+		"commit":      app.Build.Commit,
+		"build_time":  app.Build.Time,
+	}
+}
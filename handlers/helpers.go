@@ -3,15 +3,15 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
-)
+	"net/url"
+	"strconv"
+	"strings"
 
-type JSONResponse struct {
-	Error   bool        `json:"error"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data",omitempty`
-}
+	"github.com/davidkuda/lyricsapi/validator"
+)
 
 type envelope map[string]any
 
@@ -58,17 +58,90 @@ func (app *Application) readJSON(
 	return nil
 }
 
-func (app *Application) errorJSON(w http.ResponseWriter, err error, status ...int) error {
-	statusCode := http.StatusBadRequest
-	if len(status) > 0 {
-		statusCode = status[0]
+// readFields parses a sparse fieldset such as "?fields=id,artist,name".
+// It returns defaults if the parameter is missing. Unknown fields are
+// recorded in v under the key "fields".
+func (app *Application) readFields(qs url.Values, allowed, defaults []string, v *validator.Validator) []string {
+	raw := qs.Get("fields")
+	if raw == "" {
+		return defaults
+	}
+
+	var fields []string
+	for _, f := range strings.Split(raw, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		if !contains(allowed, f) {
+			v.AddError("fields", fmt.Sprintf("unknown field %q, must be one of %s", f, strings.Join(allowed, ", ")))
+			continue
+		}
+		if !contains(fields, f) {
+			fields = append(fields, f)
+		}
+	}
+
+	if len(fields) == 0 {
+		v.AddError("fields", "must contain at least one field")
 	}
+	return fields
+}
 
-	var payload JSONResponse
-	payload.Error = true
-	payload.Message = err.Error()
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
 
-	env := envelope{"error": payload}
+// negotiateType returns the offer that the Accept header prefers, or the
+// first offer if the header is missing or accepts none of them. An exact
+// type beats "text/*", which beats "*/*"; on a tie, the type listed first
+// in the header wins.
+func negotiateType(header string, offers ...string) string {
+	if header == "" {
+		return offers[0]
+	}
 
-	return app.writeJSON(w, statusCode, env, nil)
+	best, bestQ, bestSpec := offers[0], 0.0, -1
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			if param = strings.TrimSpace(param); strings.HasPrefix(param, "q=") {
+				parsed, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+				if err != nil {
+					parsed = 0
+				}
+				q = parsed
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+
+		for _, offer := range offers {
+			spec := -1
+			switch {
+			case name == offer:
+				spec = 2
+			case strings.HasSuffix(name, "/*") && strings.HasPrefix(offer, strings.TrimSuffix(name, "*")):
+				spec = 1
+			case name == "*/*":
+				spec = 0
+			}
+			if spec < 0 {
+				continue
+			}
+			if q > bestQ || (q == bestQ && spec > bestSpec) {
+				best, bestQ, bestSpec = offer, q, spec
+			}
+		}
+	}
+	return best
 }
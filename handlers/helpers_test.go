@@ -0,0 +1,23 @@
+package handlers
+
+import "testing"
+
+func TestNegotiateType(t *testing.T) {
+	tests := map[string]string{
+		"":                                     "application/json",
+		"*/*":                                  "application/json",
+		"text/plain":                           "text/plain",
+		"text/*":                               "text/plain",
+		"text/html":                            "application/json",
+		"text/plain, application/json":         "text/plain",
+		"application/json, text/plain":         "application/json",
+		"text/plain;q=0.5, */*":                "application/json",
+		"text/plain; charset=utf-8, */*;q=0.1": "text/plain",
+		"text/plain;q=0":                       "application/json",
+	}
+	for header, want := range tests {
+		if got := negotiateType(header, "application/json", "text/plain"); got != want {
+			t.Errorf("negotiateType(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
@@ -1,12 +1,18 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+
+	"github.com/davidkuda/lyricsapi/dbio"
+	"github.com/davidkuda/lyricsapi/models"
+	"github.com/davidkuda/lyricsapi/router"
 )
 
 func (app *Application) EnableCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Add("Vary", "Origin")
+		addVary(w.Header(), "Origin")
 		origin := r.Header.Get("Origin")
 
 		if origin != "" {
@@ -14,8 +20,9 @@ func (app *Application) EnableCORS(next http.Handler) http.Handler {
 				if origin == app.CORS.TrustedOrigins[i] {
 					w.Header().Set("Access-Control-Allow-Origin", origin)
 					w.Header().Set("Access-Control-Allow-Credentials", "true")
-					w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
-					w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, credentials")
+					w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+					w.Header().Set("Access-Control-Expose-Headers", "ETag, Last-Modified, Deprecation, Link")
+					w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, credentials, If-Match, If-None-Match, If-Modified-Since")
 					break
 				}
 			}
@@ -33,3 +40,74 @@ func (app *Application) EnableCORS(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// RequireSession only calls next if the request carries a valid session
+// cookie. The user of the session is stored in the request context.
+func (app *Application) RequireSession(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := app.hasValidSessionCookie(w, r)
+		if !ok {
+			app.Logger.Printf("%s %s: Unauthorized Request", r.Method, r.URL.Path)
+			return
+		}
+
+		r = app.contextSetUser(r, user)
+		next(w, r)
+	}
+}
+
+// RequireEditor only calls next if the user of the session is an editor.
+func (app *Application) RequireEditor(next http.HandlerFunc) http.HandlerFunc {
+	return app.RequireSession(func(w http.ResponseWriter, r *http.Request) {
+		if !app.contextGetUser(r).IsEditor() {
+			app.notPermittedResponse(w, r)
+			return
+		}
+		next(w, r)
+	})
+}
+
+// RequireAdmin only calls next if the user of the session is an admin.
+func (app *Application) RequireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return app.RequireSession(func(w http.ResponseWriter, r *http.Request) {
+		if !app.contextGetUser(r).IsAdmin() {
+			app.notPermittedResponse(w, r)
+			return
+		}
+		next(w, r)
+	})
+}
+
+// IdentifyUser stores the user of the session in the request context, or
+// models.AnonymousUser if there is no valid session. Unlike RequireSession,
+// it lets anonymous requests through, for routes whose responses depend on
+// who asks.
+func (app *Application) IdentifyUser(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		addVary(w.Header(), "Cookie")
+
+		user, err := app.sessionUser(r)
+		if err != nil {
+			// a stale cookie must not keep anyone from reading songs
+			if !errors.Is(err, errNoSession) && !errors.Is(err, errSessionExpired) && !errors.Is(err, dbio.ErrNotFound) {
+				app.dbErrorResponse(w, r, err)
+				return
+			}
+			user = models.AnonymousUser
+		}
+
+		r = app.contextSetUser(r, user)
+		next(w, r)
+	}
+}
+
+// Deprecated marks responses of an unversioned alias as deprecated and
+// points clients to the successor, a router pattern such as
+// "/v1/songs/{id}" that is expanded with the parameters of the request.
+func (app *Application) Deprecated(successor string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", router.Expand(successor, r)))
+		next.ServeHTTP(w, r)
+	})
+}
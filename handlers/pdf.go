@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/davidkuda/lyricsapi/dbio"
+	"github.com/davidkuda/lyricsapi/models"
+	"github.com/davidkuda/lyricsapi/router"
+	"github.com/davidkuda/lyricsapi/sheet"
+	"github.com/davidkuda/lyricsapi/validator"
+)
+
+// Limits of PDF songbooks.
+const (
+	maxBundleSongs      = 200
+	maxBundleTitleChars = 200
+)
+
+// HandleSongPDF handles GET /songs/{id}.pdf. The song is laid out in
+// ?columns=1 (the default) or 2, with its chords moved by ?transpose=
+// semitones.
+func (app *Application) HandleSongPDF(w http.ResponseWriter, r *http.Request) {
+	id := router.Param(r, "id")
+
+	v := validator.New()
+	columns := readColumns(r.URL.Query(), v)
+	transpose := readTranspose(r.URL.Query(), v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	song, ok := app.visibleSong(w, r, id)
+	if !ok {
+		return
+	}
+	if checkNotModified(w, r, songPDFETag(&song, columns, transpose), song.UpdatedAt) {
+		return
+	}
+
+	song = sheet.Transpose(&song, transpose)
+	var b bytes.Buffer
+	if err := sheet.PDF(&b, []models.Song{song}, sheet.PDFOptions{Columns: columns}); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	writePDF(w, song.ID+".pdf", b.Bytes())
+}
+
+// HandleBundlePDF handles POST /bundle.pdf: a songbook of the songs in the
+// body, in their order, each transposed by its own number of semitones.
+// The songbook starts with a table of contents.
+//
+//	{"title": "Campfire", "columns": 2, "songs": [{"id": "wonderwall", "transpose": -2}]}
+func (app *Application) HandleBundlePDF(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Title   string `json:"title"`
+		Columns *int   `json:"columns"`
+		Songs   []struct {
+			ID        string `json:"id"`
+			Transpose int    `json:"transpose"`
+		} `json:"songs"`
+	}
+	if err := app.readJSON(w, r, &input); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	// like ?columns= of a single song, the default is one column
+	columns := 1
+	if input.Columns != nil {
+		columns = *input.Columns
+	}
+
+	v := validator.New()
+	v.Check(validator.MaxChars(input.Title, maxBundleTitleChars), "title", fmt.Sprintf("must not be more than %d characters long", maxBundleTitleChars))
+	v.Check(columns == 1 || columns == 2, "columns", "must be 1 or 2")
+	v.Check(len(input.Songs) > 0, "songs", "must contain at least one song")
+	v.Check(len(input.Songs) <= maxBundleSongs, "songs", fmt.Sprintf("must not contain more than %d songs", maxBundleSongs))
+	for i, s := range input.Songs {
+		key := fmt.Sprintf("songs[%d]", i)
+		v.Check(validator.Matches(s.ID, validator.SlugRX), key+".id", "must be the id of a song")
+		v.Check(s.Transpose >= -maxTranspose && s.Transpose <= maxTranspose, key+".transpose", fmt.Sprintf("must be a number from %d to %d", -maxTranspose, maxTranspose))
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	songs := make([]models.Song, len(input.Songs))
+	for i, s := range input.Songs {
+		song, err := app.bundleSong(r.Context(), s.ID)
+		if err != nil && !errors.Is(err, dbio.ErrNotFound) {
+			app.dbErrorResponse(w, r, err)
+			return
+		}
+		if err != nil || !song.VisibleTo(user) {
+			v.AddError(fmt.Sprintf("songs[%d].id", i), fmt.Sprintf("refers to the unknown song %q", s.ID))
+			continue
+		}
+		songs[i] = sheet.Transpose(&song, s.Transpose)
+	}
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	title := input.Title
+	if title == "" {
+		title = "Songbook"
+	}
+	var b bytes.Buffer
+	if err := sheet.PDF(&b, songs, sheet.PDFOptions{Title: title, Columns: columns, Contents: true}); err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	writePDF(w, "songbook.pdf", b.Bytes())
+}
+
+// bundleSong returns the song id from the cache, following renames.
+func (app *Application) bundleSong(ctx context.Context, id string) (models.Song, error) {
+	get := func(id string) (models.Song, error) {
+		return app.SongCache.Get(ctx, id, func(ctx context.Context) (models.Song, error) {
+			return dbio.GetSong(ctx, id, models.SongFields, app.DB, app.Logger)
+		})
+	}
+
+	song, err := get(id)
+	if !errors.Is(err, dbio.ErrNotFound) {
+		return song, err
+	}
+	newID, err := dbio.ResolveSongAlias(ctx, id, app.DB)
+	if err != nil {
+		return models.Song{}, err
+	}
+	return get(newID)
+}
+
+func writePDF(w http.ResponseWriter, filename string, body []byte) {
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename))
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// maxTranspose is the most semitones chords can be moved up or down.
+const maxTranspose = 11
+
+// readColumns parses ?columns=, 1 or 2. It returns 1 if the parameter is
+// missing.
+func readColumns(qs url.Values, v *validator.Validator) int {
+	switch qs.Get("columns") {
+	case "", "1":
+		return 1
+	case "2":
+		return 2
+	}
+	v.AddError("columns", "must be 1 or 2")
+	return 1
+}
+
+// readTranspose parses ?transpose=, the semitones to move the chords by.
+// It returns 0 if the parameter is missing.
+func readTranspose(qs url.Values, v *validator.Validator) int {
+	raw := qs.Get("transpose")
+	if raw == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < -maxTranspose || n > maxTranspose {
+		v.AddError("transpose", fmt.Sprintf("must be a number from %d to %d", -maxTranspose, maxTranspose))
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/davidkuda/lyricsapi/models"
+	"github.com/davidkuda/lyricsapi/validator"
+)
+
+func TestHandleBundlePDFValidation(t *testing.T) {
+	app := &Application{Logger: log.New(io.Discard, "", 0)}
+
+	body := `{"columns": 3, "songs": [{"id": "Not A Slug"}, {"id": "wonderwall", "transpose": 12}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/bundle.pdf", strings.NewReader(body))
+	req = app.contextSetUser(req, models.AnonymousUser)
+	rec := httptest.NewRecorder()
+	app.HandleBundlePDF(rec, req)
+
+	var p problem
+	if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422", rec.Code)
+	}
+	var fields []string
+	for _, e := range p.Errors {
+		fields = append(fields, e.Field)
+	}
+	if got, want := strings.Join(fields, " "), "columns songs[0].id songs[1].transpose"; got != want {
+		t.Errorf("invalid fields = %q, want %q", got, want)
+	}
+}
+
+func TestHandleBundlePDFColumns(t *testing.T) {
+	app := &Application{Logger: log.New(io.Discard, "", 0)}
+
+	for _, columns := range []string{"0", "-1", "3"} {
+		body := `{"columns": ` + columns + `, "songs": [{"id": "wonderwall"}]}`
+		req := httptest.NewRequest(http.MethodPost, "/v1/bundle.pdf", strings.NewReader(body))
+		req = app.contextSetUser(req, models.AnonymousUser)
+		rec := httptest.NewRecorder()
+		app.HandleBundlePDF(rec, req)
+
+		var p problem
+		json.NewDecoder(rec.Body).Decode(&p)
+		if rec.Code != http.StatusUnprocessableEntity || len(p.Errors) != 1 || p.Errors[0].Field != "columns" {
+			t.Errorf("columns %s: status %d, errors %+v, want 422 for columns", columns, rec.Code, p.Errors)
+		}
+	}
+}
+
+func TestReadPDFOptions(t *testing.T) {
+	v := validator.New()
+	qs := url.Values{"columns": {"2"}, "transpose": {"-3"}}
+	if columns, transpose := readColumns(qs, v), readTranspose(qs, v); columns != 2 || transpose != -3 || !v.Valid() {
+		t.Errorf("columns %d, transpose %d, errors %v", columns, transpose, v.Errors)
+	}
+
+	qs = url.Values{"columns": {"3"}, "transpose": {"up"}}
+	readColumns(qs, v)
+	readTranspose(qs, v)
+	if _, ok := v.Errors["columns"]; !ok {
+		t.Errorf("columns=3 was accepted")
+	}
+	if _, ok := v.Errors["transpose"]; !ok {
+		t.Errorf("transpose=up was accepted")
+	}
+
+	if got := songPDFETag(&models.Song{Version: 42}, 2, 3); got != `"42-pdf2+3"` {
+		t.Errorf("songPDFETag() = %s", got)
+	}
+}
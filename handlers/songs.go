@@ -1,138 +1,557 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
-	"log"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 
+	"github.com/davidkuda/lyricsapi/chord"
 	"github.com/davidkuda/lyricsapi/dbio"
 	"github.com/davidkuda/lyricsapi/models"
+	"github.com/davidkuda/lyricsapi/router"
+	"github.com/davidkuda/lyricsapi/sheet"
+	"github.com/davidkuda/lyricsapi/slug"
+	"github.com/davidkuda/lyricsapi/validator"
 )
 
-// /songs
-func (a *Application) HandleSongsFixedPath(w http.ResponseWriter, r *http.Request) {
-	if r.Method == http.MethodGet {
-		listSongs(w, r, a)
+// HandleCreateSong handles POST /songs. Songs are created as drafts,
+// unless the body asks for another status the user may set. If the song
+// has sections, its lyrics and chords are derived from them. Chord sheets
+// with unknown chords are rejected, other findings of the linter are
+// returned as warnings.
+func (app *Application) HandleCreateSong(w http.ResponseWriter, r *http.Request) {
+	user := app.contextGetUser(r)
+
+	s := models.Song{}
+	if err := app.readJSON(w, r, &s); err != nil {
+		app.badRequestResponse(w, r, err)
 		return
 	}
 
-	ok, _ := a.hasValidSessionCookie(w, r)
-	if !ok {
-		a.Logger.Println("HandleSongsFixedPath: Unauthorized Request")
+	// the derived lyrics and chords are subject to the same limits
+	s.DeriveTextAndChords()
+
+	v := validator.New()
+	if models.ValidateSong(v, &s); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
-
-	if r.Method == http.MethodPost {
-		a.createSong(w, r)
+	diagnostics := sheet.Lint(&s)
+	if sheet.HasErrors(diagnostics) {
+		app.invalidChordSheetResponse(w, r, diagnostics)
 		return
 	}
+	if s.Status == "" {
+		s.Status = models.StatusDraft
+	}
+	if !app.checkStatusTransition(w, r, user, models.StatusDraft, s.Status) {
+		return
+	}
+	if !app.resolveKey(w, r, user, &s, nil) {
+		return
+	}
+	s.CreatedBy = user.Name
 
-	if r.Method == http.MethodOptions {
-		w.WriteHeader(http.StatusOK)
+	if s.ID == "" {
+		id, err := app.generateSongID(r.Context(), &s)
+		if err != nil {
+			app.dbErrorResponse(w, r, err)
+			return
+		}
+		s.ID = id
+	}
+
+	if err := dbio.CreateSong(r.Context(), &s, app.DB, app.Logger); err != nil {
+		if errors.Is(err, dbio.ErrConflict) {
+			app.conflictResponse(w, r, fmt.Sprintf("the id %q is already taken", s.ID))
+			return
+		}
+		app.dbErrorResponse(w, r, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusMethodNotAllowed)
-	return
+	env := envelope{"status": "Success: Created New Song", "id": s.ID}
+	if len(diagnostics) > 0 {
+		env["diagnostics"] = diagnostics
+	}
+	if err := app.writeJSON(w, http.StatusCreated, env, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
 }
 
-// /songs/:id
-func (a *Application) HandleSongsSubtreePath(w http.ResponseWriter, r *http.Request) {
-	id := strings.TrimPrefix(r.URL.Path, "/songs/")
+// HandleUpdateSong handles PUT /songs/{id}. A different id in the body
+// renames the song; the old id keeps redirecting to the new one. A status
+// in the body moves the song through the workflow. Members may only edit
+// their own songs that haven't been published.
+func (app *Application) HandleUpdateSong(w http.ResponseWriter, r *http.Request) {
+	id := router.Param(r, "id")
+	user := app.contextGetUser(r)
 
-	if r.Method == http.MethodGet {
-		returnSong(w, r, id, a)
+	s := models.Song{}
+	if err := app.readJSON(w, r, &s); err != nil {
+		app.badRequestResponse(w, r, err)
 		return
 	}
+	if s.ID == "" {
+		s.ID = id
+	}
 
-	ok, _ := a.hasValidSessionCookie(w, r)
-	if !ok {
-		a.Logger.Println("HandleSong beyond Get: Invalid Session")
+	// the derived lyrics and chords are subject to the same limits
+	s.DeriveTextAndChords()
+
+	v := validator.New()
+	if models.ValidateSong(v, &s); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+	diagnostics := sheet.Lint(&s)
+	if sheet.HasErrors(diagnostics) {
+		app.invalidChordSheetResponse(w, r, diagnostics)
 		return
 	}
 
-	if r.Method == http.MethodDelete {
-		a.handleDeleteSong(w, id)
+	current, err := dbio.GetSong(r.Context(), id, []string{"key", "keyConfidence"}, app.DB, app.Logger)
+	if err != nil {
+		app.dbErrorResponse(w, r, err)
+		return
+	}
+	if !app.checkMayEdit(w, r, user, &current) {
+		return
+	}
+	if s.Status != "" && !app.checkStatusTransition(w, r, user, current.Status, s.Status) {
+		return
+	}
+	if !app.resolveKey(w, r, user, &s, &current) {
 		return
 	}
 
-	w.WriteHeader(http.StatusMethodNotAllowed)
-	return
+	if s.ID != id {
+		taken, err := dbio.SongIDTaken(r.Context(), s.ID, app.DB)
+		if err != nil {
+			app.dbErrorResponse(w, r, err)
+			return
+		}
+		alias, err := dbio.ResolveSongAlias(r.Context(), s.ID, app.DB)
+		if err != nil && !errors.Is(err, dbio.ErrNotFound) {
+			app.dbErrorResponse(w, r, err)
+			return
+		}
+		// renaming a song back to one of its own old ids is fine
+		if taken && alias != id {
+			app.conflictResponse(w, r, fmt.Sprintf("the id %q is already taken", s.ID))
+			return
+		}
+	}
+
+	if err := dbio.UpdateSong(r.Context(), id, &s, ifMatchVersions(r), app.DB, app.Logger); err != nil {
+		app.dbErrorResponse(w, r, err)
+		return
+	}
+
+	app.SongCache.Invalidate(r.Context(), id)
+	app.SongCache.Invalidate(r.Context(), s.ID)
+
+	headers := http.Header{}
+	headers.Set("ETag", songETag(&s, models.SongFields))
+	headers.Set("Last-Modified", s.UpdatedAt.UTC().Format(http.TimeFormat))
+
+	env := envelope{"status": "Success: Updated Song", "id": s.ID}
+	if len(diagnostics) > 0 {
+		env["diagnostics"] = diagnostics
+	}
+	if err := app.writeJSON(w, http.StatusOK, env, headers); err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
 }
 
-func (app *Application) createSong(w http.ResponseWriter, r *http.Request) {
-	s := models.Song{}
-	data, err := io.ReadAll(r.Body)
+// checkMayEdit responds with an error and returns false unless user may
+// change or delete current, see models.Song.MayEdit. Songs the user can't
+// see don't exist for them.
+func (app *Application) checkMayEdit(w http.ResponseWriter, r *http.Request, user *models.User, current *models.Song) bool {
+	if !current.VisibleTo(user) {
+		app.notFoundResponse(w, r)
+		return false
+	}
+	if !current.MayEdit(user) {
+		app.notPermittedResponse(w, r)
+		return false
+	}
+	return true
+}
+
+// checkStatusTransition responds with an error and returns false if user
+// may not move a song from one status to another.
+func (app *Application) checkStatusTransition(w http.ResponseWriter, r *http.Request, user *models.User, from, to string) bool {
+	if !models.CanTransition(from, to) {
+		v := validator.New()
+		v.AddError("status", fmt.Sprintf("can't change from %s to %s", from, to))
+		app.failedValidationResponse(w, r, v.Errors)
+		return false
+	}
+	if !user.MayTransition(from, to) {
+		app.notPermittedResponse(w, r)
+		return false
+	}
+	return true
+}
+
+// resolveKey sets the key of s, which has been validated. A key an editor
+// set is kept, whether the client sends it back or omits it. A detected
+// key follows the chords: it is detected again from the new chords if the
+// client omits it or sends it back unchanged. Only editors may set a
+// different key, which then overrides the detection. If user may not set
+// the key, it responds with an error and returns false. current is nil for
+// new songs.
+func (app *Application) resolveKey(w http.ResponseWriter, r *http.Request, user *models.User, s *models.Song, current *models.Song) bool {
+	// the confidence is never taken from the client
+	s.KeyConfidence = nil
+	if s.Key == "" && current != nil && current.Key != "" && current.KeyConfidence == nil {
+		s.Key = current.Key
+		return true
+	}
+	if s.Key == "" {
+		sheet.DetectKey(s)
+		return true
+	}
+
+	k, _ := chord.ParseKey(s.Key)
+	s.Key = k.String()
+	if current != nil && current.Key == s.Key {
+		// sending the detected key back doesn't make it an override
+		if current.KeyConfidence != nil {
+			sheet.DetectKey(s)
+		}
+		return true
+	}
+
+	if !user.IsEditor() {
+		app.notPermittedResponse(w, r)
+		return false
+	}
+	return true
+}
+
+// maxSlugCandidates limits how many ids are tried before giving up.
+const maxSlugCandidates = 100
+
+// generateSongID returns the first free slug for the song, see
+// slug.Candidates for the order in which slugs are tried.
+func (app *Application) generateSongID(ctx context.Context, s *models.Song) (string, error) {
+	var id string
+	var err error
+
+	slug.Candidates(s.Name, s.Artist, maxSlugCandidates, func(candidate string) bool {
+		var taken bool
+		taken, err = dbio.SongIDTaken(ctx, candidate, app.DB)
+		if err != nil {
+			return true
+		}
+		if !taken {
+			id = candidate
+		}
+		return !taken
+	})
+
+	if err != nil {
+		return "", err
+	}
+	if id == "" {
+		return "", fmt.Errorf("no free id for song %q by %q", s.Name, s.Artist)
+	}
+	return id, nil
+}
+
+// HandleDeleteSong handles DELETE /songs/{id}. The song is moved to the
+// trash, from where admins can restore it until it is purged. Members may
+// only delete their own songs that haven't been published.
+func (app *Application) HandleDeleteSong(w http.ResponseWriter, r *http.Request) {
+	songID := router.Param(r, "id")
+
+	current, err := dbio.GetSong(r.Context(), songID, nil, app.DB, app.Logger)
 	if err != nil {
-		app.Logger.Println("io.ReadAll:", err)
+		app.dbErrorResponse(w, r, err)
+		return
 	}
-	defer r.Body.Close()
-	if err := json.Unmarshal(data, &s); err != nil {
-		app.Logger.Println("json.Unmarshal:", err)
-		w.WriteHeader(http.StatusInternalServerError)
+	if !app.checkMayEdit(w, r, app.contextGetUser(r), &current) {
 		return
 	}
-	if err := dbio.CreateSong(&s, app.DB, app.Logger); err != nil {
-		app.Logger.Println("dbio.CreateSong:", err)
-		w.WriteHeader(http.StatusInternalServerError)
+
+	if err := dbio.DeleteSong(r.Context(), songID, ifMatchVersions(r), app.DB, app.Logger); err != nil {
+		app.dbErrorResponse(w, r, err)
 		return
 	}
+	app.SongCache.Invalidate(r.Context(), songID)
 
-	env := envelope{"status": "Success: Created New Song"} 
-	app.writeJSON(w, http.StatusCreated, env, nil)
+	env := envelope{"status": "Success: Moved Song with ID " + songID + " to the trash"}
+	if err := app.writeJSON(w, http.StatusOK, env, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
 }
 
-func (app *Application) handleDeleteSong(w http.ResponseWriter, songID string) {
-	if err := dbio.DeleteSong(songID, app.DB, app.Logger); err != nil {
-		app.Logger.Println("dbio.DeleteSong:", err)
-		w.WriteHeader(http.StatusInternalServerError)
+// HandleListSongs handles GET /songs. Without ?fields= only the summary of
+// each song is sent. See readSongFilter for the songs that are listed.
+func (app *Application) HandleListSongs(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	v := validator.New()
+	fields := app.readFields(qs, models.SongFields, models.SongSummaryFields, v)
+	filter := app.readSongFilter(qs, app.contextGetUser(r), v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
-	w.Write([]byte("Success: Deleted Song with ID " + songID))
-}
 
-func listSongs(w http.ResponseWriter, r *http.Request, app *Application) {
-	songs := dbio.ListSongs(app.DB)
-	// ? how to only send the fields Song.Artist and Song.SongName? i.e. omit SongText
-	body, err := json.Marshal(songs)
+	songs, err := dbio.ListSongs(r.Context(), app.DB, fields, filter)
 	if err != nil {
-		status := http.StatusInternalServerError
-		log.Printf("%s %s: Error: %d %s", r.URL, r.Method, status, err)
-		http.Error(w, http.StatusText(status), status)
+		app.dbErrorResponse(w, r, err)
+		return
+	}
+
+	// the songs that are listed can't tell when one was deleted
+	lastModified, err := dbio.SongsLastModified(r.Context(), app.DB)
+	if err != nil {
+		app.dbErrorResponse(w, r, err)
+		return
+	}
+
+	// only the requested fields are sent, even if they are empty
+	body := make([]map[string]any, 0, len(songs))
+	for i := range songs {
+		body = append(body, songs[i].Select(fields))
+	}
+
+	js, err := json.Marshal(body)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
 	}
+
+	if checkNotModified(w, r, bodyETag(js), lastModified) {
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	w.Write(body)
+	w.Write(js)
 }
 
-func returnSong(w http.ResponseWriter, r *http.Request, id string, app *Application) {
-	song, err := dbio.GetSong(id, app.DB, app.Logger)
+// readSongFilter parses the query parameters that filter songs: ?status=,
+// ?tags=a,b (songs with all of them), ?genre= (including its subgenres),
+// ?key= (e.g. "Am", enharmonic keys match) and ?language=. Invalid values
+// are recorded in v.
+//
+// Anonymous users only see the published songs, authors also their own
+// unpublished songs and editors all songs.
+func (app *Application) readSongFilter(qs url.Values, user *models.User, v *validator.Validator) dbio.SongFilter {
+	var filter dbio.SongFilter
 
-	if err != nil {
-		if err == dbio.ErrSongDoesNotExist {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusNotFound)
-			resp := make(map[string]string)
-			resp["message"] = err.Error()
-			jsonResp, err := json.Marshal(resp)
-			if err != nil {
-				app.Logger.Printf("Error happened in JSON marshal. Err: %s", err)
+	switch {
+	case user.IsEditor():
+		filter.AllStatuses = true
+	case !user.IsAnonymous():
+		filter.Author = user.Name
+	}
+
+	if status := qs.Get("status"); status != "" {
+		v.Check(validator.PermittedValue(status, models.SongStatuses...), "status", "must be one of "+strings.Join(models.SongStatuses, ", "))
+		filter.Status = status
+	}
+
+	if raw := qs.Get("tags"); raw != "" {
+		for _, tag := range strings.Split(raw, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag != "" && !contains(filter.Tags, tag) {
+				filter.Tags = append(filter.Tags, tag)
 			}
-			w.Write(jsonResp)
-			return
 		}
 	}
 
-	body, err := json.Marshal(song)
+	if genre := qs.Get("genre"); genre != "" {
+		v.Check(models.GenreExists(genre), "genre", "must be a genre of the taxonomy, see GET /v1/genres")
+		filter.Genres = models.GenreWithSubgenres(genre)
+	}
+
+	if raw := qs.Get("key"); raw != "" {
+		k, err := chord.ParseKey(raw)
+		v.Check(err == nil, "key", "must be a major or minor key, e.g. \"C\", \"F#m\" or \"Bb major\"")
+		filter.Key = k.String()
+	}
+
+	if language := qs.Get("language"); language != "" {
+		v.Check(models.Languages[language] != "", "language", "must be an ISO 639-1 code in lower case, e.g. \"en\" or \"de\"")
+		filter.Language = language
+	}
+
+	return filter
+}
+
+// HandleShowSong handles GET /songs/{id}. Without ?fields= the whole song
+// is sent. Clients that prefer text/plain get a chord sheet with lines of
+// at most ?width= characters instead, see sheet.Text. With ?notation=
+// nashville or roman, the chords are written as degrees of the key of the
+// song, see chord.Chord.Nashville and chord.Chord.Roman.
+func (app *Application) HandleShowSong(w http.ResponseWriter, r *http.Request) {
+	id := router.Param(r, "id")
+	addVary(w.Header(), "Accept")
+	text := negotiateType(r.Header.Get("Accept"), "application/json", "text/plain") == "text/plain"
+
+	v := validator.New()
+	fields := app.readFields(r.URL.Query(), models.SongFields, models.SongFields, v)
+	width := readWidth(r.URL.Query(), v)
+	notation := readNotation(r.URL.Query(), v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// the cache holds whole songs, the requested fields are picked below
+	song, ok := app.visibleSong(w, r, id)
+	if !ok {
+		return
+	}
+	if notation != "" && song.Key == "" {
+		v.AddError("notation", "the song has no key to count the chords from")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	etag := songETag(&song, fields)
+	if text {
+		etag = songTextETag(&song, width)
+	}
+	if notation != "" {
+		etag = notationETag(etag, notation)
+	}
+	if checkNotModified(w, r, etag, song.UpdatedAt) {
+		return
+	}
+
+	if notation != "" {
+		song = withNotation(&song, notation)
+	}
+
+	if text {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, sheet.Text(&song, width))
+		return
+	}
+
+	js, err := json.Marshal(song.Select(fields))
 	if err != nil {
-		status := http.StatusInternalServerError
-		log.Printf("%s %s: Error: %d %s", r.URL, r.Method, status, err)
-		http.Error(w, http.StatusText(status), status)
+		app.serverErrorResponse(w, r, err)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	w.Write(body)
+	w.Write(js)
+}
+
+// The notations of chords besides their names, see readNotation.
+const (
+	notationNashville = "nashville"
+	notationRoman     = "roman"
+)
+
+// readNotation parses ?notation=, the notation of the chords: "nashville"
+// or "roman". It returns "" for chord names, the default.
+func readNotation(qs url.Values, v *validator.Validator) string {
+	notation := qs.Get("notation")
+	if notation != "" {
+		v.Check(validator.PermittedValue(notation, notationNashville, notationRoman), "notation", "must be nashville or roman")
+	}
+	return notation
+}
+
+// withNotation returns s with its chords written in notation, counted from
+// the key of s, which must be set.
+func withNotation(s *models.Song, notation string) models.Song {
+	k, _ := chord.ParseKey(s.Key)
+	return sheet.MapChords(s, func(name string) string {
+		c, _ := chord.Parse(name)
+		if notation == notationRoman {
+			return c.Roman(k)
+		}
+		return c.Nashville(k)
+	})
+}
+
+// Limits of the ?width= of plain text sheets.
+const (
+	minSheetWidth = 20
+	maxSheetWidth = 200
+)
+
+// readWidth parses ?width=, the width of plain text sheets. It returns
+// sheet.DefaultWidth if the parameter is missing.
+func readWidth(qs url.Values, v *validator.Validator) int {
+	raw := qs.Get("width")
+	if raw == "" {
+		return sheet.DefaultWidth
+	}
+	width, err := strconv.Atoi(raw)
+	if err != nil || width < minSheetWidth || width > maxSheetWidth {
+		v.AddError("width", fmt.Sprintf("must be a number from %d to %d", minSheetWidth, maxSheetWidth))
+		return sheet.DefaultWidth
+	}
+	return width
+}
+
+// visibleSong returns the whole song id from the cache. If the song doesn't
+// exist or the user may not see it, it responds with an error, or with a
+// redirect if the song was renamed, and returns false.
+func (app *Application) visibleSong(w http.ResponseWriter, r *http.Request, id string) (models.Song, bool) {
+	song, err := app.SongCache.Get(r.Context(), id, func(ctx context.Context) (models.Song, error) {
+		return dbio.GetSong(ctx, id, models.SongFields, app.DB, app.Logger)
+	})
+	if err != nil {
+		if errors.Is(err, dbio.ErrNotFound) {
+			app.redirectRenamedSong(w, r, id)
+			return models.Song{}, false
+		}
+		app.dbErrorResponse(w, r, err)
+		return models.Song{}, false
+	}
+	if !song.VisibleTo(app.contextGetUser(r)) {
+		app.notFoundResponse(w, r)
+		return models.Song{}, false
+	}
+	return song, true
+}
+
+// redirectRenamedSong permanently redirects requests for the old id of a
+// renamed song to its current id, or responds with 404 if id is unknown.
+// The id is replaced in the segment after "songs", so that requests for
+// sub-resources such as /songs/{id}/chords are redirected too.
+func (app *Application) redirectRenamedSong(w http.ResponseWriter, r *http.Request, id string) {
+	newID, err := dbio.ResolveSongAlias(r.Context(), id, app.DB)
+	if err != nil {
+		app.dbErrorResponse(w, r, err)
+		return
+	}
+
+	u := *r.URL
+	u.Path = renamedSongPath(r.URL.Path, id, newID)
+	u.RawPath = ""
+	http.Redirect(w, r, u.RequestURI(), http.StatusMovedPermanently)
+}
+
+// renamedSongPath returns p with the id in the segment after "songs"
+// replaced by newID. An extension such as ".pdf" is kept.
+func renamedSongPath(p, id, newID string) string {
+	parts := strings.Split(p, "/")
+	for i := 1; i < len(parts); i++ {
+		if parts[i-1] == "songs" && (parts[i] == id || strings.HasPrefix(parts[i], id+".")) {
+			parts[i] = newID + strings.TrimPrefix(parts[i], id)
+			break
+		}
+	}
+	return strings.Join(parts, "/")
 }
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/davidkuda/lyricsapi/chord"
+	"github.com/davidkuda/lyricsapi/dbio"
+	"github.com/davidkuda/lyricsapi/models"
+	"github.com/davidkuda/lyricsapi/router"
+	"github.com/davidkuda/lyricsapi/sheet"
+	"github.com/davidkuda/lyricsapi/songxml"
+	"github.com/davidkuda/lyricsapi/validator"
+)
+
+// Limits of imports.
+const (
+	maxImportBytes     = 20 << 20 // of the request body
+	maxImportFileBytes = 1 << 20
+	maxImportFiles     = 500
+)
+
+// HandleSongXML handles GET /songs/{id}.xml, the song as an XML file for
+// presentation software in ?format=openlyrics (the default) or opensong.
+func (app *Application) HandleSongXML(w http.ResponseWriter, r *http.Request) {
+	id := router.Param(r, "id")
+
+	format := songxml.OpenLyrics
+	if raw := r.URL.Query().Get("format"); raw != "" {
+		v := validator.New()
+		if v.Check(validator.PermittedValue(raw, songxml.Formats...), "format", "must be one of "+strings.Join(songxml.Formats, ", ")); !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+		format = songxml.Format(raw)
+	}
+
+	song, ok := app.visibleSong(w, r, id)
+	if !ok {
+		return
+	}
+	if checkNotModified(w, r, notationETag(songETag(&song, models.SongFields), string(format)), song.UpdatedAt) {
+		return
+	}
+
+	body, err := songxml.Marshal(&song, format)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", song.ID+".xml"))
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// importResult reports the import of a file. Files that could not be
+// imported have an error, and the invalid fields or the diagnostics of the
+// chord sheet if that is why.
+type importResult struct {
+	File        string             `json:"file"`
+	Format      songxml.Format     `json:"format,omitempty"`
+	ID          string             `json:"id,omitempty"`
+	Error       string             `json:"error,omitempty"`
+	Errors      []fieldError       `json:"errors,omitempty"`
+	Diagnostics []sheet.Diagnostic `json:"diagnostics,omitempty"`
+}
+
+// HandleImportSongs handles POST /songs/import. The body is an OpenLyrics
+// or OpenSong file (application/xml), or a ZIP archive of such files
+// (application/zip). Each song is created as a draft of the user. A file
+// that can't be imported doesn't stop the others; the response reports
+// each file:
+//
+//	{"imported": 1, "failed": 1, "results": [{"file": "a.xml", "format": "openlyrics", "id": "amazing-grace"}, {"file": "b.xml", "error": "…"}]}
+func (app *Application) HandleImportSongs(w http.ResponseWriter, r *http.Request) {
+	mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	archive := false
+	switch mediaType {
+	case "application/zip", "application/x-zip-compressed":
+		archive = true
+	case "application/xml", "text/xml":
+	default:
+		app.errorResponse(w, r, http.StatusUnsupportedMediaType, "unsupported_media_type", "the body must be an XML file (application/xml) or a ZIP archive of XML files (application/zip)")
+		return
+	}
+	if charset := strings.ToLower(params["charset"]); charset != "" && charset != "utf-8" {
+		app.errorResponse(w, r, http.StatusUnsupportedMediaType, "unsupported_media_type", "XML files must be encoded in UTF-8")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportBytes))
+	if err != nil {
+		app.badRequestResponse(w, r, fmt.Errorf("the body must not be larger than %d MB", maxImportBytes>>20))
+		return
+	}
+
+	user := app.contextGetUser(r)
+	var results []importResult
+	if archive {
+		zr, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+		if err != nil {
+			app.badRequestResponse(w, r, fmt.Errorf("the body is not a ZIP archive: %w", err))
+			return
+		}
+		var entries []*zip.File
+		for _, f := range zr.File {
+			if importable(f.Name) {
+				entries = append(entries, f)
+			}
+		}
+		if !app.checkImportFiles(w, r, len(entries)) {
+			return
+		}
+		// each file is read right before it is imported, so that only one is
+		// held in memory however well the archive is compressed
+		for _, f := range entries {
+			data, reason := readZipFile(f)
+			if reason != "" {
+				results = append(results, importResult{File: f.Name, Error: reason})
+				continue
+			}
+			results = append(results, app.importSong(r.Context(), user, f.Name, data))
+		}
+	} else {
+		name := "song.xml"
+		if _, params, err := mime.ParseMediaType(r.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+			name = params["filename"]
+		}
+		if len(body) > maxImportFileBytes {
+			results = append(results, importResult{File: name, Error: fmt.Sprintf("is larger than %d MB", maxImportFileBytes>>20)})
+		} else {
+			results = append(results, app.importSong(r.Context(), user, name, body))
+		}
+	}
+
+	imported := 0
+	for _, res := range results {
+		if res.ID != "" {
+			imported++
+		}
+	}
+	env := envelope{"imported": imported, "failed": len(results) - imported, "results": results}
+	if err := app.writeJSON(w, http.StatusOK, env, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// checkImportFiles responds with an error and returns false unless an
+// archive with n songs may be imported.
+func (app *Application) checkImportFiles(w http.ResponseWriter, r *http.Request, n int) bool {
+	v := validator.New()
+	v.Check(n > 0, "body", "must contain at least one XML file")
+	v.Check(n <= maxImportFiles, "body", fmt.Sprintf("must not contain more than %d files", maxImportFiles))
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return false
+	}
+	return true
+}
+
+// importable reports whether the file of an archive is a song, and not a
+// directory or the metadata of macOS.
+func importable(name string) bool {
+	if strings.HasSuffix(name, "/") || strings.HasPrefix(name, "__MACOSX/") {
+		return false
+	}
+	return !strings.HasPrefix(path.Base(name), ".")
+}
+
+// readZipFile returns the content of f, or why it can't be imported.
+func readZipFile(f *zip.File) ([]byte, string) {
+	tooLarge := fmt.Sprintf("is larger than %d MB", maxImportFileBytes>>20)
+	if f.UncompressedSize64 > maxImportFileBytes {
+		return nil, tooLarge
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, "can't be read from the archive: " + err.Error()
+	}
+	defer rc.Close()
+	// the size in the header may lie
+	data, err := io.ReadAll(io.LimitReader(rc, maxImportFileBytes+1))
+	if err != nil {
+		return nil, "can't be read from the archive: " + err.Error()
+	}
+	if len(data) > maxImportFileBytes {
+		return nil, tooLarge
+	}
+	return data, ""
+}
+
+// importSong creates the song in data as a draft of user, like POST /songs
+// does. Only editors may set the key; for others it is detected.
+func (app *Application) importSong(ctx context.Context, user *models.User, name string, data []byte) importResult {
+	res := importResult{File: name}
+	s, format, err := songxml.Unmarshal(data)
+	res.Format = format
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	s.DeriveTextAndChords()
+	v := validator.New()
+	if models.ValidateSong(v, &s); !v.Valid() {
+		res.Error = "the song contains invalid fields"
+		res.Errors = fieldErrors(v.Errors)
+		return res
+	}
+	res.Diagnostics = sheet.Lint(&s)
+	if sheet.HasErrors(res.Diagnostics) {
+		res.Error = "the chord sheet contains errors"
+		return res
+	}
+
+	s.Status = models.StatusDraft
+	s.KeyConfidence = nil
+	if !user.IsEditor() {
+		s.Key = ""
+	}
+	if s.Key == "" {
+		sheet.DetectKey(&s)
+	} else {
+		k, _ := chord.ParseKey(s.Key)
+		s.Key = k.String()
+	}
+	s.CreatedBy = user.Name
+
+	id, err := app.generateSongID(ctx, &s)
+	if err == nil {
+		s.ID = id
+		err = dbio.CreateSong(ctx, &s, app.DB, app.Logger)
+	}
+	if err != nil {
+		if !errors.Is(err, dbio.ErrConflict) {
+			app.Logger.Printf("import %s: %v", name, err)
+		}
+		res.Error = "the song could not be stored, try again later"
+		return res
+	}
+	res.ID = s.ID
+	return res
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/davidkuda/lyricsapi/models"
+)
+
+func TestHandleImportSongsReport(t *testing.T) {
+	app := &Application{Logger: log.New(io.Discard, "", 0)}
+
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+	for name, content := range map[string]string{
+		"songs/":                  "",
+		"__MACOSX/songs/._a.xml":  "metadata",
+		"songs/.DS_Store":         "metadata",
+		"songs/notes.txt":         "not a song",
+		"songs/unknown-chord.xml": `<song><title>Start Me Up</title><author>The Rolling Stones</author><lyrics>.Cmja7&#10; If you start me up</lyrics></song>`,
+		"songs/no-title.xml":      `<song><author>The Rolling Stones</author><lyrics> If you start me up</lyrics></song>`,
+	} {
+		f, _ := zw.Create(name)
+		io.WriteString(f, content)
+	}
+	zw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/songs/import", &b)
+	req.Header.Set("Content-Type", "application/zip")
+	req = app.contextSetUser(req, &models.User{Name: "mick", Role: models.RoleEditor})
+	rec := httptest.NewRecorder()
+	app.HandleImportSongs(rec, req)
+
+	var report struct {
+		Imported int            `json:"imported"`
+		Failed   int            `json:"failed"`
+		Results  []importResult `json:"results"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || report.Imported != 0 || report.Failed != 3 || len(report.Results) != 3 {
+		t.Fatalf("status = %d, report = %+v, want 200 with 3 failed files", rec.Code, report)
+	}
+	results := map[string]importResult{}
+	for _, res := range report.Results {
+		results[res.File] = res
+	}
+	if res := results["songs/notes.txt"]; !strings.Contains(res.Error, "not an OpenLyrics or OpenSong song") {
+		t.Errorf("notes.txt: %+v", res)
+	}
+	if res := results["songs/unknown-chord.xml"]; res.Format != "opensong" || len(res.Diagnostics) != 1 || res.Diagnostics[0].Code != "unknown_chord" {
+		t.Errorf("unknown-chord.xml: %+v", res)
+	}
+	if res := results["songs/no-title.xml"]; len(res.Errors) == 0 || res.Errors[0].Field != "name" {
+		t.Errorf("no-title.xml: %+v", res)
+	}
+}
+
+func TestHandleImportSongsValidation(t *testing.T) {
+	app := &Application{Logger: log.New(io.Discard, "", 0)}
+
+	var empty bytes.Buffer
+	zip.NewWriter(&empty).Close()
+
+	// too many files are rejected before any is read
+	var many bytes.Buffer
+	zw := zip.NewWriter(&many)
+	for i := 0; i <= maxImportFiles; i++ {
+		zw.Create(fmt.Sprintf("song-%d.xml", i))
+	}
+	zw.Close()
+
+	tests := []struct {
+		contentType string
+		body        string
+		status      int
+	}{
+		{"application/json", "{}", http.StatusUnsupportedMediaType},
+		{"application/xml; charset=latin1", "<song/>", http.StatusUnsupportedMediaType},
+		{"application/zip", "not a zip", http.StatusBadRequest},
+		{"application/zip", empty.String(), http.StatusUnprocessableEntity},
+		{"application/zip", many.String(), http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/v1/songs/import", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", tt.contentType)
+		req = app.contextSetUser(req, &models.User{Name: "mick"})
+		rec := httptest.NewRecorder()
+		app.HandleImportSongs(rec, req)
+		if rec.Code != tt.status {
+			t.Errorf("%s (%d bytes): status %d, want %d", tt.contentType, len(tt.body), rec.Code, tt.status)
+		}
+	}
+}
+
+func TestHandleSongXMLValidation(t *testing.T) {
+	app := &Application{Logger: log.New(io.Discard, "", 0)}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/songs/angie.xml?format=chordpro", nil)
+	req = app.contextSetUser(req, models.AnonymousUser)
+	rec := httptest.NewRecorder()
+	app.HandleSongXML(rec, req)
+
+	var p problem
+	json.NewDecoder(rec.Body).Decode(&p)
+	if rec.Code != http.StatusUnprocessableEntity || len(p.Errors) != 1 || p.Errors[0].Field != "format" {
+		t.Errorf("status %d, errors %+v, want 422 for format", rec.Code, p.Errors)
+	}
+}
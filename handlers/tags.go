@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/davidkuda/lyricsapi/dbio"
+	"github.com/davidkuda/lyricsapi/models"
+	"github.com/davidkuda/lyricsapi/validator"
+)
+
+// HandleListTags handles GET /tags. It lists the tags of the songs the user
+// may see with the number of songs per tag, most used first. It takes the
+// same filters as GET /songs, e.g. ?language=de for the tags of German
+// songs.
+func (app *Application) HandleListTags(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+	filter := app.readSongFilter(r.URL.Query(), app.contextGetUser(r), v)
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	tags, err := dbio.ListTags(r.Context(), app.DB, filter)
+	if err != nil {
+		app.dbErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, envelope{"tags": tags}, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// HandleListGenres handles GET /genres. It sends the genre taxonomy, each
+// genre with the slug of its parent.
+func (app *Application) HandleListGenres(w http.ResponseWriter, r *http.Request) {
+	if err := app.writeJSON(w, http.StatusOK, envelope{"genres": models.Genres}, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
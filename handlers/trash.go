@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/davidkuda/lyricsapi/dbio"
+	"github.com/davidkuda/lyricsapi/models"
+	"github.com/davidkuda/lyricsapi/router"
+)
+
+// HandleListTrash handles GET /trash. It lists the summaries of the deleted
+// songs that have not been purged yet, most recently deleted first.
+func (app *Application) HandleListTrash(w http.ResponseWriter, r *http.Request) {
+	songs, err := dbio.ListDeletedSongs(r.Context(), app.DB)
+	if err != nil {
+		app.dbErrorResponse(w, r, err)
+		return
+	}
+
+	items := make([]map[string]any, 0, len(songs))
+	for i := range songs {
+		item := songs[i].Select(models.SongSummaryFields)
+		item["deletedAt"] = songs[i].DeletedAt
+		items = append(items, item)
+	}
+
+	if err := app.writeJSON(w, http.StatusOK, envelope{"songs": items}, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+// HandleRestoreSong handles POST /songs/{id}/restore. It takes a song out
+// of the trash.
+func (app *Application) HandleRestoreSong(w http.ResponseWriter, r *http.Request) {
+	songID := router.Param(r, "id")
+
+	if err := dbio.RestoreSong(r.Context(), songID, app.DB, app.Logger); err != nil {
+		app.dbErrorResponse(w, r, err)
+		return
+	}
+	app.SongCache.Invalidate(r.Context(), songID)
+
+	env := envelope{"status": "Success: Restored Song with ID " + songID}
+	if err := app.writeJSON(w, http.StatusOK, env, nil); err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+
+	"github.com/davidkuda/lyricsapi/dbio"
+	"github.com/davidkuda/lyricsapi/handlers"
+	"github.com/davidkuda/lyricsapi/sheet"
+)
+
+// detectMissingKeys detects the keys of the songs that were stored before
+// songs had keys. New and updated songs get their key when they are
+// written.
+func detectMissingKeys(app *handlers.Application) {
+	ctx := context.Background()
+
+	songs, err := dbio.SongsWithoutKey(ctx, app.DB)
+	if err != nil {
+		app.Logger.Printf("detect keys: %v", err)
+		return
+	}
+
+	n := 0
+	for i := range songs {
+		s := &songs[i]
+		sheet.DetectKey(s)
+		changed, err := dbio.SetSongKey(ctx, s.ID, s.Key, s.KeyConfidence, app.DB)
+		if err != nil {
+			app.Logger.Printf("detect keys: %v", err)
+			return
+		}
+		if changed {
+			app.SongCache.Invalidate(ctx, s.ID)
+			n++
+		}
+	}
+	if n > 0 {
+		app.Logger.Printf("detect keys: detected the keys of %d songs", n)
+	}
+}
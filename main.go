@@ -1,21 +1,54 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/redis/go-redis/v9"
+
+	"github.com/davidkuda/lyricsapi/cache"
 	"github.com/davidkuda/lyricsapi/dbio"
 	"github.com/davidkuda/lyricsapi/handlers"
 )
 
+// Build information, injected at build time with ldflags, e.g.:
+//
+//	go build -ldflags "-X main.version=1.2.0 -X main.commit=$(git rev-parse HEAD)"
+//
+// See scripts/build.sh.
+var (
+	version   = "dev"
+	commit    = "unknown"
+	buildTime = "unknown"
+)
+
+const (
+	songCacheSize = 1000 // number of songs in the in-memory cache
+	songCacheTTL  = 10 * time.Minute
+
+	trashRetentionDays = 30 // default of TRASH_RETENTION_DAYS
+	trashPurgeInterval = time.Hour
+)
+
 // in main, it's ok to log.Fatal or to os.Exit(1), but not in other places
 func main() {
 	var app handlers.Application
 
 	app.Logger = log.New(os.Stdout, "", log.Ldate|log.Ltime|log.Lshortfile)
 
+	app.Env = os.Getenv("ENV")
+	if len(app.Env) == 0 {
+		app.Env = "development"
+	}
+	app.Build.Version = version
+	app.Build.Commit = commit
+	app.Build.Time = buildTime
+
 	dbAddr := os.Getenv("DB_ADDR")
 	dbName := os.Getenv("DB_NAME")
 	dbUser := os.Getenv("DB_USER")
@@ -38,12 +71,48 @@ func main() {
 
 	app.DB = db
 
+	// songs stored before artists were records of their own
+	linked, err := dbio.LinkArtists(context.Background(), db)
+	if err != nil {
+		log.Fatalf("dbio.LinkArtists(): %v", err)
+	}
+	if linked > 0 {
+		log.Printf("Linked %d songs to their artists", linked)
+	}
+
+	// songs are cached in redis if REDIS_ADDR is set, otherwise in memory
+	var songCacheBackend cache.Backend = cache.NewLRU(songCacheSize)
+	if redisAddr := os.Getenv("REDIS_ADDR"); len(redisAddr) > 0 {
+		rdb := redis.NewClient(&redis.Options{
+			Addr:     redisAddr,
+			Password: os.Getenv("REDIS_PASSWORD"),
+		})
+		if err := rdb.Ping(context.Background()).Err(); err != nil {
+			log.Fatalf("rdb.Ping(): %v", err)
+		}
+		log.Printf("Caching songs in redis at %s", redisAddr)
+		songCacheBackend = cache.NewRedis(rdb)
+	}
+	app.SongCache = cache.NewSongs(songCacheBackend, songCacheTTL)
+
+	// songs stored before keys were detected get theirs in the background
+	go detectMissingKeys(&app)
+
+	// deleted songs are purged from the trash after TRASH_RETENTION_DAYS
+	retentionDays := trashRetentionDays
+	if s := os.Getenv("TRASH_RETENTION_DAYS"); len(s) > 0 {
+		retentionDays, err = strconv.Atoi(s)
+		if err != nil || retentionDays < 1 {
+			log.Fatalf("TRASH_RETENTION_DAYS must be a positive number of days, got %q", s)
+		}
+	}
+	go purgeTrash(&app, time.Duration(retentionDays)*24*time.Hour, trashPurgeInterval)
+
 	// list allowed cors origins separated by space
 	allowedCORSOrigins := strings.Split(os.Getenv("ALLOWED_CORS_ORIGINS"), " ")
 	app.CORS = struct{ TrustedOrigins []string }{allowedCORSOrigins}
 
-	mux := http.NewServeMux()
-	setupHandlers(mux, app)
+	routes := setupRoutes(&app)
 
 	listenAddr := os.Getenv("LISTEN_ADDR")
 	if len(listenAddr) == 0 {
@@ -55,7 +124,9 @@ func main() {
 		listenAddr,
 		app.LogRequests(
 			app.EnableCORS(
-				mux,
+				app.Compress(
+					routes,
+				),
 			),
 		),
 	))
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/davidkuda/lyricsapi/slug"
+)
+
+// Artist is the canonical record of an artist. Songs refer to it, so that
+// different spellings of the same name end up at the same artist.
+// ID: number of the artist
+// Name: canonical name, e.g. "The Rolling Stones"
+// SortName: name to sort by, e.g. "Rolling Stones, The"
+// Aliases: other spellings that were merged into the artist
+type Artist struct {
+	ID       int64    `json:"id"`
+	Name     string   `json:"name"`
+	SortName string   `json:"sortName"`
+	Aliases  []string `json:"aliases,omitempty"`
+}
+
+// leadingArticle matches the English articles that are moved to the end of
+// a sort name.
+var leadingArticle = regexp.MustCompile(`^(?i)(the|a|an)\s+(.+)$`)
+
+// ArtistSortName returns the name to sort an artist by: a leading article
+// is moved to the end, e.g. "The Rolling Stones" becomes "Rolling Stones,
+// The".
+func ArtistSortName(name string) string {
+	name = strings.Join(strings.Fields(name), " ")
+	if m := leadingArticle.FindStringSubmatch(name); m != nil {
+		return m[2] + ", " + m[1]
+	}
+	return name
+}
+
+// ArtistKey returns the key that identifies the name of an artist. Names
+// that differ only in case, spacing, accents or a leading or trailing
+// "the" have the same key, e.g. "The Rolling Stones", "rolling stones" and
+// "Rolling Stones, The".
+func ArtistKey(name string) string {
+	name = strings.ToLower(strings.Join(strings.Fields(name), " "))
+	name = strings.TrimPrefix(name, "the ")
+	name = strings.TrimSuffix(name, ", the")
+
+	if key := slug.Make(name); key != "" {
+		return key
+	}
+	// names without latin letters or digits, e.g. in Japanese
+	return name
+}
@@ -0,0 +1,33 @@
+package models
+
+import "testing"
+
+func TestArtistKey(t *testing.T) {
+	same := []string{"The Rolling Stones", "Rolling Stones", "rolling  stones", "Rolling Stones, The", "THE ROLLING STONES"}
+	for _, name := range same {
+		if got := ArtistKey(name); got != "rolling-stones" {
+			t.Errorf("ArtistKey(%q) = %q, want rolling-stones", name, got)
+		}
+	}
+
+	if ArtistKey("Beyoncé") != ArtistKey("Beyonce") {
+		t.Error("accents must not change the key")
+	}
+	if ArtistKey("The The") == "" || ArtistKey("坂本九") == "" {
+		t.Error("every name must have a key")
+	}
+}
+
+func TestArtistSortName(t *testing.T) {
+	tests := map[string]string{
+		"The Rolling Stones":  "Rolling Stones, The",
+		"A Perfect Circle":    "Perfect Circle, A",
+		"Pink Floyd":          "Pink Floyd",
+		"Theory of a Deadman": "Theory of a Deadman",
+	}
+	for name, want := range tests {
+		if got := ArtistSortName(name); got != want {
+			t.Errorf("ArtistSortName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
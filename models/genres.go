@@ -0,0 +1,70 @@
+package models
+
+// Genre is a node of the genre taxonomy. Top level genres have no parent.
+// A song has a single genre, listing the songs of a genre includes the
+// songs of its subgenres.
+type Genre struct {
+	Slug   string `json:"slug"`
+	Name   string `json:"name"`
+	Parent string `json:"parent,omitempty"`
+}
+
+// Genres is the taxonomy, parents before their children.
+var Genres = []Genre{
+	{Slug: "rock", Name: "Rock"},
+	{Slug: "classic-rock", Name: "Classic Rock", Parent: "rock"},
+	{Slug: "hard-rock", Name: "Hard Rock", Parent: "rock"},
+	{Slug: "alternative", Name: "Alternative", Parent: "rock"},
+	{Slug: "punk", Name: "Punk", Parent: "rock"},
+	{Slug: "metal", Name: "Metal", Parent: "rock"},
+	{Slug: "pop", Name: "Pop"},
+	{Slug: "folk", Name: "Folk"},
+	{Slug: "singer-songwriter", Name: "Singer-Songwriter", Parent: "folk"},
+	{Slug: "traditional", Name: "Traditional", Parent: "folk"},
+	{Slug: "celtic", Name: "Celtic", Parent: "folk"},
+	{Slug: "country", Name: "Country"},
+	{Slug: "bluegrass", Name: "Bluegrass", Parent: "country"},
+	{Slug: "blues", Name: "Blues"},
+	{Slug: "jazz", Name: "Jazz"},
+	{Slug: "soul", Name: "Soul and R&B"},
+	{Slug: "reggae", Name: "Reggae"},
+	{Slug: "latin", Name: "Latin"},
+	{Slug: "chanson", Name: "Chanson"},
+	{Slug: "religious", Name: "Religious"},
+	{Slug: "worship", Name: "Worship", Parent: "religious"},
+	{Slug: "hymn", Name: "Hymn", Parent: "religious"},
+	{Slug: "gospel", Name: "Gospel", Parent: "religious"},
+	{Slug: "childrens", Name: "Children's Songs"},
+	{Slug: "classical", Name: "Classical"},
+}
+
+// GenreExists reports whether slug is a genre of the taxonomy.
+func GenreExists(slug string) bool {
+	for _, g := range Genres {
+		if g.Slug == slug {
+			return true
+		}
+	}
+	return false
+}
+
+// GenreWithSubgenres returns slug and the slugs of all genres below it.
+func GenreWithSubgenres(slug string) []string {
+	slugs := []string{slug}
+	// parents come before their children, so one pass finds all descendants
+	for _, g := range Genres {
+		if g.Parent != "" && contains(slugs, g.Parent) && !contains(slugs, g.Slug) {
+			slugs = append(slugs, g.Slug)
+		}
+	}
+	return slugs
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGenreWithSubgenres(t *testing.T) {
+	got := GenreWithSubgenres("religious")
+	want := []string{"religious", "worship", "hymn", "gospel"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GenreWithSubgenres(religious) = %v, want %v", got, want)
+	}
+
+	if got := GenreWithSubgenres("punk"); !reflect.DeepEqual(got, []string{"punk"}) {
+		t.Errorf("GenreWithSubgenres(punk) = %v, want only punk", got)
+	}
+}
+
+func TestGenresTaxonomy(t *testing.T) {
+	seen := map[string]bool{}
+	for _, g := range Genres {
+		if seen[g.Slug] {
+			t.Errorf("genre %q is listed twice", g.Slug)
+		}
+		if g.Parent != "" && !seen[g.Parent] {
+			t.Errorf("genre %q is listed before its parent %q", g.Slug, g.Parent)
+		}
+		seen[g.Slug] = true
+	}
+}
@@ -10,21 +10,120 @@ import (
 type Songs []Song
 
 // Song contains all data related to a piece of music
-// ID: slug of the song, song name with hyphens, e.g. "wish-you-were-here"
-// Artist: artist of the song, e.g. "Pink Floyd"
+// ID: slug of the song, song name with hyphens, e.g. "wish-you-were-here";
+// generated from Name and Artist if the client does not send one
+// Artist: artist of the song, e.g. "Pink Floyd"; the canonical name of the
+// artist record once the song is stored
+// ArtistID: ID of the artist record, see Artist
 // Name: name of the song
 // Text: lyrics, text of the song
 // Chords: chords of the song, plain text
+// Sections: the structured body of the song, see Section; if it is set,
+// Text and Chords are derived from it
+// Arrangement: labels of the sections in the order they are played,
+// separated by spaces, e.g. "V1 C V2 C B C"
+// Status: where the song is in the workflow, one of SongStatuses
+// Genre: slug of a genre of the taxonomy, see Genres
+// Language: ISO 639-1 code of the language of the lyrics, e.g. "de"
+// Key: key of the song in the notation of chords, e.g. "Am"; detected from
+// the chords unless an editor sets it
+// KeyConfidence: confidence of the detected key from 0 to 1, nil if an
+// editor set the key
+// Tags: free labels such as "campfire" or "christmas", lower case slugs
 // Copyright: copyright information of the song
+// CCLI: number of the song at CCLI, the licensing service of churches
 // Covers: list of URLs to great covers, e.g. on YouTube
+// Version: changes with every write, used as the ETag of the song
+// UpdatedAt: time of the last write, used as Last-Modified of the song
+// DeletedAt: time the song was moved to the trash, zero if it wasn't
+// CreatedBy: name of the user who created the song, empty if unknown
 type Song struct {
-	ID        string   `json:"id"`
-	Artist    string   `json:"artist"`
-	Name      string   `json:"name"`
-	Text      string   `json:"lyrics,omitempty"`
-	Chords    string   `json:"chords,omitempty"`
-	Copyright string   `json:"copyright,omitempty"`
-	Covers    []string `json:"covers,omitempty"`
+	ID            string    `json:"id"`
+	Artist        string    `json:"artist"`
+	ArtistID      int64     `json:"artistId,omitempty"`
+	Name          string    `json:"name"`
+	Status        string    `json:"status,omitempty"`
+	Genre         string    `json:"genre,omitempty"`
+	Language      string    `json:"language,omitempty"`
+	Key           string    `json:"key,omitempty"`
+	KeyConfidence *float64  `json:"keyConfidence,omitempty"`
+	Tags          []string  `json:"tags,omitempty"`
+	Text          string    `json:"lyrics,omitempty"`
+	Chords        string    `json:"chords,omitempty"`
+	Sections      []Section `json:"sections,omitempty"`
+	Arrangement   string    `json:"arrangement,omitempty"`
+	Copyright     string    `json:"copyright,omitempty"`
+	CCLI          string    `json:"ccli,omitempty"`
+	Covers        []string  `json:"covers,omitempty"`
+	Version       int64     `json:"-"`
+	UpdatedAt     time.Time `json:"-"`
+	DeletedAt     time.Time `json:"-"`
+	CreatedBy     string    `json:"-"`
+}
+
+// SongFields are the JSON names of the fields of a song that are stored in
+// the database, in the order they are sent to clients.
+var SongFields = []string{"id", "artist", "artistId", "name", "status", "genre", "language", "key", "keyConfidence", "tags", "lyrics", "chords", "sections", "arrangement", "copyright", "ccli"}
+
+// SongSummaryFields are the fields needed to browse songs, without the
+// (long) lyrics and chords.
+var SongSummaryFields = []string{"id", "artist", "name"}
+
+// Select returns the given fields of the song, keyed by their JSON names.
+// It is used to send sparse fieldsets, e.g. for "?fields=id,name".
+func (s *Song) Select(fields []string) map[string]any {
+	m := make(map[string]any, len(fields))
+	for _, f := range fields {
+		switch f {
+		case "id":
+			m[f] = s.ID
+		case "artist":
+			m[f] = s.Artist
+		case "artistId":
+			m[f] = s.ArtistID
+		case "name":
+			m[f] = s.Name
+		case "status":
+			m[f] = s.Status
+		case "genre":
+			m[f] = s.Genre
+		case "language":
+			m[f] = s.Language
+		case "key":
+			m[f] = s.Key
+		case "keyConfidence":
+			m[f] = s.KeyConfidence
+		case "tags":
+			tags := s.Tags
+			if tags == nil {
+				tags = []string{}
+			}
+			m[f] = tags
+		case "lyrics":
+			m[f] = s.Text
+		case "chords":
+			m[f] = s.Chords
+		case "sections":
+			sections := s.Sections
+			if sections == nil {
+				sections = []Section{}
+			}
+			m[f] = sections
+		case "arrangement":
+			m[f] = s.Arrangement
+		case "copyright":
+			m[f] = s.Copyright
+		case "ccli":
+			m[f] = s.CCLI
+		}
+	}
+	return m
+}
+
+// TagCount is a tag and the number of songs that carry it.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
 }
 
 type SessionToken struct {
@@ -36,9 +135,31 @@ type SessionToken struct {
 type User struct {
 	Name      string    `json:"name"`
 	Password  string    `json:"password"` // a hash of a password
+	Role      string    `json:"-"`        // one of Roles, empty means RoleMember
 	CreatedAt time.Time `json:"-"`        // a hyphen means it's not put into the json
 }
 
+// The roles of users. Members write songs, editors also publish them and
+// admins also manage the trash.
+const (
+	RoleMember = "member"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+// Roles are all valid roles.
+var Roles = []string{RoleMember, RoleEditor, RoleAdmin}
+
+// IsEditor reports whether u may publish songs. Admins are editors too.
+func (u *User) IsEditor() bool {
+	return u.Role == RoleEditor || u.Role == RoleAdmin
+}
+
+// IsAdmin reports whether u may manage the trash.
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
 var AnonymousUser = &User{}
 
 // Check if a User instance is the AnonymousUser.
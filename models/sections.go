@@ -0,0 +1,145 @@
+package models
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// The structured body of a song. A song consists of sections, e.g. verses
+// and a chorus, that are played in the order of its arrangement, e.g.
+// "V1 C V2 C B C". A chorus that is sung four times is stored once.
+//
+// Each line of a section has the chords that are played on it, at the
+// position of the character they are played on:
+//
+//	{"lyrics": "Start me up", "chords": [{"chord": "C", "position": 0}, {"chord": "F", "position": 6}]}
+//
+// is sent to older clients as
+//
+//	C     F
+//	Start me up
+
+// The kinds of sections.
+const (
+	SectionIntro        = "intro"
+	SectionVerse        = "verse"
+	SectionPreChorus    = "pre-chorus"
+	SectionChorus       = "chorus"
+	SectionBridge       = "bridge"
+	SectionInstrumental = "instrumental"
+	SectionOutro        = "outro"
+	SectionTag          = "tag"
+)
+
+// SectionKinds are all valid kinds of sections.
+var SectionKinds = []string{
+	SectionIntro, SectionVerse, SectionPreChorus, SectionChorus,
+	SectionBridge, SectionInstrumental, SectionOutro, SectionTag,
+}
+
+// Section is a part of a song.
+// Label: short, unique name used in the arrangement, e.g. "V1" or "C"
+// Kind: one of SectionKinds
+// Lines: the lines of the section, in order
+type Section struct {
+	Label string `json:"label"`
+	Kind  string `json:"kind"`
+	Lines []Line `json:"lines"`
+}
+
+// Line is a line of lyrics with the chords played on it. Lyrics are empty
+// for lines of chords only, e.g. in an intro.
+type Line struct {
+	Lyrics string          `json:"lyrics"`
+	Chords []ChordPosition `json:"chords,omitempty"`
+}
+
+// ChordPosition is a chord and the index of the character (not the byte) of
+// the lyrics it is played on. Positions past the end of the lyrics are
+// played after the words.
+type ChordPosition struct {
+	Chord    string `json:"chord"`
+	Position int    `json:"position"`
+}
+
+// ArrangementLabels returns the labels of the sections in the order they
+// are played. Without an arrangement, the sections are played once each,
+// in order.
+func (s *Song) ArrangementLabels() []string {
+	if labels := strings.Fields(s.Arrangement); len(labels) > 0 {
+		return labels
+	}
+	labels := make([]string, 0, len(s.Sections))
+	for _, sec := range s.Sections {
+		labels = append(labels, sec.Label)
+	}
+	return labels
+}
+
+// Section returns the section with the given label, or nil.
+func (s *Song) Section(label string) *Section {
+	for i := range s.Sections {
+		if s.Sections[i].Label == label {
+			return &s.Sections[i]
+		}
+	}
+	return nil
+}
+
+// Arranged returns the sections in the order they are played, repeated
+// sections included.
+func (s *Song) Arranged() []*Section {
+	var sections []*Section
+	for _, label := range s.ArrangementLabels() {
+		if sec := s.Section(label); sec != nil {
+			sections = append(sections, sec)
+		}
+	}
+	return sections
+}
+
+// DeriveTextAndChords sets Text and Chords from the sections, for clients
+// that don't know about sections. Text has the lyrics of the arranged
+// sections, Chords a line of chords for each line of Text, at the same
+// positions, so the two can be laid over each other. Sections are
+// separated by blank lines. Songs without sections are left alone.
+func (s *Song) DeriveTextAndChords() {
+	if len(s.Sections) == 0 {
+		return
+	}
+
+	var text, chords []string
+	for i, sec := range s.Arranged() {
+		if i > 0 {
+			text, chords = append(text, ""), append(chords, "")
+		}
+		for _, line := range sec.Lines {
+			text = append(text, line.Lyrics)
+			chords = append(chords, line.ChordLine())
+		}
+	}
+
+	s.Text = strings.Join(text, "\n")
+	s.Chords = strings.Join(chords, "\n")
+}
+
+// ChordLine returns the chords of the line, each at its position. A chord
+// that would run into the next one is followed by a single space and the
+// next one is moved to the right.
+func (l *Line) ChordLine() string {
+	var b strings.Builder
+	col := 0
+	for i, c := range l.Chords {
+		if i > 0 && c.Position <= col {
+			b.WriteByte(' ')
+			col++
+		}
+		for col < c.Position {
+			b.WriteByte(' ')
+			col++
+		}
+		b.WriteString(c.Chord)
+		col += utf8.RuneCountInString(c.Chord)
+	}
+	return b.String()
+}
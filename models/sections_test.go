@@ -0,0 +1,72 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/davidkuda/lyricsapi/validator"
+)
+
+func sectionsSong() Song {
+	return Song{
+		Artist: "The Rolling Stones",
+		Name:   "Start Me Up",
+		Sections: []Section{
+			{Label: "V1", Kind: SectionVerse, Lines: []Line{
+				{Lyrics: "If you start me up", Chords: []ChordPosition{{"C", 0}, {"F", 7}}},
+			}},
+			{Label: "C", Kind: SectionChorus, Lines: []Line{
+				{Lyrics: "Start me up", Chords: []ChordPosition{{"Bb", 0}, {"F", 6}}},
+				{Lyrics: "I'll never stop"},
+			}},
+		},
+		Arrangement: "V1 C C",
+	}
+}
+
+func TestDeriveTextAndChords(t *testing.T) {
+	s := sectionsSong()
+	s.DeriveTextAndChords()
+
+	wantText := "If you start me up\n\nStart me up\nI'll never stop\n\nStart me up\nI'll never stop"
+	if s.Text != wantText {
+		t.Errorf("Text = %q, want %q", s.Text, wantText)
+	}
+	wantChords := "C      F\n\nBb    F\n\n\nBb    F\n"
+	if s.Chords != wantChords {
+		t.Errorf("Chords = %q, want %q", s.Chords, wantChords)
+	}
+
+	// without an arrangement, each section is played once
+	s.Arrangement = ""
+	if got := s.ArrangementLabels(); len(got) != 2 || got[1] != "C" {
+		t.Errorf("ArrangementLabels() = %v, want [V1 C]", got)
+	}
+}
+
+func TestChordLine(t *testing.T) {
+	l := Line{Lyrics: "Oh", Chords: []ChordPosition{{"Cmaj7", 0}, {"G", 2}, {"Am", 12}}}
+	if got, want := l.ChordLine(), "Cmaj7 G     Am"; got != want {
+		t.Errorf("ChordLine() = %q, want %q", got, want)
+	}
+}
+
+func TestValidateSections(t *testing.T) {
+	s := sectionsSong()
+	v := validator.New()
+	ValidateSong(v, &s)
+	if !v.Valid() {
+		t.Errorf("valid song was rejected: %v", v.Errors)
+	}
+
+	s.Sections[1].Label = "V1"
+	s.Sections[0].Kind = "refrain"
+	s.Sections[0].Lines[0].Chords[1].Position = 0
+	s.Arrangement = "V1 X"
+	v = validator.New()
+	ValidateSong(v, &s)
+	for _, key := range []string{"sections[1].label", "sections[0].kind", "sections[0].lines[0].chords[1].position", "arrangement"} {
+		if _, ok := v.Errors[key]; !ok {
+			t.Errorf("expected an error for %q, got %v", key, v.Errors)
+		}
+	}
+}
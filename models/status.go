@@ -0,0 +1,69 @@
+package models
+
+// The statuses of a song. A song is created as a draft, its author submits
+// it for review and an editor publishes it. Published songs can be archived
+// to take them offline without deleting them.
+const (
+	StatusDraft     = "draft"
+	StatusInReview  = "in_review"
+	StatusPublished = "published"
+	StatusArchived  = "archived"
+)
+
+// SongStatuses are all valid statuses.
+var SongStatuses = []string{StatusDraft, StatusInReview, StatusPublished, StatusArchived}
+
+// StatusTransitions maps a status to the statuses a song may move to from
+// it.
+var StatusTransitions = map[string][]string{
+	StatusDraft:     {StatusInReview, StatusPublished},
+	StatusInReview:  {StatusDraft, StatusPublished},
+	StatusPublished: {StatusDraft, StatusArchived},
+	StatusArchived:  {StatusDraft, StatusPublished},
+}
+
+// CanTransition reports whether a song may move from one status to another.
+// Keeping the status is always allowed.
+func CanTransition(from, to string) bool {
+	if from == to {
+		return true
+	}
+	for _, s := range StatusTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// MayTransition reports whether u may move a song from one status to
+// another. Only editors publish songs or take them back, members move
+// songs between draft and review.
+func (u *User) MayTransition(from, to string) bool {
+	if from == to || u.IsEditor() {
+		return true
+	}
+	return !editorStatus(from) && !editorStatus(to)
+}
+
+func editorStatus(status string) bool {
+	return status == StatusPublished || status == StatusArchived
+}
+
+// VisibleTo reports whether u may see s. Published songs are public, the
+// others are only seen by their author and by editors.
+func (s *Song) VisibleTo(u *User) bool {
+	if s.Status == StatusPublished || u.IsEditor() {
+		return true
+	}
+	return !u.IsAnonymous() && s.CreatedBy != "" && s.CreatedBy == u.Name
+}
+
+// MayEdit reports whether u may change or delete s. Editors edit any song,
+// members only their own songs that haven't been published or archived.
+func (s *Song) MayEdit(u *User) bool {
+	if u.IsEditor() {
+		return true
+	}
+	return !editorStatus(s.Status) && !u.IsAnonymous() && s.CreatedBy != "" && s.CreatedBy == u.Name
+}
@@ -0,0 +1,87 @@
+package models
+
+import "testing"
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{StatusDraft, StatusDraft, true},
+		{StatusDraft, StatusInReview, true},
+		{StatusDraft, StatusPublished, true},
+		{StatusDraft, StatusArchived, false},
+		{StatusInReview, StatusArchived, false},
+		{StatusPublished, StatusInReview, false},
+		{StatusPublished, StatusArchived, true},
+		{StatusArchived, StatusPublished, true},
+	}
+	for _, tt := range tests {
+		if got := CanTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransition(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestMayTransition(t *testing.T) {
+	member := &User{Name: "ann", Role: RoleMember}
+	editor := &User{Name: "ed", Role: RoleEditor}
+	admin := &User{Name: "root", Role: RoleAdmin}
+
+	if !member.MayTransition(StatusDraft, StatusInReview) || !member.MayTransition(StatusInReview, StatusDraft) {
+		t.Error("members must move songs between draft and review")
+	}
+	if member.MayTransition(StatusInReview, StatusPublished) || member.MayTransition(StatusPublished, StatusDraft) {
+		t.Error("members must not publish or unpublish songs")
+	}
+	if !editor.MayTransition(StatusInReview, StatusPublished) || !admin.MayTransition(StatusPublished, StatusArchived) {
+		t.Error("editors and admins must publish and archive songs")
+	}
+}
+
+func TestVisibleTo(t *testing.T) {
+	author := &User{Name: "ann", Role: RoleMember}
+	other := &User{Name: "bob", Role: RoleMember}
+	editor := &User{Name: "ed", Role: RoleEditor}
+
+	published := &Song{Status: StatusPublished, CreatedBy: "ann"}
+	draft := &Song{Status: StatusDraft, CreatedBy: "ann"}
+	orphan := &Song{Status: StatusDraft}
+
+	for _, u := range []*User{AnonymousUser, author, other, editor} {
+		if !published.VisibleTo(u) {
+			t.Errorf("published song is hidden from %q", u.Name)
+		}
+	}
+	if !draft.VisibleTo(author) || !draft.VisibleTo(editor) {
+		t.Error("draft must be visible to its author and to editors")
+	}
+	if draft.VisibleTo(other) || draft.VisibleTo(AnonymousUser) {
+		t.Error("draft must be hidden from others")
+	}
+	if orphan.VisibleTo(&User{}) || orphan.VisibleTo(AnonymousUser) {
+		t.Error("draft without author must only be visible to editors")
+	}
+}
+
+func TestMayEdit(t *testing.T) {
+	author := &User{Name: "ann", Role: RoleMember}
+	other := &User{Name: "bob", Role: RoleMember}
+	editor := &User{Name: "ed", Role: RoleEditor}
+	draft := &Song{Status: StatusDraft, CreatedBy: "ann"}
+	published := &Song{Status: StatusPublished, CreatedBy: "ann"}
+	archived := &Song{Status: StatusArchived, CreatedBy: "ann"}
+
+	if !draft.MayEdit(author) || !draft.MayEdit(editor) {
+		t.Error("draft must be editable by its author and by editors")
+	}
+	if draft.MayEdit(other) || draft.MayEdit(AnonymousUser) {
+		t.Error("draft must not be editable by others")
+	}
+	if published.MayEdit(author) || published.MayEdit(other) || archived.MayEdit(author) {
+		t.Error("published and archived songs must only be editable by editors")
+	}
+	if !published.MayEdit(editor) || !archived.MayEdit(editor) {
+		t.Error("editors must edit published and archived songs")
+	}
+}
@@ -0,0 +1,172 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/davidkuda/lyricsapi/chord"
+	"github.com/davidkuda/lyricsapi/validator"
+)
+
+// Limits for the fields of a song. Lyrics and chords are generous, since
+// some songs are long, but bounded so a single song can't fill the db.
+const (
+	maxSongIDChars        = 100
+	maxSongArtistChars    = 200
+	maxSongNameChars      = 200
+	maxSongTextChars      = 50_000
+	maxSongChordsChars    = 20_000
+	maxSongCopyrightChars = 500
+	maxSongCCLIChars      = 10
+	maxSongCovers         = 20
+	maxCoverURLChars      = 2_000
+	maxSongTags           = 20
+	maxTagChars           = 50
+	maxSongSections       = 50
+	maxSectionLabelChars  = 10
+	maxSectionLines       = 200
+	maxLineChars          = 500
+	maxLineChords         = 50
+	maxChordChars         = 20
+	maxArrangementLength  = 100
+
+	maxUserNameChars = 100
+	minPasswordChars = 8
+	// bcrypt ignores everything after the 72nd byte of a password
+	maxPasswordBytes = 72
+)
+
+// ccliRX matches song numbers of CCLI.
+var ccliRX = regexp.MustCompile(`^[0-9]+$`)
+
+// ValidateSong checks all fields of s. The keys of the errors are the JSON
+// names of the fields. The ID is optional, the server generates one from
+// the name and artist if it is missing. So is the status, which keeps its
+// value or defaults to a draft.
+func ValidateSong(v *validator.Validator, s *Song) {
+	if s.Status != "" {
+		v.Check(validator.PermittedValue(s.Status, SongStatuses...), "status", "must be one of "+strings.Join(SongStatuses, ", "))
+	}
+	if s.ID != "" {
+		v.Check(validator.MaxChars(s.ID, maxSongIDChars), "id", fmt.Sprintf("must not be more than %d characters long", maxSongIDChars))
+		v.Check(validator.Matches(s.ID, validator.SlugRX), "id", "must be a slug of lower case letters, digits and hyphens, e.g. \"wish-you-were-here\"")
+	}
+
+	v.Check(validator.NotBlank(s.Artist), "artist", "must be provided")
+	v.Check(validator.MaxChars(s.Artist, maxSongArtistChars), "artist", fmt.Sprintf("must not be more than %d characters long", maxSongArtistChars))
+
+	v.Check(validator.NotBlank(s.Name), "name", "must be provided")
+	v.Check(validator.MaxChars(s.Name, maxSongNameChars), "name", fmt.Sprintf("must not be more than %d characters long", maxSongNameChars))
+
+	v.Check(validator.MaxChars(s.Text, maxSongTextChars), "lyrics", fmt.Sprintf("must not be more than %d characters long", maxSongTextChars))
+	v.Check(validator.MaxChars(s.Chords, maxSongChordsChars), "chords", fmt.Sprintf("must not be more than %d characters long", maxSongChordsChars))
+	v.Check(validator.MaxChars(s.Copyright, maxSongCopyrightChars), "copyright", fmt.Sprintf("must not be more than %d characters long", maxSongCopyrightChars))
+
+	if s.CCLI != "" {
+		v.Check(validator.Matches(s.CCLI, ccliRX), "ccli", "must be the number of the song at CCLI, e.g. \"22025\"")
+		v.Check(validator.MaxChars(s.CCLI, maxSongCCLIChars), "ccli", fmt.Sprintf("must not be more than %d characters long", maxSongCCLIChars))
+	}
+
+	if s.Genre != "" {
+		v.Check(GenreExists(s.Genre), "genre", "must be a genre of the taxonomy, see GET /v1/genres")
+	}
+	if s.Language != "" {
+		v.Check(Languages[s.Language] != "", "language", "must be an ISO 639-1 code in lower case, e.g. \"en\" or \"de\"")
+	}
+	if s.Key != "" {
+		_, err := chord.ParseKey(s.Key)
+		v.Check(err == nil, "key", "must be a major or minor key, e.g. \"C\", \"F#m\" or \"Bb major\"")
+	}
+
+	v.Check(len(s.Tags) <= maxSongTags, "tags", fmt.Sprintf("must not contain more than %d entries", maxSongTags))
+	for i, tag := range s.Tags {
+		key := fmt.Sprintf("tags[%d]", i)
+		v.Check(validator.Matches(tag, validator.SlugRX), key, "must be a slug of lower case letters, digits and hyphens, e.g. \"campfire\"")
+		v.Check(validator.MaxChars(tag, maxTagChars), key, fmt.Sprintf("must not be more than %d characters long", maxTagChars))
+	}
+
+	validateSections(v, s)
+
+	v.Check(len(s.Covers) <= maxSongCovers, "covers", fmt.Sprintf("must not contain more than %d entries", maxSongCovers))
+	for i, cover := range s.Covers {
+		key := fmt.Sprintf("covers[%d]", i)
+		v.Check(validator.IsURL(cover), key, "must be an absolute http or https URL")
+		v.Check(validator.MaxChars(cover, maxCoverURLChars), key, fmt.Sprintf("must not be more than %d characters long", maxCoverURLChars))
+	}
+}
+
+// validateSections checks the structured body of s: labels must be unique,
+// chords must be in order and the arrangement must only refer to existing
+// sections.
+func validateSections(v *validator.Validator, s *Song) {
+	v.Check(len(s.Sections) <= maxSongSections, "sections", fmt.Sprintf("must not contain more than %d entries", maxSongSections))
+
+	labels := map[string]bool{}
+	for i, sec := range s.Sections {
+		key := fmt.Sprintf("sections[%d]", i)
+		v.Check(validator.NotBlank(sec.Label), key+".label", "must be provided")
+		v.Check(!strings.ContainsAny(sec.Label, " \t\n"), key+".label", "must not contain whitespace")
+		v.Check(validator.MaxChars(sec.Label, maxSectionLabelChars), key+".label", fmt.Sprintf("must not be more than %d characters long", maxSectionLabelChars))
+		v.Check(!labels[sec.Label], key+".label", fmt.Sprintf("must be unique, %q is used twice", sec.Label))
+		labels[sec.Label] = true
+		v.Check(validator.PermittedValue(sec.Kind, SectionKinds...), key+".kind", "must be one of "+strings.Join(SectionKinds, ", "))
+
+		v.Check(len(sec.Lines) <= maxSectionLines, key+".lines", fmt.Sprintf("must not contain more than %d entries", maxSectionLines))
+		for j, line := range sec.Lines {
+			lineKey := fmt.Sprintf("%s.lines[%d]", key, j)
+			v.Check(validator.MaxChars(line.Lyrics, maxLineChars), lineKey+".lyrics", fmt.Sprintf("must not be more than %d characters long", maxLineChars))
+			v.Check(!strings.Contains(line.Lyrics, "\n"), lineKey+".lyrics", "must be a single line")
+			v.Check(len(line.Chords) <= maxLineChords, lineKey+".chords", fmt.Sprintf("must not contain more than %d entries", maxLineChords))
+
+			for k, c := range line.Chords {
+				chordKey := fmt.Sprintf("%s.chords[%d]", lineKey, k)
+				v.Check(validator.NotBlank(c.Chord), chordKey+".chord", "must be provided")
+				v.Check(!strings.ContainsAny(c.Chord, " \t\n"), chordKey+".chord", "must not contain whitespace")
+				v.Check(validator.MaxChars(c.Chord, maxChordChars), chordKey+".chord", fmt.Sprintf("must not be more than %d characters long", maxChordChars))
+				v.Check(c.Position >= 0 && c.Position <= maxLineChars, chordKey+".position", fmt.Sprintf("must be between 0 and %d", maxLineChars))
+				if k > 0 {
+					v.Check(c.Position > line.Chords[k-1].Position, chordKey+".position", "must be greater than the position of the previous chord")
+				}
+			}
+		}
+	}
+
+	arrangement := strings.Fields(s.Arrangement)
+	if len(arrangement) > 0 && len(s.Sections) == 0 {
+		v.AddError("arrangement", "must not be set without sections")
+		return
+	}
+	v.Check(len(arrangement) <= maxArrangementLength, "arrangement", fmt.Sprintf("must not contain more than %d sections", maxArrangementLength))
+	for _, label := range arrangement {
+		v.Check(labels[label], "arrangement", fmt.Sprintf("refers to the unknown section %q", label))
+	}
+}
+
+// ValidateUser checks a new user before it is stored. u.Password must
+// still be the plain text password.
+func ValidateUser(v *validator.Validator, u *User) {
+	ValidateUserName(v, u.Name, "name")
+	ValidatePasswordPlaintext(v, u.Password, "password")
+	v.Check(validator.MinChars(u.Password, minPasswordChars), "password", fmt.Sprintf("must be at least %d characters long", minPasswordChars))
+	if u.Role != "" {
+		v.Check(validator.PermittedValue(u.Role, Roles...), "role", "must be one of "+strings.Join(Roles, ", "))
+	}
+}
+
+// ValidateCredentials checks the payload of a sign in. It is less strict
+// than ValidateUser, so that old, shorter passwords keep working.
+func ValidateCredentials(v *validator.Validator, userName, password string) {
+	ValidateUserName(v, userName, "userName")
+	ValidatePasswordPlaintext(v, password, "password")
+}
+
+func ValidateUserName(v *validator.Validator, name, key string) {
+	v.Check(validator.NotBlank(name), key, "must be provided")
+	v.Check(validator.MaxChars(name, maxUserNameChars), key, fmt.Sprintf("must not be more than %d characters long", maxUserNameChars))
+}
+
+func ValidatePasswordPlaintext(v *validator.Validator, password, key string) {
+	v.Check(password != "", key, "must be provided")
+	v.Check(validator.MaxBytes(password, maxPasswordBytes), key, fmt.Sprintf("must not be more than %d bytes long", maxPasswordBytes))
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/davidkuda/lyricsapi/validator"
+)
+
+func TestValidateSong(t *testing.T) {
+	valid := Song{
+		ID:     "wish-you-were-here",
+		Artist: "Pink Floyd",
+		Name:   "Wish You Were Here",
+		Covers: []string{"https://www.youtube.com/watch?v=IXdNnw99-Ic"},
+	}
+
+	v := validator.New()
+	ValidateSong(v, &valid)
+	if !v.Valid() {
+		t.Errorf("valid song was rejected: %v", v.Errors)
+	}
+
+	invalid := Song{
+		ID:        "Wish You Were Here",
+		Name:      strings.Repeat("x", maxSongNameChars+1),
+		Copyright: "ok",
+		Covers:    []string{"https://ok.example.com", "not a url"},
+	}
+
+	v = validator.New()
+	ValidateSong(v, &invalid)
+	for _, key := range []string{"id", "artist", "name", "covers[1]"} {
+		if _, ok := v.Errors[key]; !ok {
+			t.Errorf("expected an error for %q, got %v", key, v.Errors)
+		}
+	}
+	if len(v.Errors) != 4 {
+		t.Errorf("got %d errors, want 4: %v", len(v.Errors), v.Errors)
+	}
+
+	// the id is generated by the server if it is missing
+	v = validator.New()
+	valid.ID = ""
+	ValidateSong(v, &valid)
+	if !v.Valid() {
+		t.Errorf("song without id was rejected: %v", v.Errors)
+	}
+}
+
+func TestValidateUserRole(t *testing.T) {
+	for _, role := range []string{"", RoleMember, RoleAdmin} {
+		v := validator.New()
+		ValidateUser(v, &User{Name: "david", Password: "correct horse battery", Role: role})
+		if !v.Valid() {
+			t.Errorf("role %q was rejected: %v", role, v.Errors)
+		}
+	}
+
+	v := validator.New()
+	ValidateUser(v, &User{Name: "david", Password: "correct horse battery", Role: "root"})
+	if _, ok := v.Errors["role"]; !ok {
+		t.Errorf("role \"root\" was accepted")
+	}
+}
+
+func TestValidateSongClassification(t *testing.T) {
+	s := Song{
+		Artist:   "Reinhard Mey",
+		Name:     "Über den Wolken",
+		Genre:    "chanson",
+		Language: "de",
+		Tags:     []string{"campfire", "70s"},
+	}
+
+	v := validator.New()
+	ValidateSong(v, &s)
+	if !v.Valid() {
+		t.Errorf("valid song was rejected: %v", v.Errors)
+	}
+
+	s.Genre = "schlager"
+	s.Language = "deu"
+	s.Tags = []string{"campfire", "Sing Along"}
+	v = validator.New()
+	ValidateSong(v, &s)
+	for _, key := range []string{"genre", "language", "tags[1]"} {
+		if _, ok := v.Errors[key]; !ok {
+			t.Errorf("expected an error for %q, got %v", key, v.Errors)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+// Package pdf writes simple PDF documents: pages of text in the standard
+// fonts, lines, links between pages and bookmarks. It needs no external
+// programs and embeds no fonts, every PDF reader has the standard ones.
+//
+// Coordinates are in points (1/72 inch) from the bottom left corner of the
+// page, as usual in PDF.
+package pdf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// The size of an A4 page in points.
+const (
+	A4Width  = 595
+	A4Height = 842
+)
+
+// Font is one of the standard fonts.
+type Font int
+
+const (
+	Courier Font = iota
+	CourierBold
+	Helvetica
+	HelveticaBold
+)
+
+var fontNames = []string{"Courier", "Courier-Bold", "Helvetica", "Helvetica-Bold"}
+
+// CharWidth is the width of a character of the Courier fonts at size 1.
+const CharWidth = 0.6
+
+// Document is a PDF document. The zero value is an empty document.
+type Document struct {
+	Title  string
+	Author string
+
+	pages     []*Page
+	bookmarks []bookmark
+}
+
+type bookmark struct {
+	title string
+	page  int
+}
+
+// Page is a page of a document.
+type Page struct {
+	width, height float64
+	content       bytes.Buffer
+	links         []link
+}
+
+type link struct {
+	x, y, w, h float64
+	page       int
+}
+
+// AddPage appends an empty page of the given size to d.
+func (d *Document) AddPage(width, height float64) *Page {
+	p := &Page{width: width, height: height}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// Page returns the page with index i, counting from 0.
+func (d *Document) Page(i int) *Page {
+	return d.pages[i]
+}
+
+// PageCount returns the number of pages of d.
+func (d *Document) PageCount() int {
+	return len(d.pages)
+}
+
+// Bookmark adds an entry to the outline of d, which readers show as a table
+// of contents, that leads to the page with index page.
+func (d *Document) Bookmark(title string, page int) {
+	d.bookmarks = append(d.bookmarks, bookmark{title: title, page: page})
+}
+
+// Text writes s with its baseline starting at x, y. Characters the font
+// can't show are written as "?".
+func (p *Page) Text(x, y float64, font Font, size float64, s string) {
+	fmt.Fprintf(&p.content, "BT /F%d %s Tf %s %s Td %s Tj ET\n", font+1, num(size), num(x), num(y), literal(s))
+}
+
+// Line draws a line of width from x1, y1 to x2, y2.
+func (p *Page) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s m %s %s l S\n", num(width), num(x1), num(y1), num(x2), num(y2))
+}
+
+// Link makes the rectangle with the bottom left corner x, y a link to the
+// page with index page.
+func (p *Page) Link(x, y, w, h float64, page int) {
+	p.links = append(p.links, link{x: x, y: y, w: w, h: h, page: page})
+}
+
+// WriteTo writes d as a PDF file to w.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	pw := &writer{w: bufio.NewWriter(w)}
+	pw.write("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// objects 1 to 3 are the catalog, the page tree and the info, the
+	// fonts follow, then the outline and the pages with their contents
+	const catalog, pages, info, fonts = 1, 2, 3, 4
+	outline := fonts + len(fontNames)
+	firstPage := outline + 1 + len(d.bookmarks)
+	pageObj := func(i int) int { return firstPage + 2*i }
+
+	root := fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R", pages)
+	if len(d.bookmarks) > 0 {
+		root += fmt.Sprintf(" /Outlines %d 0 R /PageMode /UseOutlines", outline)
+	}
+	pw.object(catalog, root+" >>")
+
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", pageObj(i))
+	}
+	pw.object(pages, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+
+	meta := "<< /Producer (lyricsapi)"
+	if d.Title != "" {
+		meta += " /Title " + textString(d.Title)
+	}
+	if d.Author != "" {
+		meta += " /Author " + textString(d.Author)
+	}
+	pw.object(info, meta+" >>")
+
+	for i, name := range fontNames {
+		pw.object(fonts+i, fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", name))
+	}
+
+	if n := len(d.bookmarks); n > 0 {
+		pw.object(outline, fmt.Sprintf("<< /Type /Outlines /First %d 0 R /Last %d 0 R /Count %d >>", outline+1, outline+n, n))
+		for i, b := range d.bookmarks {
+			item := fmt.Sprintf("<< /Title %s /Parent %d 0 R /Dest [%d 0 R /Fit]", textString(b.title), outline, pageObj(b.page))
+			if i > 0 {
+				item += fmt.Sprintf(" /Prev %d 0 R", outline+i)
+			}
+			if i < n-1 {
+				item += fmt.Sprintf(" /Next %d 0 R", outline+i+2)
+			}
+			pw.object(outline+1+i, item+" >>")
+		}
+	}
+
+	var fontRefs strings.Builder
+	for i := range fontNames {
+		fmt.Fprintf(&fontRefs, "/F%d %d 0 R ", i+1, fonts+i)
+	}
+	for i, p := range d.pages {
+		page := fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources << /Font << %s>> >> /Contents %d 0 R",
+			pages, num(p.width), num(p.height), fontRefs.String(), pageObj(i)+1)
+		if len(p.links) > 0 {
+			annots := make([]string, len(p.links))
+			for j, l := range p.links {
+				annots[j] = fmt.Sprintf("<< /Type /Annot /Subtype /Link /Border [0 0 0] /Rect [%s %s %s %s] /Dest [%d 0 R /Fit] >>",
+					num(l.x), num(l.y), num(l.x+l.w), num(l.y+l.h), pageObj(l.page))
+			}
+			page += " /Annots [" + strings.Join(annots, " ") + "]"
+		}
+		pw.object(pageObj(i), page+" >>")
+
+		var z bytes.Buffer
+		zw := zlib.NewWriter(&z)
+		zw.Write(p.content.Bytes())
+		zw.Close()
+		pw.stream(pageObj(i)+1, z.Bytes())
+	}
+
+	xref := pw.n
+	pw.write(fmt.Sprintf("xref\n0 %d\n0000000000 65535 f \n", len(pw.offsets)+1))
+	for _, off := range pw.offsets {
+		pw.write(fmt.Sprintf("%010d 00000 n \n", off))
+	}
+	pw.write(fmt.Sprintf("trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(pw.offsets)+1, catalog, info, xref))
+
+	if pw.err == nil {
+		pw.err = pw.w.Flush()
+	}
+	return pw.n, pw.err
+}
+
+// writer keeps track of the offsets of the objects for the cross-reference
+// table. Objects must be written in the order of their numbers.
+type writer struct {
+	w       *bufio.Writer
+	n       int64
+	offsets []int64
+	err     error
+}
+
+func (pw *writer) write(s string) {
+	if pw.err != nil {
+		return
+	}
+	n, err := pw.w.WriteString(s)
+	pw.n += int64(n)
+	pw.err = err
+}
+
+func (pw *writer) object(num int, body string) {
+	if num != len(pw.offsets)+1 {
+		panic("pdf: objects written out of order")
+	}
+	pw.offsets = append(pw.offsets, pw.n)
+	pw.write(fmt.Sprintf("%d 0 obj\n%s\nendobj\n", num, body))
+}
+
+func (pw *writer) stream(num int, data []byte) {
+	pw.object(num, fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", len(data), data))
+}
+
+// num formats a coordinate with at most two decimals.
+func num(f float64) string {
+	s := strconv.FormatFloat(f, 'f', 2, 64)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+// replacements are characters the WinAnsi encoding lacks that have an
+// obvious substitute. Each is a single character, so that text that has
+// been laid out in columns, e.g. chords over lyrics, keeps its width; "^"
+// is the major seventh of "CΔ" as "C^".
+var replacements = map[rune]byte{'♯': '#', '♭': 'b', 'Δ': '^', '−': '-'}
+
+// literal encodes s as a string in the WinAnsi encoding of the fonts.
+func literal(s string) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for _, r := range s {
+		c, ok := replacements[r]
+		if !ok {
+			c, ok = charmap.Windows1252.EncodeRune(r)
+		}
+		if !ok {
+			c = '?'
+		}
+		if c == '(' || c == ')' || c == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// textString encodes s for the document outline and info, which take
+// UTF-16 with a byte order mark.
+func textString(s string) string {
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, u := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", u)
+	}
+	b.WriteByte('>')
+	return b.String()
+}
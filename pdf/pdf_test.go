@@ -0,0 +1,73 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWriteTo(t *testing.T) {
+	var d Document
+	d.Title = "Songbook – Summer"
+	p := d.AddPage(A4Width, A4Height)
+	p.Text(50, 700, Courier, 10, "Für (Elise) \\ F♯m")
+	p.Link(50, 695, 100, 12, 1)
+	d.AddPage(A4Width, A4Height).Line(50, 50, 100, 50, 0.5)
+	d.Bookmark("Für Elise", 1)
+
+	var b bytes.Buffer
+	n, err := d.WriteTo(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	if n != int64(len(out)) {
+		t.Errorf("WriteTo() = %d, wrote %d bytes", n, len(out))
+	}
+	if !strings.HasPrefix(out, "%PDF-1.4\n") || !strings.HasSuffix(out, "%%EOF\n") {
+		t.Fatalf("missing header or trailer")
+	}
+
+	// every entry of the cross-reference table points at its object
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindStringSubmatch(out)
+	if m == nil {
+		t.Fatal("missing startxref")
+	}
+	xref, _ := strconv.Atoi(m[1])
+	if !strings.HasPrefix(out[xref:], "xref\n0 ") {
+		t.Fatalf("startxref %d does not point at the cross-reference table", xref)
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n \n`).FindAllStringSubmatch(out[xref:], -1)
+	if len(entries) == 0 {
+		t.Fatal("empty cross-reference table")
+	}
+	for i, e := range entries {
+		off, _ := strconv.Atoi(e[1])
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !strings.HasPrefix(out[off:], want) {
+			t.Errorf("entry %d points at %q", i+1, out[off:off+10])
+		}
+	}
+
+	if !strings.Contains(out, "/Count 2") || !strings.Contains(out, "/Subtype /Link") || !strings.Contains(out, "/Outlines") {
+		t.Errorf("missing pages, link or outline")
+	}
+}
+
+func TestLiteral(t *testing.T) {
+	if got, want := literal("Für (Elise) \\ F♯m"), "(F\xfcr \\(Elise\\) \\\\ F#m)"; got != want {
+		t.Errorf("literal() = %q, want %q", got, want)
+	}
+	// substitutes keep the width of text laid out in columns
+	if got, want := literal("CΔ  G−"), "(C^  G-)"; got != want {
+		t.Errorf("literal() = %q, want %q", got, want)
+	}
+	if got := literal("日本"); got != "(??)" {
+		t.Errorf("literal() = %q, want (??)", got)
+	}
+	if got := textString("Fü"); got != "<FEFF004600FC>" {
+		t.Errorf("textString() = %q", got)
+	}
+}
@@ -0,0 +1,287 @@
+// Package router is a small, method-aware HTTP router with typed path
+// parameters.
+//
+// Patterns are made of slash separated segments. A segment is either a
+// literal ("songs") or contains one parameter in curly braces, optionally
+// with a type and a literal prefix or suffix:
+//
+//	/songs/{id}          any non-empty segment
+//	/songs/{id:slug}     lower case letters, digits and hyphens
+//	/songs/{id:slug}.pdf a slug followed by ".pdf"
+//	/pages/{n:int}       a non-negative integer
+//
+// When a path matches a route but the method does not, the router answers
+// with 405 Method Not Allowed and sets the Allow header.
+package router
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Types that can be used for path parameters, e.g. {id:slug}.
+var paramTypes = map[string]*regexp.Regexp{
+	"":     regexp.MustCompile(`^.+$`),
+	"slug": regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`),
+	"int":  regexp.MustCompile(`^[0-9]+$`),
+}
+
+type segment struct {
+	literal string // set if the segment has no parameter
+	prefix  string
+	name    string
+	typ     *regexp.Regexp
+	suffix  string
+}
+
+type route struct {
+	method   string
+	pattern  string
+	segments []segment
+	handler  http.Handler
+}
+
+// Router dispatches requests to the handler of the best matching route.
+type Router struct {
+	routes []*route
+
+	// NotFound is called if no route matches the path.
+	NotFound http.Handler
+	// MethodNotAllowed is called if a route matches the path but not the
+	// method. The Allow header is set before it is called.
+	MethodNotAllowed http.Handler
+}
+
+func New() *Router {
+	return &Router{
+		NotFound: http.NotFoundHandler(),
+		MethodNotAllowed: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		}),
+	}
+}
+
+// Handle registers a handler for the given method and pattern. It panics
+// if the pattern is malformed, since that is a programming error.
+func (rt *Router) Handle(method, pattern string, handler http.Handler) {
+	rt.routes = append(rt.routes, &route{
+		method:   method,
+		pattern:  pattern,
+		segments: parsePattern(pattern),
+		handler:  handler,
+	})
+}
+
+func (rt *Router) HandleFunc(method, pattern string, handler http.HandlerFunc) {
+	rt.Handle(method, pattern, handler)
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts, ok := splitPath(r.URL.EscapedPath())
+	if !ok {
+		rt.NotFound.ServeHTTP(w, r)
+		return
+	}
+
+	var best *route
+	var bestParams map[string]string
+	allowed := map[string]bool{}
+
+	for _, rte := range rt.routes {
+		params, ok := rte.match(parts)
+		if !ok {
+			continue
+		}
+		allowed[rte.method] = true
+		if rte.method == http.MethodGet {
+			allowed[http.MethodHead] = true
+		}
+		if !methodMatches(rte.method, r.Method) {
+			continue
+		}
+		if best == nil || moreSpecific(rte, best) {
+			best, bestParams = rte, params
+		}
+	}
+
+	if best != nil {
+		ctx := context.WithValue(r.Context(), paramsContextKey, bestParams)
+		best.handler.ServeHTTP(w, r.WithContext(ctx))
+		return
+	}
+
+	if len(allowed) == 0 {
+		rt.NotFound.ServeHTTP(w, r)
+		return
+	}
+
+	methods := make([]string, 0, len(allowed))
+	for m := range allowed {
+		methods = append(methods, m)
+	}
+	sort.Strings(methods)
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	rt.MethodNotAllowed.ServeHTTP(w, r)
+}
+
+func methodMatches(routeMethod, reqMethod string) bool {
+	return routeMethod == reqMethod ||
+		(routeMethod == http.MethodGet && reqMethod == http.MethodHead)
+}
+
+// moreSpecific reports whether a should win over b. Literal segments beat
+// parameters, and typed or affixed parameters beat bare ones.
+func moreSpecific(a, b *route) bool {
+	for i := range a.segments {
+		sa, sb := a.segments[i].specificity(), b.segments[i].specificity()
+		if sa != sb {
+			return sa > sb
+		}
+	}
+	return false
+}
+
+func (s segment) specificity() int {
+	switch {
+	case s.name == "":
+		return 3
+	case s.prefix != "" || s.suffix != "":
+		return 2
+	case s.typ != paramTypes[""]:
+		return 1
+	}
+	return 0
+}
+
+func (rte *route) match(parts []string) (map[string]string, bool) {
+	if len(parts) != len(rte.segments) {
+		return nil, false
+	}
+
+	var params map[string]string
+	for i, seg := range rte.segments {
+		part := parts[i]
+		if seg.name == "" {
+			if part != seg.literal {
+				return nil, false
+			}
+			continue
+		}
+		if !strings.HasPrefix(part, seg.prefix) || !strings.HasSuffix(part, seg.suffix) {
+			return nil, false
+		}
+		if len(part) < len(seg.prefix)+len(seg.suffix) {
+			return nil, false
+		}
+		value := part[len(seg.prefix) : len(part)-len(seg.suffix)]
+		if !seg.typ.MatchString(value) {
+			return nil, false
+		}
+		if params == nil {
+			params = map[string]string{}
+		}
+		params[seg.name] = value
+	}
+
+	return params, true
+}
+
+// splitPath splits an escaped path into unescaped segments. Escaping first
+// lets parameters contain encoded slashes, e.g. the chord "D%2FF%23".
+func splitPath(escaped string) ([]string, bool) {
+	escaped = strings.TrimPrefix(escaped, "/")
+	if escaped == "" {
+		return []string{}, true
+	}
+
+	parts := strings.Split(escaped, "/")
+	for i, p := range parts {
+		unescaped, err := url.PathUnescape(p)
+		if err != nil {
+			return nil, false
+		}
+		parts[i] = unescaped
+	}
+	return parts, true
+}
+
+func parsePattern(pattern string) []segment {
+	if !strings.HasPrefix(pattern, "/") {
+		panic("router: pattern must begin with '/': " + pattern)
+	}
+
+	trimmed := strings.TrimPrefix(pattern, "/")
+	if trimmed == "" {
+		return []segment{}
+	}
+
+	var segments []segment
+	for _, p := range strings.Split(trimmed, "/") {
+		open, close := strings.Index(p, "{"), strings.Index(p, "}")
+		if open == -1 && close == -1 {
+			segments = append(segments, segment{literal: p})
+			continue
+		}
+		if open == -1 || close < open || strings.Count(p, "{") != 1 {
+			panic("router: malformed segment in pattern " + pattern)
+		}
+
+		name, typName, _ := strings.Cut(p[open+1:close], ":")
+		typ, ok := paramTypes[typName]
+		if name == "" || !ok {
+			panic("router: malformed parameter in pattern " + pattern)
+		}
+
+		segments = append(segments, segment{
+			prefix: p[:open],
+			name:   name,
+			typ:    typ,
+			suffix: p[close+1:],
+		})
+	}
+	return segments
+}
+
+type contextKey string
+
+const paramsContextKey = contextKey("params")
+
+// Param returns the value of the path parameter name, or "" if the route
+// has no such parameter.
+func Param(r *http.Request, name string) string {
+	params, _ := r.Context().Value(paramsContextKey).(map[string]string)
+	return params[name]
+}
+
+// IntParam returns the path parameter name as an int. Routes should use the
+// "int" type for the parameter, so the error only occurs on overflow.
+func IntParam(r *http.Request, name string) (int, error) {
+	return strconv.Atoi(Param(r, name))
+}
+
+// Expand fills the parameters of pattern with the path parameters of r,
+// e.g. "/v1/songs/{id}" becomes "/v1/songs/wish-you-were-here".
+func Expand(pattern string, r *http.Request) string {
+	segments := parsePattern(pattern)
+	if len(segments) == 0 {
+		return "/"
+	}
+
+	var b strings.Builder
+	for _, seg := range segments {
+		b.WriteByte('/')
+		if seg.name == "" {
+			b.WriteString(seg.literal)
+			continue
+		}
+		b.WriteString(seg.prefix)
+		b.WriteString(url.PathEscape(Param(r, seg.name)))
+		b.WriteString(seg.suffix)
+	}
+	return b.String()
+}
@@ -0,0 +1,66 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouter(t *testing.T) {
+	rt := New()
+	echo := func(name string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name + ":" + Param(r, "id")))
+		}
+	}
+	rt.HandleFunc(http.MethodGet, "/songs", echo("list"))
+	rt.HandleFunc(http.MethodGet, "/songs/{id:slug}", echo("show"))
+	rt.HandleFunc(http.MethodDelete, "/songs/{id:slug}", echo("delete"))
+	rt.HandleFunc(http.MethodGet, "/songs/{id:slug}.pdf", echo("pdf"))
+	rt.HandleFunc(http.MethodGet, "/chords/{id}", echo("chord"))
+
+	tests := []struct {
+		method, path string
+		status       int
+		body, allow  string
+	}{
+		{"GET", "/songs", 200, "list:", ""},
+		{"GET", "/songs/start-me-up", 200, "show:start-me-up", ""},
+		{"HEAD", "/songs/start-me-up", 200, "", ""},
+		{"DELETE", "/songs/start-me-up", 200, "delete:start-me-up", ""},
+		{"GET", "/songs/start-me-up.pdf", 200, "pdf:start-me-up", ""},
+		{"GET", "/chords/D%2FF%23", 200, "chord:D/F#", ""},
+		{"GET", "/songs/", 404, "", ""},
+		{"GET", "/songs/Not_A_Slug", 404, "", ""},
+		{"GET", "/nope", 404, "", ""},
+		{"POST", "/songs/start-me-up", 405, "", "DELETE, GET, HEAD"},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		rt.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+		if rec.Code != tt.status {
+			t.Errorf("%s %s: status = %d, want %d", tt.method, tt.path, rec.Code, tt.status)
+		}
+		if tt.status == 200 && tt.method != "HEAD" && rec.Body.String() != tt.body {
+			t.Errorf("%s %s: body = %q, want %q", tt.method, tt.path, rec.Body.String(), tt.body)
+		}
+		if got := rec.Header().Get("Allow"); got != tt.allow {
+			t.Errorf("%s %s: Allow = %q, want %q", tt.method, tt.path, got, tt.allow)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	rt := New()
+	var got string
+	rt.HandleFunc(http.MethodGet, "/songs/{id:slug}", func(w http.ResponseWriter, r *http.Request) {
+		got = Expand("/v1/songs/{id:slug}", r)
+	})
+	rt.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/songs/hold-on", nil))
+
+	if want := "/v1/songs/hold-on"; got != want {
+		t.Errorf("Expand = %q, want %q", got, want)
+	}
+}
@@ -4,18 +4,64 @@ import (
 	"net/http"
 
 	"github.com/davidkuda/lyricsapi/handlers"
+	"github.com/davidkuda/lyricsapi/router"
 )
 
-// Go’s servemux supports two different types of URL patterns:
-// fixed paths and subtree paths. Fixed paths do not end with a
-// trailing slash, whereas subtree paths do end with a trailing
-// slash.
-
-func setupHandlers(mux *http.ServeMux, app handlers.Application) {
-	mux.HandleFunc("/healthz", app.HandleHealthCheck)
-	mux.HandleFunc("/songs", app.HandleSongsFixedPath)
-	mux.HandleFunc("/songs/", app.HandleSongsSubtreePath)
-	mux.HandleFunc("/signin", app.Authenticate)
-	mux.HandleFunc("/signout", app.SignOut)
-	mux.HandleFunc("/session", app.HasActiveSession) // check if active session
+// All routes are mounted under /v1. The routes that existed before the API
+// was versioned stay available at their old, unversioned paths, but those
+// responses carry a Deprecation header.
+
+func setupRoutes(app *handlers.Application) http.Handler {
+	rt := router.New()
+	rt.NotFound = http.HandlerFunc(app.HandleNotFound)
+	rt.MethodNotAllowed = http.HandlerFunc(app.HandleMethodNotAllowed)
+
+	// route registers h under /v1 and at each of the deprecated aliases
+	route := func(method, pattern string, h http.HandlerFunc, aliases ...string) {
+		rt.Handle(method, "/v1"+pattern, h)
+		for _, alias := range aliases {
+			rt.Handle(method, alias, app.Deprecated("/v1"+pattern, h))
+		}
+	}
+
+	// probes stay unversioned, they are not part of the API; /healthz is
+	// the liveness probe of old deployments
+	rt.HandleFunc(http.MethodGet, "/livez", app.HandleLiveness)
+	rt.HandleFunc(http.MethodGet, "/healthz", app.HandleLiveness)
+	rt.HandleFunc(http.MethodGet, "/readyz", app.HandleReadiness)
+
+	route(http.MethodGet, "/songs", app.IdentifyUser(app.HandleListSongs), "/songs")
+	route(http.MethodPost, "/songs", app.RequireSession(app.HandleCreateSong), "/songs")
+	route(http.MethodGet, "/songs/{id:slug}", app.IdentifyUser(app.HandleShowSong), "/songs/{id:slug}")
+	route(http.MethodPut, "/songs/{id:slug}", app.RequireSession(app.HandleUpdateSong))
+	route(http.MethodDelete, "/songs/{id:slug}", app.RequireSession(app.HandleDeleteSong), "/songs/{id:slug}")
+
+	route(http.MethodGet, "/songs/{id:slug}/chords", app.IdentifyUser(app.HandleListSongChords))
+	route(http.MethodGet, "/songs/{id:slug}/capo-suggestions", app.IdentifyUser(app.HandleCapoSuggestions))
+	route(http.MethodGet, "/chords/{name}/diagram.svg", app.HandleChordDiagram)
+
+	route(http.MethodGet, "/songs/{id:slug}.pdf", app.IdentifyUser(app.HandleSongPDF))
+	route(http.MethodPost, "/bundle.pdf", app.IdentifyUser(app.HandleBundlePDF))
+	route(http.MethodGet, "/songbook.epub", app.IdentifyUser(app.HandleSongbookEPUB))
+	route(http.MethodGet, "/songs/{id:slug}.xml", app.IdentifyUser(app.HandleSongXML))
+	route(http.MethodPost, "/songs/import", app.RequireSession(app.HandleImportSongs))
+
+	route(http.MethodGet, "/artists", app.HandleListArtists)
+	route(http.MethodGet, "/artists/{id:int}", app.IdentifyUser(app.HandleShowArtist))
+	route(http.MethodPost, "/artists/{id:int}/merge", app.RequireEditor(app.HandleMergeArtists))
+
+	route(http.MethodGet, "/tags", app.IdentifyUser(app.HandleListTags))
+	route(http.MethodGet, "/genres", app.HandleListGenres)
+
+	// deleted songs stay in the trash until they are purged
+	route(http.MethodGet, "/trash", app.RequireAdmin(app.HandleListTrash))
+	route(http.MethodPost, "/songs/{id:slug}/restore", app.RequireAdmin(app.HandleRestoreSong))
+
+	route(http.MethodGet, "/cache/stats", app.RequireSession(app.HandleCacheStats))
+
+	route(http.MethodPost, "/signin", app.Authenticate, "/signin")
+	route(http.MethodPost, "/signout", app.SignOut, "/signout")
+	route(http.MethodGet, "/session", app.HasActiveSession, "/session") // check if active session
+
+	return rt
 }
@@ -0,0 +1,13 @@
+#!/usr/bin/env bash
+# Builds the lyricsapi binary with version information baked in.
+# Usage: ./scripts/build.sh [version]
+set -euo pipefail
+
+VERSION="${1:-$(git describe --tags --always --dirty 2>/dev/null || echo dev)}"
+COMMIT="$(git rev-parse HEAD 2>/dev/null || echo unknown)"
+BUILD_TIME="$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+
+go build \
+  -ldflags "-X main.version=${VERSION} -X main.commit=${COMMIT} -X main.buildTime=${BUILD_TIME}" \
+  -o lyricsapi \
+  .
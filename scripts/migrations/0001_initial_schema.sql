@@ -0,0 +1,26 @@
+-- The schema as it is used by the dbio package. Migrations are applied in
+-- order of their number, e.g.:
+--   for f in scripts/migrations/*.sql; do psql -f "$f"; done
+
+CREATE TABLE IF NOT EXISTS songs (
+    id        TEXT PRIMARY KEY,
+    artist    TEXT NOT NULL,
+    name      TEXT NOT NULL,
+    text      TEXT NOT NULL DEFAULT '',
+    chords    TEXT NOT NULL DEFAULT '',
+    copyright TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS users (
+    name       TEXT PRIMARY KEY,
+    password   BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    token     TEXT PRIMARY KEY,
+    user_name TEXT NOT NULL REFERENCES users (name) ON DELETE CASCADE,
+    expiry    TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS sessions_expiry_idx ON sessions (expiry);
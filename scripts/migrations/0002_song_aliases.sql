@@ -0,0 +1,8 @@
+-- Old IDs of renamed songs. Requests for an alias are redirected to the
+-- song's current ID.
+CREATE TABLE IF NOT EXISTS song_aliases (
+    alias   TEXT PRIMARY KEY,
+    song_id TEXT NOT NULL REFERENCES songs (id) ON UPDATE CASCADE ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS song_aliases_song_id_idx ON song_aliases (song_id);
@@ -0,0 +1,7 @@
+-- Every write to a song gets a new version from a global sequence, so a
+-- version is never reused, not even if a song is deleted and recreated.
+-- The version is the ETag of the song, updated_at its Last-Modified.
+CREATE SEQUENCE IF NOT EXISTS song_version_seq;
+
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT nextval('song_version_seq');
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
@@ -0,0 +1,9 @@
+-- Deleting a song moves it to the trash: deleted_at is set and the song is
+-- hidden, but it keeps its id and can be restored by an admin until it is
+-- purged after the retention period (TRASH_RETENTION_DAYS).
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS songs_deleted_at_idx ON songs (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- The role of a user decides what they may do, see models.Roles.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'member';
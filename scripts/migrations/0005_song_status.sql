@@ -0,0 +1,11 @@
+-- Songs go through a workflow: draft -> in_review -> published -> archived,
+-- see models.StatusTransitions. Only published songs are public; songs that
+-- existed before the workflow are published already. New songs are drafts.
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'published';
+
+-- The author of a song sees it before it is published.
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS created_by TEXT REFERENCES users (name) ON UPDATE CASCADE ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS songs_status_idx ON songs (status);
+
+-- users.role may now also be 'editor', editors publish songs.
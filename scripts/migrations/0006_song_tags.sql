@@ -0,0 +1,21 @@
+-- The genre is a slug of the taxonomy in models.Genres, the language an
+-- ISO 639-1 code. Both are optional.
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS genre TEXT;
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS language TEXT;
+
+CREATE INDEX IF NOT EXISTS songs_genre_idx ON songs (genre);
+CREATE INDEX IF NOT EXISTS songs_language_idx ON songs (language);
+
+-- Tags are shared between songs.
+CREATE TABLE IF NOT EXISTS tags (
+    id   BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS song_tags (
+    song_id TEXT NOT NULL REFERENCES songs (id) ON UPDATE CASCADE ON DELETE CASCADE,
+    tag_id  BIGINT NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+    PRIMARY KEY (song_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS song_tags_tag_id_idx ON song_tags (tag_id);
@@ -0,0 +1,26 @@
+-- Artists are records of their own. songs.artist keeps the canonical name
+-- of the artist of a song, for sorting and for old clients.
+CREATE TABLE IF NOT EXISTS artists (
+    id        BIGSERIAL PRIMARY KEY,
+    name      TEXT NOT NULL,
+    sort_name TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS artists_sort_name_idx ON artists (sort_name);
+
+-- Every spelling of the name of an artist, including the canonical one.
+-- key is models.ArtistKey of the name, spellings with the same key are the
+-- same artist.
+CREATE TABLE IF NOT EXISTS artist_aliases (
+    key       TEXT PRIMARY KEY,
+    artist_id BIGINT NOT NULL REFERENCES artists (id) ON DELETE CASCADE,
+    name      TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS artist_aliases_artist_id_idx ON artist_aliases (artist_id);
+
+-- The artists of existing songs are created by dbio.LinkArtists when the
+-- API starts, since the keys are computed in Go.
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS artist_id BIGINT REFERENCES artists (id);
+
+CREATE INDEX IF NOT EXISTS songs_artist_id_idx ON songs (artist_id);
@@ -0,0 +1,5 @@
+-- The structured body of a song: its sections and arrangement as JSON, see
+-- models.Section. text and chords are derived from it when it is written,
+-- so clients that don't know sections keep working. NULL for songs that
+-- only have plain text.
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS body JSONB;
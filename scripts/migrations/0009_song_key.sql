@@ -0,0 +1,8 @@
+-- The key of a song in the notation of chords, e.g. "Am" or "Eb". It is
+-- detected from the chords with a confidence from 0 to 1. If an editor
+-- sets the key, key_confidence is NULL. Songs that haven't been analysed
+-- yet have no key; the server detects it at startup.
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS key TEXT;
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS key_confidence REAL;
+
+CREATE INDEX IF NOT EXISTS songs_key_idx ON songs (key);
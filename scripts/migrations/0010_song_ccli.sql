@@ -0,0 +1,4 @@
+-- The number of a song at CCLI, the licensing service that churches
+-- report the songs they sing to. Presentation software such as OpenLyrics
+-- and OpenSong carries it along with the copyright.
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS ccli TEXT;
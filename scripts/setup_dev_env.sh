@@ -6,3 +6,9 @@ export DB_PASSWORD="lyricsapi"
 
 # CORS
 export ALLOWED_CORS_ORIGINS="http://localhost:8008"
+
+# Cache songs in redis instead of in memory (optional)
+# export REDIS_ADDR="localhost:6379"
+
+# Days a deleted song stays in the trash before it is purged (default: 30)
+# export TRASH_RETENTION_DAYS="30"
@@ -0,0 +1,178 @@
+package sheet
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/davidkuda/lyricsapi/epub"
+	"github.com/davidkuda/lyricsapi/models"
+)
+
+// EPUBWidth is the width of the lines of chord sheets in EPUBs. Chords
+// must stay above their syllables, so the lines can't flow, and the
+// screens of e-readers are narrow.
+const EPUBWidth = 48
+
+const epubStylesheet = `body { font-family: serif; }
+h1 { font-size: 1.4em; margin: 0 0 0.2em; }
+h2 { font-size: 1em; margin: 1em 0 0.2em; }
+pre { font-family: monospace; white-space: pre; margin: 0; }
+.byline { font-style: italic; margin: 0 0 1em; }
+.chord { font-weight: bold; color: #1a4d8f; }
+.copyright { font-size: 0.8em; margin-top: 2em; }
+.cover { text-align: center; }
+.cover img { height: 100%; max-width: 100%; }
+`
+
+// EPUB writes songs as an EPUB songbook titled title to w: a cover, a
+// table of contents and a page for each song, with its chords above the
+// lyrics as in Text, and its copyright. The same songs in the same
+// versions give the same file.
+func EPUB(w io.Writer, songs []models.Song, title string) error {
+	book := &epub.Book{
+		Identifier: songbookID(title, songs),
+		Title:      title,
+		Language:   songbookLanguage(songs),
+		Stylesheet: epubStylesheet,
+		Cover:      songbookCover(title, songs),
+	}
+
+	seen := map[string]bool{}
+	for i := range songs {
+		s := &songs[i]
+		if s.Artist != "" && !seen[s.Artist] {
+			seen[s.Artist] = true
+			book.Creators = append(book.Creators, s.Artist)
+		}
+		if s.UpdatedAt.After(book.Modified) {
+			book.Modified = s.UpdatedAt
+		}
+
+		chapter := epub.Chapter{Title: s.Name, Body: songXHTML(s)}
+		if s.Artist != "" {
+			chapter.Title += " – " + s.Artist
+		}
+		if s.Language != "" && s.Language != book.Language {
+			chapter.Language = s.Language
+		}
+		book.Chapters = append(book.Chapters, chapter)
+	}
+	if book.Modified.IsZero() {
+		// ZIP files can't tell earlier times
+		book.Modified = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	return book.Write(w)
+}
+
+// songXHTML returns the body of the page of s.
+func songXHTML(s *models.Song) string {
+	var b strings.Builder
+	b.WriteString(`<section epub:type="chapter">` + "\n")
+	b.WriteString("<h1>" + epub.Escape(s.Name) + "</h1>\n")
+
+	var byline []string
+	if s.Artist != "" {
+		byline = append(byline, s.Artist)
+	}
+	if s.Key != "" {
+		byline = append(byline, "Key: "+s.Key)
+	}
+	if len(byline) > 0 {
+		b.WriteString(`<p class="byline">` + epub.Escape(strings.Join(byline, " · ")) + "</p>\n")
+	}
+
+	for _, block := range Blocks(s) {
+		b.WriteString("<div>\n")
+		if block.Title != "" {
+			b.WriteString("<h2>" + epub.Escape(block.Title) + "</h2>\n")
+		}
+		var rows []string
+		for _, line := range block.Lines {
+			for _, row := range Rows(line, EPUBWidth) {
+				text := epub.Escape(row.Text)
+				if row.Chords {
+					text = `<span class="chord">` + text + "</span>"
+				}
+				rows = append(rows, text)
+			}
+		}
+		b.WriteString("<pre>" + strings.Join(rows, "\n") + "</pre>\n")
+		b.WriteString("</div>\n")
+	}
+
+	if s.Copyright != "" {
+		b.WriteString(`<p class="copyright">© ` + epub.Escape(strings.TrimPrefix(s.Copyright, "© ")) + "</p>\n")
+	}
+	b.WriteString("</section>")
+	return b.String()
+}
+
+// songbookID returns a UUID derived from the title and the versions of the
+// songs, so that a songbook keeps its identifier until it changes.
+func songbookID(title string, songs []models.Song) string {
+	h := sha256.New()
+	io.WriteString(h, title)
+	for _, s := range songs {
+		fmt.Fprintf(h, "\n%s@%d", s.ID, s.Version)
+	}
+	sum := h.Sum(nil)
+	// a name-based UUID of RFC 4122, version 5
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// songbookLanguage returns the language most of songs are in, or "und"
+// for undetermined.
+func songbookLanguage(songs []models.Song) string {
+	counts := map[string]int{}
+	language := "und"
+	for _, s := range songs {
+		if s.Language == "" {
+			continue
+		}
+		counts[s.Language]++
+		if counts[s.Language] > counts[language] {
+			language = s.Language
+		}
+	}
+	return language
+}
+
+// maxCoverLineChars is the width of the lines of the title on the cover.
+const maxCoverLineChars = 18
+
+// songbookCover draws the cover: the title and how many songs there are.
+func songbookCover(title string, songs []models.Song) []byte {
+	var lines []string
+	for _, word := range strings.Fields(title) {
+		if n := len(lines); n > 0 && len([]rune(lines[n-1]))+1+len([]rune(word)) <= maxCoverLineChars {
+			lines[n-1] += " " + word
+			continue
+		}
+		lines = append(lines, word)
+	}
+
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<svg xmlns="http://www.w3.org/2000/svg" width="600" height="800" viewBox="0 0 600 800">` + "\n")
+	b.WriteString(`<rect width="600" height="800" fill="#1a4d8f"/>` + "\n")
+	b.WriteString(`<rect x="30" y="30" width="540" height="740" fill="none" stroke="#fff" stroke-width="2"/>` + "\n")
+	y := 320 - 28*len(lines)
+	for _, line := range lines {
+		fmt.Fprintf(&b, `<text x="300" y="%d" fill="#fff" font-family="serif" font-size="48" text-anchor="middle">%s</text>`+"\n", y, epub.Escape(line))
+		y += 60
+	}
+	count := strconv.Itoa(len(songs)) + " songs"
+	if len(songs) == 1 {
+		count = "1 song"
+	}
+	fmt.Fprintf(&b, `<text x="300" y="%d" fill="#fff" font-family="serif" font-size="24" text-anchor="middle">%s</text>`+"\n", y+40, count)
+	b.WriteString("</svg>\n")
+	return []byte(b.String())
+}
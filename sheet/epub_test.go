@@ -0,0 +1,79 @@
+package sheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davidkuda/lyricsapi/models"
+)
+
+func TestEPUB(t *testing.T) {
+	songs := []models.Song{
+		{ID: "start-me-up", Version: 3, Name: "Start Me Up", Artist: "The Rolling Stones", Language: "en", Key: "C",
+			Copyright: "Promopub B.V.", Text: "[Chorus]\n[C]If you start me [F]up <now>",
+			UpdatedAt: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)},
+		{ID: "99-luftballons", Version: 1, Name: "99 Luftballons", Artist: "Nena", Language: "de", Text: "Hast du etwas Zeit für mich"},
+		{ID: "angie", Version: 7, Name: "Angie", Artist: "The Rolling Stones", Language: "en"},
+	}
+
+	var b bytes.Buffer
+	if err := EPUB(&b, songs, "Campfire Songs of the Summer"); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(content)
+	}
+
+	opf := files["OEBPS/content.opf"]
+	for _, want := range []string{
+		"<dc:language>en</dc:language>",
+		"<dc:creator>The Rolling Stones</dc:creator>\n    <dc:creator>Nena</dc:creator>\n    <meta",
+		"2024-03-01T12:00:00Z",
+		"urn:uuid:",
+	} {
+		if !strings.Contains(opf, want) {
+			t.Errorf("package document does not contain %q:\n%s", want, opf)
+		}
+	}
+
+	page := files["OEBPS/chapter-001.xhtml"]
+	for _, want := range []string{
+		"<title>Start Me Up – The Rolling Stones</title>",
+		`<p class="byline">The Rolling Stones · Key: C</p>`,
+		"<h2>Chorus</h2>",
+		"<pre><span class=\"chord\">C               F</span>\nIf you start me up &lt;now&gt;</pre>",
+		`<p class="copyright">© Promopub B.V.</p>`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("page does not contain %q:\n%s", want, page)
+		}
+	}
+	if !strings.Contains(files["OEBPS/chapter-002.xhtml"], `xml:lang="de"`) {
+		t.Errorf("German song is not marked as German")
+	}
+	if cover := files["OEBPS/cover.svg"]; !strings.Contains(cover, ">Campfire Songs of<") || !strings.Contains(cover, ">3 songs<") {
+		t.Errorf("cover:\n%s", cover)
+	}
+
+	// a new version of a song is a new edition of the book
+	id := songbookID("Campfire", songs)
+	if songbookID("Campfire", songs) != id {
+		t.Errorf("songbookID() is not stable")
+	}
+	songs[2].Version++
+	if songbookID("Campfire", songs) == id {
+		t.Errorf("songbookID() ignores versions")
+	}
+}
@@ -0,0 +1,259 @@
+package sheet
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/davidkuda/lyricsapi/chord"
+	"github.com/davidkuda/lyricsapi/models"
+)
+
+// Severities of diagnostics. Songs with errors are rejected, warnings are
+// reported but the song is stored.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Codes of diagnostics.
+const (
+	CodeUnknownChord       = "unknown_chord"
+	CodeMixedAccidentals   = "mixed_accidentals"
+	CodeMisplacedChord     = "misplaced_chord"
+	CodeTrailingWhitespace = "trailing_whitespace"
+)
+
+// Diagnostic is a problem Lint found in a song. Field is the JSON name of
+// the field, e.g. "chords" or "sections[0].lines[2].chords[1].chord". Line
+// and Column count from 1 within the field, and are 0 if they don't apply.
+type Diagnostic struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Field    string `json:"field"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Message  string `json:"message"`
+}
+
+// HasErrors reports whether any of ds is an error.
+func HasErrors(ds []Diagnostic) bool {
+	for _, d := range ds {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Lint checks the chord sheet of s: chords that can't be parsed, notes
+// spelled with both sharps and flats, chords that don't sit on the lyrics
+// and trailing whitespace. The lyrics and chords of songs with sections are
+// derived from them, so only the sections are checked.
+func Lint(s *models.Song) []Diagnostic {
+	var ds []Diagnostic
+	field := "sections"
+	if len(s.Sections) > 0 {
+		ds = lintSections(s)
+	} else {
+		field = "chords"
+		if hasInlineChords(s.Text) {
+			field = "lyrics"
+		}
+		ds = append(ds, lintText(s.Text)...)
+		ds = append(ds, lintChords(s.Text, s.Chords)...)
+	}
+
+	if d, ok := lintAccidentals(PlayedChords(s)); ok {
+		d.Field = field
+		ds = append(ds, d)
+	}
+	return ds
+}
+
+func lintSections(s *models.Song) []Diagnostic {
+	var ds []Diagnostic
+	for i, sec := range s.Sections {
+		for j, line := range sec.Lines {
+			lineKey := fmt.Sprintf("sections[%d].lines[%d]", i, j)
+			if hasTrailingWhitespace(line.Lyrics) {
+				ds = append(ds, trailingWhitespace(lineKey+".lyrics", 0))
+			}
+
+			length := utf8.RuneCountInString(line.Lyrics)
+			for k, c := range line.Chords {
+				chordKey := fmt.Sprintf("%s.chords[%d]", lineKey, k)
+				if !IsChord(c.Chord) {
+					ds = append(ds, unknownChord(chordKey+".chord", 0, 0, c.Chord))
+				}
+				if line.Lyrics != "" && c.Position > length {
+					ds = append(ds, Diagnostic{
+						Severity: SeverityWarning,
+						Code:     CodeMisplacedChord,
+						Field:    chordKey + ".position",
+						Message:  fmt.Sprintf("%s is at position %d, after the end of the lyrics at %d", c.Chord, c.Position, length),
+					})
+				}
+			}
+		}
+	}
+	return ds
+}
+
+// lintText checks the lyrics and the chords in brackets in them.
+func lintText(text string) []Diagnostic {
+	var ds []Diagnostic
+	if text == "" {
+		return nil
+	}
+	for i, line := range strings.Split(text, "\n") {
+		if hasTrailingWhitespace(line) {
+			ds = append(ds, trailingWhitespace("lyrics", i+1))
+		}
+		for _, loc := range inlineRX.FindAllStringSubmatchIndex(line, -1) {
+			symbol := line[loc[2]:loc[3]]
+			if looksLikeChord(symbol) && !IsChord(symbol) {
+				col := utf8.RuneCountInString(line[:loc[0]]) + 1
+				ds = append(ds, unknownChord("lyrics", i+1, col, symbol))
+			}
+		}
+	}
+	return ds
+}
+
+// lintChords checks the lines of chords, and whether each of them sits
+// above a line of lyrics.
+func lintChords(text, chords string) []Diagnostic {
+	if chords == "" {
+		return nil
+	}
+
+	var ds []Diagnostic
+	chordLines := strings.Split(chords, "\n")
+	for i, line := range chordLines {
+		if hasTrailingWhitespace(line) {
+			ds = append(ds, trailingWhitespace("chords", i+1))
+		}
+		for _, c := range ParseChordLine(line) {
+			if looksLikeChord(c.Chord) && !IsChord(c.Chord) {
+				ds = append(ds, unknownChord("chords", i+1, c.Position+1, c.Chord))
+			}
+		}
+	}
+
+	// with inline chords or without lyrics, the chords stand on their own
+	if text == "" || hasInlineChords(text) {
+		return ds
+	}
+	textLines := strings.Split(text, "\n")
+	if len(textLines) != len(chordLines) {
+		return append(ds, Diagnostic{
+			Severity: SeverityWarning,
+			Code:     CodeMisplacedChord,
+			Field:    "chords",
+			Message:  fmt.Sprintf("has %d lines but the lyrics have %d, so the chords can't be placed above the lyrics", len(chordLines), len(textLines)),
+		})
+	}
+	for i, line := range chordLines {
+		lyrics := strings.TrimRight(textLines[i], " \t\r")
+		if lyrics == "" {
+			// instrumental lines have chords only
+			continue
+		}
+		length := utf8.RuneCountInString(lyrics)
+		for _, c := range ParseChordLine(line) {
+			if c.Position > length && IsChord(c.Chord) {
+				ds = append(ds, Diagnostic{
+					Severity: SeverityWarning,
+					Code:     CodeMisplacedChord,
+					Field:    "chords",
+					Line:     i + 1,
+					Column:   c.Position + 1,
+					Message:  fmt.Sprintf("%s is after the end of line %d of the lyrics", c.Chord, i+1),
+				})
+			}
+		}
+	}
+	return ds
+}
+
+// lintAccidentals warns about songs that spell notes with both sharps and
+// flats, e.g. "Gm F# Bb", which is usually a typo.
+func lintAccidentals(played []string) (Diagnostic, bool) {
+	var sharps, flats []string
+	seen := map[string]bool{}
+	for _, symbol := range played {
+		c, err := chord.Parse(symbol)
+		if err != nil {
+			continue
+		}
+		for _, note := range []string{c.Root, c.Bass} {
+			if len(note) < 2 || seen[note] {
+				continue
+			}
+			seen[note] = true
+			switch {
+			case strings.ContainsAny(note[1:], "#♯"):
+				sharps = append(sharps, note)
+			case strings.ContainsAny(note[1:], "b♭"):
+				flats = append(flats, note)
+			}
+		}
+	}
+	if len(sharps) == 0 || len(flats) == 0 {
+		return Diagnostic{}, false
+	}
+	return Diagnostic{
+		Severity: SeverityWarning,
+		Code:     CodeMixedAccidentals,
+		Message: fmt.Sprintf("the chords spell notes with sharps (%s) and flats (%s), use one or the other",
+			strings.Join(sharps, ", "), strings.Join(flats, ", ")),
+	}, true
+}
+
+// annotationRX matches the words of chord sheets that aren't chords:
+// labels ("Intro:"), remarks in parentheses, bar lines, repeats ("x2") and
+// "N.C." for no chord.
+var annotationRX = regexp.MustCompile(`^(?:.*:|\(.*\)|[|/%.\-]+|[xX]?\d+[xX]?|N\.?C\.?)$`)
+
+// wordRX matches capitalized words such as "Chorus" or "Bridge". Chords
+// that are spelled with letters only are shorter, e.g. "Cdim" or "Bbsus".
+var wordRX = regexp.MustCompile(`^[A-Z][a-z]{4,}$`)
+
+// shortWords are section names that are short enough to pass for chords.
+var shortWords = map[string]bool{"Coda": true, "End": true, "Fine": true}
+
+// looksLikeChord reports whether s was meant to be a chord, that is whether
+// it starts with a note name and isn't a label or remark.
+func looksLikeChord(s string) bool {
+	if s == "" || s[0] < 'A' || s[0] > 'G' {
+		return false
+	}
+	return !annotationRX.MatchString(s) && !wordRX.MatchString(s) && !shortWords[s]
+}
+
+func hasTrailingWhitespace(line string) bool {
+	return strings.TrimRight(line, " \t\r") != line
+}
+
+func trailingWhitespace(field string, line int) Diagnostic {
+	return Diagnostic{
+		Severity: SeverityWarning,
+		Code:     CodeTrailingWhitespace,
+		Field:    field,
+		Line:     line,
+		Message:  "has trailing whitespace",
+	}
+}
+
+func unknownChord(field string, line, col int, symbol string) Diagnostic {
+	return Diagnostic{
+		Severity: SeverityError,
+		Code:     CodeUnknownChord,
+		Field:    field,
+		Line:     line,
+		Column:   col,
+		Message:  fmt.Sprintf("%q is not a chord symbol", symbol),
+	}
+}
@@ -0,0 +1,93 @@
+package sheet
+
+import (
+	"testing"
+
+	"github.com/davidkuda/lyricsapi/models"
+)
+
+func TestLint(t *testing.T) {
+	type finding struct {
+		code, field  string
+		line, column int
+	}
+	tests := []struct {
+		name string
+		song models.Song
+		want []finding
+	}{
+		{
+			name: "clean",
+			song: models.Song{Text: "If you start me up", Chords: "C      F"},
+		},
+		{
+			name: "annotations",
+			song: models.Song{Chords: "Intro: C F x2\n| Am | N.C. | (let ring)\nCoda G"},
+		},
+		{
+			name: "unknown chord",
+			song: models.Song{Text: "If you start me up", Chords: "Cmja7  F"},
+			want: []finding{{CodeUnknownChord, "chords", 1, 1}},
+		},
+		{
+			name: "unknown inline chord",
+			song: models.Song{Text: "[Chorus]\nIf you [Cmja7]start me [F]up"},
+			want: []finding{{CodeUnknownChord, "lyrics", 2, 8}},
+		},
+		{
+			name: "mixed accidentals",
+			song: models.Song{Chords: "Gm F# Bb C9"},
+			want: []finding{{CodeMixedAccidentals, "chords", 0, 0}},
+		},
+		{
+			name: "chord after the lyrics",
+			song: models.Song{Text: "Start me up\n\nSolo", Chords: "C     F       G\nAm\n"},
+			want: []finding{{CodeMisplacedChord, "chords", 1, 15}},
+		},
+		{
+			name: "line counts differ",
+			song: models.Song{Text: "If you start me up\nI'll never stop", Chords: "C      F"},
+			want: []finding{{CodeMisplacedChord, "chords", 0, 0}},
+		},
+		{
+			name: "trailing whitespace",
+			song: models.Song{Text: "If you start me up \nI'll never stop"},
+			want: []finding{{CodeTrailingWhitespace, "lyrics", 1, 0}},
+		},
+		{
+			name: "sections",
+			song: models.Song{Sections: []models.Section{{Label: "V1", Kind: models.SectionVerse, Lines: []models.Line{
+				{Lyrics: "Start me up\t", Chords: []models.ChordPosition{{Chord: "Cmja7", Position: 0}, {Chord: "F", Position: 20}}},
+			}}}},
+			want: []finding{
+				{CodeTrailingWhitespace, "sections[0].lines[0].lyrics", 0, 0},
+				{CodeUnknownChord, "sections[0].lines[0].chords[0].chord", 0, 0},
+				{CodeMisplacedChord, "sections[0].lines[0].chords[1].position", 0, 0},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Lint(&tt.song)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Lint() = %+v, want %d diagnostics", got, len(tt.want))
+			}
+			hasErrors := false
+			for i, w := range tt.want {
+				d := got[i]
+				if d.Code != w.code || d.Field != w.field || d.Line != w.line || d.Column != w.column {
+					t.Errorf("diagnostic %d = %+v, want %+v", i, d, w)
+				}
+				isError := w.code == CodeUnknownChord
+				if (d.Severity == SeverityError) != isError {
+					t.Errorf("diagnostic %d has severity %q", i, d.Severity)
+				}
+				hasErrors = hasErrors || isError
+			}
+			if HasErrors(got) != hasErrors {
+				t.Errorf("HasErrors() = %v, want %v", !hasErrors, hasErrors)
+			}
+		})
+	}
+}
@@ -0,0 +1,256 @@
+package sheet
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/davidkuda/lyricsapi/models"
+	"github.com/davidkuda/lyricsapi/pdf"
+)
+
+// PDFOptions control the layout of PDF songbooks.
+type PDFOptions struct {
+	// Title is the title of the document and of its table of contents.
+	Title string
+	// Columns is 1 or 2. Songs run from the left column into the right one.
+	Columns int
+	// Contents starts the document with a table of contents.
+	Contents bool
+}
+
+// The layout of the pages, in points. Chords and lyrics are set in
+// Courier, so that chords line up with the syllables they are played on.
+const (
+	pdfMargin     = 50.0
+	pdfGutter     = 18.0
+	pdfFontSize   = 10.0
+	pdfLeading    = 12.0
+	pdfTitleSize  = 16.0
+	pdfFooterSize = 9.0
+	pdfFooter     = 30.0 // baseline of the page numbers
+
+	pdfContentsLeading = 14.0
+	// the longest song titles in the header, Helvetica is wider than Courier
+	maxPDFTitleChars = 55
+)
+
+// PDF writes songs as a PDF songbook to w. Each song starts on a new page,
+// in the layout of Text. The pages are numbered, and every song has a
+// bookmark in the outline of the document.
+func PDF(w io.Writer, songs []models.Song, opts PDFOptions) error {
+	doc := &pdf.Document{Title: opts.Title}
+	if doc.Title == "" && len(songs) == 1 {
+		doc.Title, doc.Author = songs[0].Name, songs[0].Artist
+	}
+	columns := 1
+	if opts.Columns == 2 {
+		columns = 2
+	}
+
+	var contents []contentsRow
+	if opts.Contents {
+		contents = contentsLayout(len(songs))
+		for i := 0; i <= contents[len(contents)-1].page; i++ {
+			doc.AddPage(pdf.A4Width, pdf.A4Height)
+		}
+	}
+
+	starts := make([]int, len(songs))
+	for i := range songs {
+		starts[i] = doc.PageCount()
+		newColumnWriter(doc, &songs[i], columns).write()
+		doc.Bookmark(songs[i].Name, starts[i])
+	}
+
+	if opts.Contents {
+		writeContents(doc, songs, starts, contents, opts.Title)
+	}
+
+	for i := 0; i < doc.PageCount(); i++ {
+		n := strconv.Itoa(i + 1)
+		x := pdf.A4Width/2 - float64(len(n))*pdf.CharWidth*pdfFooterSize/2
+		doc.Page(i).Text(x, pdfFooter, pdf.Courier, pdfFooterSize, n)
+	}
+
+	_, err := doc.WriteTo(w)
+	return err
+}
+
+// columnWriter fills the columns of the pages of a song from top to bottom.
+type columnWriter struct {
+	doc  *pdf.Document
+	song *models.Song
+	page *pdf.Page
+
+	columns, col int
+	colWidth     float64 // in points
+	chars        int     // characters that fit in a column
+	top, y       float64 // baselines of the first and the next row
+}
+
+func newColumnWriter(doc *pdf.Document, s *models.Song, columns int) *columnWriter {
+	colWidth := (pdf.A4Width - 2*pdfMargin - float64(columns-1)*pdfGutter) / float64(columns)
+	return &columnWriter{
+		doc:      doc,
+		song:     s,
+		columns:  columns,
+		colWidth: colWidth,
+		chars:    columnChars(colWidth),
+	}
+}
+
+// write writes the header and the blocks of the song.
+func (cw *columnWriter) write() {
+	cw.page = cw.doc.AddPage(pdf.A4Width, pdf.A4Height)
+	y := pdf.A4Height - pdfMargin
+	cw.page.Text(pdfMargin, y, pdf.HelveticaBold, pdfTitleSize, truncate(cw.song.Name, maxPDFTitleChars))
+	var byline []string
+	if cw.song.Artist != "" {
+		byline = append(byline, cw.song.Artist)
+	}
+	if cw.song.Key != "" {
+		byline = append(byline, "Key: "+cw.song.Key)
+	}
+	if len(byline) > 0 {
+		y -= 18
+		cw.page.Text(pdfMargin, y, pdf.Helvetica, pdfFontSize, strings.Join(byline, "  ·  "))
+	}
+	y -= 8
+	cw.page.Line(pdfMargin, y, pdf.A4Width-pdfMargin, y, 0.5)
+	cw.top = y - 20
+	cw.y = cw.top
+
+	for i, block := range Blocks(cw.song) {
+		if i > 0 {
+			cw.y -= pdfLeading
+		}
+		var title []Row
+		if block.Title != "" {
+			title = []Row{{Text: "[" + block.Title + "]", Chords: true}}
+		}
+		for j, line := range block.Lines {
+			rows := Rows(line, cw.chars)
+			if j == 0 {
+				// a title stays with the first line of its block
+				rows = append(title, rows...)
+			}
+			cw.rows(rows)
+		}
+		if len(block.Lines) == 0 && len(title) > 0 {
+			cw.rows(title)
+		}
+	}
+
+	if cw.song.Copyright != "" {
+		cw.y -= pdfLeading
+		cw.fit(1)
+		cw.page.Text(cw.x(), cw.y, pdf.Helvetica, 8, "© "+strings.TrimPrefix(cw.song.Copyright, "© "))
+	}
+}
+
+// rows writes rows that belong together, in the next column if they don't
+// fit in this one.
+func (cw *columnWriter) rows(rows []Row) {
+	cw.fit(len(rows))
+	for _, row := range rows {
+		if cw.y < pdfMargin {
+			cw.nextColumn()
+		}
+		font := pdf.Courier
+		if row.Chords {
+			font = pdf.CourierBold
+		}
+		cw.page.Text(cw.x(), cw.y, font, pdfFontSize, row.Text)
+		cw.y -= pdfLeading
+	}
+}
+
+// fit moves on to the next column unless n rows fit in this one. Rows that
+// don't fit in an empty column are broken up.
+func (cw *columnWriter) fit(n int) {
+	if cw.y-float64(n-1)*pdfLeading < pdfMargin && cw.y < cw.top {
+		cw.nextColumn()
+	}
+}
+
+func (cw *columnWriter) nextColumn() {
+	cw.col++
+	if cw.col < cw.columns {
+		cw.y = cw.top
+		return
+	}
+
+	cw.page = cw.doc.AddPage(pdf.A4Width, pdf.A4Height)
+	cw.col = 0
+	y := pdf.A4Height - pdfMargin
+	cw.page.Text(pdfMargin, y, pdf.Helvetica, pdfFooterSize, truncate(cw.song.Name, maxPDFTitleChars)+" (continued)")
+	cw.top = y - 24
+	cw.y = cw.top
+}
+
+func (cw *columnWriter) x() float64 {
+	return pdfMargin + float64(cw.col)*(cw.colWidth+pdfGutter)
+}
+
+// contentsRow is the position of an entry of the table of contents.
+type contentsRow struct {
+	page int
+	y    float64
+}
+
+// contentsLayout places n entries of the table of contents. The first page
+// starts below the title.
+func contentsLayout(n int) []contentsRow {
+	rows := make([]contentsRow, 0, n)
+	page, y := 0, pdf.A4Height-pdfMargin-36
+	for i := 0; i < n; i++ {
+		if y < pdfMargin {
+			page, y = page+1, pdf.A4Height-pdfMargin
+		}
+		rows = append(rows, contentsRow{page: page, y: y})
+		y -= pdfContentsLeading
+	}
+	if len(rows) == 0 {
+		rows = append(rows, contentsRow{})
+	}
+	return rows
+}
+
+// writeContents lists the songs with the numbers of the pages they start
+// on, each linked to its page.
+func writeContents(doc *pdf.Document, songs []models.Song, starts []int, rows []contentsRow, title string) {
+	if title == "" {
+		title = "Contents"
+	}
+	doc.Page(0).Text(pdfMargin, pdf.A4Height-pdfMargin, pdf.HelveticaBold, pdfTitleSize, truncate(title, maxPDFTitleChars))
+
+	chars := columnChars(pdf.A4Width - 2*pdfMargin)
+	for i, s := range songs {
+		n := strconv.Itoa(starts[i] + 1)
+		label := s.Name
+		if s.Artist != "" {
+			label += " – " + s.Artist
+		}
+		label = truncate(label, chars-len(n)-4)
+		dots := chars - utf8.RuneCountInString(label) - len(n) - 2
+
+		p := doc.Page(rows[i].page)
+		p.Text(pdfMargin, rows[i].y, pdf.Courier, pdfFontSize, label+" "+strings.Repeat(".", dots)+" "+n)
+		p.Link(pdfMargin, rows[i].y-3, float64(chars)*pdf.CharWidth*pdfFontSize, pdfContentsLeading, starts[i])
+	}
+}
+
+// columnChars returns how many characters fit in width points.
+func columnChars(width float64) int {
+	return int(width / (pdf.CharWidth * pdfFontSize))
+}
+
+// truncate shortens s to at most n characters, with an ellipsis.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
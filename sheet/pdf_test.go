@@ -0,0 +1,98 @@
+package sheet
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/davidkuda/lyricsapi/models"
+)
+
+// pdfPages returns the content streams of the pages of a PDF.
+func pdfPages(t *testing.T, doc []byte) []string {
+	t.Helper()
+	var pages []string
+	rx := regexp.MustCompile(`(?s)/FlateDecode >>\nstream\n(.*?)\nendstream`)
+	for _, m := range rx.FindAllSubmatch(doc, -1) {
+		zr, err := zlib.NewReader(bytes.NewReader(m[1]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages = append(pages, string(content))
+	}
+	return pages
+}
+
+func TestPDF(t *testing.T) {
+	short := models.Song{Name: "Start Me Up", Artist: "The Rolling Stones", Key: "C", Copyright: "Promopub B.V.",
+		Text: "[Chorus]\n[C]If you start me [F]up"}
+
+	var verses []string
+	for i := 0; i < 80; i++ {
+		verses = append(verses, "[G]Hold on to [D]me")
+	}
+	long := models.Song{Name: "Hold On", Artist: "davidkuda", Text: strings.Join(verses, "\n\n")}
+
+	render := func(songs []models.Song, opts PDFOptions) []string {
+		var b bytes.Buffer
+		if err := PDF(&b, songs, opts); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(b.Bytes(), []byte("%PDF-")) {
+			t.Fatal("not a PDF")
+		}
+		return pdfPages(t, b.Bytes())
+	}
+
+	pages := render([]models.Song{short}, PDFOptions{})
+	if len(pages) != 1 {
+		t.Fatalf("%d pages, want 1", len(pages))
+	}
+	for _, want := range []string{"(Start Me Up)", "(The Rolling Stones  \xb7  Key: C)", "/F2 10 Tf 50 ", "([Chorus])", "(C               F)", "(If you start me up)", "(\xa9 Promopub B.V.)"} {
+		if !strings.Contains(pages[0], want) {
+			t.Errorf("page does not contain %q:\n%s", want, pages[0])
+		}
+	}
+
+	oneColumn := render([]models.Song{long}, PDFOptions{})
+	twoColumns := render([]models.Song{long}, PDFOptions{Columns: 2})
+	if len(oneColumn) < 2 || len(twoColumns) >= len(oneColumn) {
+		t.Errorf("long song has %d pages in one column and %d in two", len(oneColumn), len(twoColumns))
+	}
+	if !strings.Contains(oneColumn[1], "(Hold On \\(continued\\))") {
+		t.Errorf("second page has no header:\n%s", oneColumn[1][:200])
+	}
+
+	pages = render([]models.Song{short, long}, PDFOptions{Title: "Campfire", Contents: true})
+	if len(pages) != 2+len(oneColumn) {
+		t.Fatalf("%d pages, want contents, 1 and %d", len(pages), len(oneColumn))
+	}
+	for _, want := range []string{"(Campfire)", "(Start Me Up \x96 The Rolling Stones ", " 2)", "(Hold On \x96 davidkuda ", " 3)"} {
+		if !strings.Contains(pages[0], want) {
+			t.Errorf("contents do not contain %q:\n%s", want, pages[0])
+		}
+	}
+	if last := fmt.Sprintf("(%d)", len(pages)); !strings.Contains(pages[len(pages)-1], last) {
+		t.Errorf("last page is not numbered %s", last)
+	}
+}
+
+func TestContentsLayout(t *testing.T) {
+	rows := contentsLayout(120)
+	if rows[0].page != 0 || rows[119].page != 2 {
+		t.Errorf("120 entries span pages %d to %d, want 0 to 2", rows[0].page, rows[119].page)
+	}
+	for i := 1; i < len(rows); i++ {
+		if rows[i].page == rows[i-1].page && rows[i].y >= rows[i-1].y {
+			t.Fatalf("entry %d is not below entry %d", i, i-1)
+		}
+	}
+}
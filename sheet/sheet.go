@@ -0,0 +1,400 @@
+// Package sheet turns songs into chord sheets. Songs come in several
+// shapes: with structured sections (see models.Section), with chords
+// inline in the lyrics ("[C]Start me [F]up"), with lines of chords above
+// the lines of lyrics, or as plain text. Blocks reads all of them into the
+// same form, which the renderers lay out.
+package sheet
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/davidkuda/lyricsapi/chord"
+	"github.com/davidkuda/lyricsapi/models"
+)
+
+// Block is a group of lines that is played together, e.g. a section.
+// Title is empty for blocks of songs without sections.
+type Block struct {
+	Title string
+	Lines []models.Line
+}
+
+// inlineRX matches a chord in brackets, e.g. "[Am]".
+var inlineRX = regexp.MustCompile(`\[([^\]\s]+)\]`)
+
+// IsChord reports whether s is a chord symbol, see chord.Parse.
+func IsChord(s string) bool {
+	_, err := chord.Parse(s)
+	return err == nil
+}
+
+// Chords returns the distinct chords of s in the order they are first
+// played. Words in lines of chords that aren't chords are left out.
+func Chords(s *models.Song) []string {
+	var chords []string
+	seen := map[string]bool{}
+	for _, c := range PlayedChords(s) {
+		if !seen[c] {
+			seen[c] = true
+			chords = append(chords, c)
+		}
+	}
+	return chords
+}
+
+// PlayedChords returns the chords of s as they are played, with repeats.
+// Words in lines of chords that aren't chords are left out.
+func PlayedChords(s *models.Song) []string {
+	var chords []string
+	for _, b := range Blocks(s) {
+		for _, l := range b.Lines {
+			for _, c := range l.Chords {
+				if IsChord(c.Chord) {
+					chords = append(chords, c.Chord)
+				}
+			}
+		}
+	}
+	return chords
+}
+
+// Blocks returns the blocks of s in the order they are played.
+func Blocks(s *models.Song) []Block {
+	switch {
+	case len(s.Sections) > 0:
+		return sectionBlocks(s)
+	case hasInlineChords(s.Text):
+		return inlineBlocks(s.Text)
+	case s.Chords != "" && lineCount(s.Chords) == lineCount(s.Text):
+		return overlayBlocks(s.Text, s.Chords)
+	}
+
+	blocks := textBlocks(s.Text)
+	if s.Chords != "" {
+		// chords we can't place are listed after the lyrics
+		chords := Block{Title: "Chords"}
+		for _, line := range strings.Split(s.Chords, "\n") {
+			chords.Lines = append(chords.Lines, models.Line{Chords: ParseChordLine(line)})
+		}
+		blocks = append(blocks, chords)
+	}
+	return blocks
+}
+
+// sectionTitles are the names of the kinds of sections.
+var sectionTitles = map[string]string{
+	models.SectionIntro:        "Intro",
+	models.SectionVerse:        "Verse",
+	models.SectionPreChorus:    "Pre-Chorus",
+	models.SectionChorus:       "Chorus",
+	models.SectionBridge:       "Bridge",
+	models.SectionInstrumental: "Instrumental",
+	models.SectionOutro:        "Outro",
+	models.SectionTag:          "Tag",
+}
+
+// SectionKind guesses the kind of a section from its title, e.g. "chorus"
+// for "Chorus 2" or "Refrain". Titles it doesn't know are verses.
+func SectionKind(title string) string {
+	title = strings.ToLower(strings.TrimSpace(title))
+	for kind, t := range sectionTitles {
+		if strings.HasPrefix(title, strings.ToLower(t)) {
+			return kind
+		}
+	}
+	for prefix, kind := range sectionAliases {
+		if strings.HasPrefix(title, prefix) {
+			return kind
+		}
+	}
+	return models.SectionVerse
+}
+
+// sectionAliases are other titles of the kinds of sections.
+var sectionAliases = map[string]string{
+	"refrain":   models.SectionChorus,
+	"prechorus": models.SectionPreChorus,
+	"ending":    models.SectionOutro,
+	"solo":      models.SectionInstrumental,
+}
+
+// SectionTitle returns the title of sec, e.g. "Chorus", or "Verse 2" for
+// the second of several verses of s.
+func SectionTitle(s *models.Song, sec *models.Section) string {
+	title := sectionTitles[sec.Kind]
+	if title == "" {
+		title = sec.Label
+	}
+
+	n, total := 0, 0
+	for i := range s.Sections {
+		if s.Sections[i].Kind == sec.Kind {
+			total++
+			if &s.Sections[i] == sec {
+				n = total
+			}
+		}
+	}
+	if total > 1 {
+		title += " " + strconv.Itoa(n)
+	}
+	return title
+}
+
+func sectionBlocks(s *models.Song) []Block {
+	var blocks []Block
+	for _, sec := range s.Arranged() {
+		blocks = append(blocks, Block{Title: SectionTitle(s, sec), Lines: sec.Lines})
+	}
+	return blocks
+}
+
+func hasInlineChords(text string) bool {
+	for _, m := range inlineRX.FindAllStringSubmatch(text, -1) {
+		if IsChord(m[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseInline reads a line with chords in brackets, e.g. "[C]Start me
+// [F]up". Brackets that don't hold a chord are kept as lyrics.
+func ParseInline(line string) models.Line {
+	var l models.Line
+	var lyrics strings.Builder
+	pos := 0 // in runes
+
+	for {
+		loc := inlineRX.FindStringSubmatchIndex(line)
+		if loc == nil {
+			break
+		}
+		before, chord := line[:loc[0]], line[loc[2]:loc[3]]
+		lyrics.WriteString(before)
+		pos += utf8.RuneCountInString(before)
+		if IsChord(chord) {
+			l.Chords = append(l.Chords, models.ChordPosition{Chord: chord, Position: pos})
+		} else {
+			lyrics.WriteString(line[loc[0]:loc[1]])
+			pos += utf8.RuneCountInString(line[loc[0]:loc[1]])
+		}
+		line = line[loc[1]:]
+	}
+	lyrics.WriteString(line)
+
+	l.Lyrics = lyrics.String()
+	return l
+}
+
+// inlineBlocks reads lyrics with inline chords. Blank lines separate
+// blocks, a line such as "[Chorus]" or "Chorus:" is the title of a block.
+func inlineBlocks(text string) []Block {
+	var blocks []Block
+	var b Block
+	flush := func() {
+		if b.Title != "" || len(b.Lines) > 0 {
+			blocks = append(blocks, b)
+		}
+		b = Block{}
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			flush()
+			continue
+		}
+		if title, ok := blockTitle(line); ok && len(b.Lines) == 0 {
+			b.Title = title
+			continue
+		}
+		b.Lines = append(b.Lines, ParseInline(line))
+	}
+	flush()
+	return blocks
+}
+
+// blockTitle recognizes titles such as "[Chorus]" or "Verse 2:".
+func blockTitle(line string) (string, bool) {
+	if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+		inner := line[1 : len(line)-1]
+		if !strings.ContainsAny(inner, "[]") && !IsChord(inner) {
+			return inner, true
+		}
+	}
+	if strings.HasSuffix(line, ":") && len(strings.Fields(line)) <= 3 && !strings.Contains(line, "[") {
+		return strings.TrimSuffix(line, ":"), true
+	}
+	return "", false
+}
+
+// ParseChordLine reads a line of chords, e.g. "C      F", into chords at
+// the columns they start at.
+func ParseChordLine(line string) []models.ChordPosition {
+	var chords []models.ChordPosition
+	col, start := 0, -1
+	var token []rune
+	for _, r := range line + " " {
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				chords = append(chords, models.ChordPosition{Chord: string(token), Position: start})
+				start, token = -1, token[:0]
+			}
+		} else {
+			if start < 0 {
+				start = col
+			}
+			token = append(token, r)
+		}
+		col++
+	}
+	return chords
+}
+
+// overlayBlocks reads lyrics and chords with a line of chords for each line
+// of lyrics, as derived by models.Song.DeriveTextAndChords.
+func overlayBlocks(text, chords string) []Block {
+	textLines := strings.Split(text, "\n")
+	chordLines := strings.Split(chords, "\n")
+
+	var blocks []Block
+	var b Block
+	for i, line := range textLines {
+		line = strings.TrimRight(line, " \t\r")
+		chordLine := strings.TrimRight(chordLines[i], " \t\r")
+		if line == "" && chordLine == "" {
+			if len(b.Lines) > 0 {
+				blocks = append(blocks, b)
+			}
+			b = Block{}
+			continue
+		}
+		b.Lines = append(b.Lines, models.Line{Lyrics: line, Chords: ParseChordLine(chordLine)})
+	}
+	if len(b.Lines) > 0 {
+		blocks = append(blocks, b)
+	}
+	return blocks
+}
+
+// textBlocks reads lyrics without chords.
+func textBlocks(text string) []Block {
+	var blocks []Block
+	var b Block
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			if len(b.Lines) > 0 {
+				blocks = append(blocks, b)
+			}
+			b = Block{}
+			continue
+		}
+		b.Lines = append(b.Lines, models.Line{Lyrics: line})
+	}
+	if len(b.Lines) > 0 {
+		blocks = append(blocks, b)
+	}
+	return blocks
+}
+
+func lineCount(s string) int {
+	return strings.Count(s, "\n") + 1
+}
+
+// DetectKey sets the key of s to the key detected from its chords, see
+// chord.DetectKey. Songs without chords get no key.
+func DetectKey(s *models.Song) {
+	k, confidence, ok := chord.DetectKey(PlayedChords(s))
+	if !ok {
+		s.Key, s.KeyConfidence = "", nil
+		return
+	}
+	s.Key, s.KeyConfidence = k.String(), &confidence
+}
+
+// MapChords returns a copy of s with every chord replaced by f(chord): in
+// the sections, inline in the lyrics and in the lines of chords. Words in
+// lines of chords that aren't chords are kept. Chords keep their column
+// unless a longer one before them pushes them to the right.
+func MapChords(s *models.Song, f func(string) string) models.Song {
+	m := *s
+	mapLine := func(l models.Line) models.Line {
+		chords := make([]models.ChordPosition, len(l.Chords))
+		for i, c := range l.Chords {
+			chords[i] = c
+			if IsChord(c.Chord) {
+				chords[i].Chord = f(c.Chord)
+			}
+		}
+		return models.Line{Lyrics: l.Lyrics, Chords: chords}
+	}
+
+	if len(s.Sections) > 0 {
+		m.Sections = make([]models.Section, len(s.Sections))
+		for i, sec := range s.Sections {
+			m.Sections[i] = sec
+			m.Sections[i].Lines = make([]models.Line, len(sec.Lines))
+			for j, l := range sec.Lines {
+				m.Sections[i].Lines[j] = mapLine(l)
+			}
+		}
+		m.DeriveTextAndChords()
+		return m
+	}
+
+	m.Text = inlineRX.ReplaceAllStringFunc(s.Text, func(marker string) string {
+		name := marker[1 : len(marker)-1]
+		if !IsChord(name) {
+			return marker
+		}
+		return "[" + f(name) + "]"
+	})
+
+	if s.Chords != "" {
+		lines := strings.Split(s.Chords, "\n")
+		for i, line := range lines {
+			mapped := mapLine(models.Line{Chords: ParseChordLine(line)})
+			lines[i] = mapped.ChordLine()
+		}
+		m.Chords = strings.Join(lines, "\n")
+	}
+	return m
+}
+
+// Transpose returns a copy of s with its chords and key moved by
+// semitones. The chords are spelled with sharps or flats as the new key
+// is; the key of songs without one is detected first.
+func Transpose(s *models.Song, semitones int) models.Song {
+	semitones = (semitones%12 + 12) % 12
+	if semitones == 0 {
+		return *s
+	}
+
+	k, err := chord.ParseKey(s.Key)
+	if err != nil {
+		var ok bool
+		if k, _, ok = chord.DetectKey(PlayedChords(s)); !ok {
+			return *s
+		}
+	}
+	k = k.Transpose(semitones)
+	flats := k.Flats()
+
+	t := MapChords(s, func(symbol string) string {
+		c, err := chord.Parse(symbol)
+		if err != nil {
+			return symbol
+		}
+		return c.Transpose(semitones, flats).String()
+	})
+	if s.Key != "" {
+		t.Key = k.String()
+	}
+	return t
+}
@@ -0,0 +1,177 @@
+package sheet
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/davidkuda/lyricsapi/models"
+)
+
+func TestIsChord(t *testing.T) {
+	for _, s := range []string{"C", "Am", "F#m7", "Bbmaj7/D", "Dsus4", "Cadd9", "Em7b5", "G/B", "C°"} {
+		if !IsChord(s) {
+			t.Errorf("IsChord(%q) = false, want true", s)
+		}
+	}
+	for _, s := range []string{"", "Chorus", "Bridge", "Intro", "H", "am", "x"} {
+		if IsChord(s) {
+			t.Errorf("IsChord(%q) = true, want false", s)
+		}
+	}
+}
+
+func TestParseInline(t *testing.T) {
+	got := ParseInline("[C]If you start me [F]up [sic]")
+	want := models.Line{
+		Lyrics: "If you start me up [sic]",
+		Chords: []models.ChordPosition{{Chord: "C", Position: 0}, {Chord: "F", Position: 16}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseInline() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseChordLine(t *testing.T) {
+	got := ParseChordLine("C      F   G7")
+	want := []models.ChordPosition{{Chord: "C", Position: 0}, {Chord: "F", Position: 7}, {Chord: "G7", Position: 11}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseChordLine() = %+v, want %+v", got, want)
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	tests := []struct {
+		name string
+		song models.Song
+		want []Block
+	}{
+		{
+			name: "inline chords",
+			song: models.Song{Text: "[Chorus]\n[C]Start me [F]up\n\nI'll never stop"},
+			want: []Block{
+				{Title: "Chorus", Lines: []models.Line{{Lyrics: "Start me up", Chords: []models.ChordPosition{{Chord: "C", Position: 0}, {Chord: "F", Position: 9}}}}},
+				{Lines: []models.Line{{Lyrics: "I'll never stop"}}},
+			},
+		},
+		{
+			name: "lines of chords",
+			song: models.Song{Text: "Start me up\n\nI'll never stop", Chords: "Bb    F\n\n"},
+			want: []Block{
+				{Lines: []models.Line{{Lyrics: "Start me up", Chords: []models.ChordPosition{{Chord: "Bb", Position: 0}, {Chord: "F", Position: 6}}}}},
+				{Lines: []models.Line{{Lyrics: "I'll never stop"}}},
+			},
+		},
+		{
+			name: "chords that can't be placed",
+			song: models.Song{Text: "Start me up", Chords: "C F\nG"},
+			want: []Block{
+				{Lines: []models.Line{{Lyrics: "Start me up"}}},
+				{Title: "Chords", Lines: []models.Line{
+					{Chords: []models.ChordPosition{{Chord: "C", Position: 0}, {Chord: "F", Position: 2}}},
+					{Chords: []models.ChordPosition{{Chord: "G", Position: 0}}},
+				}},
+			},
+		},
+		{
+			name: "sections",
+			song: models.Song{
+				Sections: []models.Section{
+					{Label: "V1", Kind: models.SectionVerse, Lines: []models.Line{{Lyrics: "one"}}},
+					{Label: "V2", Kind: models.SectionVerse, Lines: []models.Line{{Lyrics: "two"}}},
+					{Label: "C", Kind: models.SectionChorus, Lines: []models.Line{{Lyrics: "chorus"}}},
+				},
+				Arrangement: "V1 C V2 C",
+			},
+			want: []Block{
+				{Title: "Verse 1", Lines: []models.Line{{Lyrics: "one"}}},
+				{Title: "Chorus", Lines: []models.Line{{Lyrics: "chorus"}}},
+				{Title: "Verse 2", Lines: []models.Line{{Lyrics: "two"}}},
+				{Title: "Chorus", Lines: []models.Line{{Lyrics: "chorus"}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Blocks(&tt.song); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Blocks() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChords(t *testing.T) {
+	s := models.Song{Text: "[G]Start me [D/F#]up\n[Em]If you [G]start me up\n[x2]"}
+	want := []string{"G", "D/F#", "Em"}
+	if got := Chords(&s); !reflect.DeepEqual(got, want) {
+		t.Errorf("Chords() = %v, want %v", got, want)
+	}
+}
+
+func TestMapChords(t *testing.T) {
+	lower := strings.ToLower
+
+	s := models.Song{Text: "[C]Start me [F]up [sic]", Chords: "Am    G\nx2 Bbmaj7 C"}
+	got := MapChords(&s, lower)
+	if want := "[c]Start me [f]up [sic]"; got.Text != want {
+		t.Errorf("Text = %q, want %q", got.Text, want)
+	}
+	if want := "am    g\nx2 bbmaj7 c"; got.Chords != want {
+		t.Errorf("Chords = %q, want %q", got.Chords, want)
+	}
+
+	// chords that grow push the next ones to the right
+	s = models.Song{Chords: "C D"}
+	got = MapChords(&s, func(c string) string { return c + "maj7" })
+	if want := "Cmaj7 Dmaj7"; got.Chords != want {
+		t.Errorf("Chords = %q, want %q", got.Chords, want)
+	}
+
+	s = models.Song{Sections: []models.Section{
+		{Label: "V", Kind: models.SectionVerse, Lines: []models.Line{
+			{Lyrics: "Start me up", Chords: []models.ChordPosition{{Chord: "C", Position: 0}}},
+		}},
+	}}
+	got = MapChords(&s, lower)
+	if got.Sections[0].Lines[0].Chords[0].Chord != "c" || got.Chords != "c" {
+		t.Errorf("sections not mapped: %+v, chords %q", got.Sections, got.Chords)
+	}
+	if s.Sections[0].Lines[0].Chords[0].Chord != "C" {
+		t.Errorf("MapChords changed the song it was given")
+	}
+}
+
+func TestTranspose(t *testing.T) {
+	s := models.Song{Key: "G", Chords: "G     D/F#  Em7\nx2 C"}
+	got := Transpose(&s, 3)
+	if want := "Bb    F/A   Gm7\nx2 Eb"; got.Chords != want || got.Key != "Bb" {
+		t.Errorf("Transpose() = %q in %s, want %q in Bb", got.Chords, got.Key, want)
+	}
+
+	// without a key, the spelling follows the detected one
+	s = models.Song{Chords: "C F G C"}
+	if got := Transpose(&s, -3); got.Chords != "A D E A" || got.Key != "" {
+		t.Errorf("Transpose() = %q in %q, want \"A D E A\" without a key", got.Chords, got.Key)
+	}
+	if got := Transpose(&s, 12); got.Chords != s.Chords {
+		t.Errorf("Transpose() by an octave = %q", got.Chords)
+	}
+}
+
+func TestSectionKind(t *testing.T) {
+	tests := map[string]string{
+		"Chorus 2":   models.SectionChorus,
+		"Pre-Chorus": models.SectionPreChorus,
+		"refrain":    models.SectionChorus,
+		"Intro":      models.SectionIntro,
+		"Solo":       models.SectionInstrumental,
+		"Verse 3":    models.SectionVerse,
+		"Coda":       models.SectionVerse,
+	}
+	for title, want := range tests {
+		if got := SectionKind(title); got != want {
+			t.Errorf("SectionKind(%q) = %q, want %q", title, got, want)
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package sheet
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/davidkuda/lyricsapi/models"
+)
+
+// DefaultWidth is the width of plain text sheets if none is given.
+const DefaultWidth = 80
+
+// Text renders s as a plain text chord sheet for a monospace font: the
+// name and artist, then each block with its chords above the syllables
+// they are played on. Lines longer than width are wrapped between words,
+// a chord always stays above its syllable.
+func Text(s *models.Song, width int) string {
+	if width <= 0 {
+		width = DefaultWidth
+	}
+
+	var b strings.Builder
+	b.WriteString(s.Name + "\n")
+	if s.Artist != "" {
+		b.WriteString(s.Artist + "\n")
+	}
+
+	for _, block := range Blocks(s) {
+		b.WriteString("\n")
+		if block.Title != "" {
+			b.WriteString("[" + block.Title + "]\n")
+		}
+		for _, line := range block.Lines {
+			for _, row := range Layout(line, width) {
+				b.WriteString(row + "\n")
+			}
+		}
+	}
+
+	if s.Copyright != "" {
+		b.WriteString("\n© " + strings.TrimPrefix(s.Copyright, "© ") + "\n")
+	}
+	return b.String()
+}
+
+// Layout lays out a line of lyrics with its chords as rows of text no
+// wider than width: a row of chords above each row of lyrics, unless the
+// line has no chords. Where chords would run into each other, the lyrics
+// are stretched, with hyphens inside words and spaces between them.
+func Layout(l models.Line, width int) []string {
+	rows := Rows(l, width)
+	lines := make([]string, len(rows))
+	for i, row := range rows {
+		lines[i] = row.Text
+	}
+	return lines
+}
+
+// Row is a row of a laid out line, either chords or lyrics.
+type Row struct {
+	Text   string
+	Chords bool
+}
+
+// Rows is Layout, but tells the rows of chords from those of lyrics.
+func Rows(l models.Line, width int) []Row {
+	lyrics, cols := spread(l)
+
+	var rows []Row
+	for _, seg := range wrap(lyrics, l.Chords, cols, width) {
+		if len(l.Chords) > 0 && seg.last > seg.first {
+			chords := make([]rune, 0, width)
+			for i := seg.first; i < seg.last; i++ {
+				for len(chords) < cols[i]-seg.start {
+					chords = append(chords, ' ')
+				}
+				chords = append(chords, []rune(l.Chords[i].Chord)...)
+			}
+			rows = append(rows, Row{Text: string(chords), Chords: true})
+		}
+
+		end := seg.end
+		if end > len(lyrics) {
+			end = len(lyrics)
+		}
+		if seg.start < end {
+			if text := strings.TrimRight(string(lyrics[seg.start:end]), " "); text != "" {
+				rows = append(rows, Row{Text: text})
+			}
+		}
+	}
+	if len(rows) == 0 {
+		rows = append(rows, Row{})
+	}
+	return rows
+}
+
+// spread returns the lyrics of l, stretched so that no chord runs into the
+// next, and the column of each chord in them.
+func spread(l models.Line) ([]rune, []int) {
+	src := []rune(l.Lyrics)
+	out := make([]rune, 0, len(src))
+	cols := make([]int, 0, len(l.Chords))
+
+	next, minCol := 0, 0
+	for _, c := range l.Chords {
+		for next < c.Position && next < len(src) {
+			out = append(out, src[next])
+			next++
+		}
+		// chords after the end of the lyrics are padded to their position
+		for i := next; i < c.Position; i++ {
+			out = append(out, ' ')
+		}
+		next = max(next, c.Position)
+
+		if col := len(out); col < minCol {
+			fill := ' '
+			if next > 0 && next < len(src) && isLetter(src[next-1]) && isLetter(src[next]) {
+				fill = '-'
+			}
+			for ; col < minCol; col++ {
+				out = append(out, fill)
+			}
+		}
+		cols = append(cols, len(out))
+		minCol = len(out) + utf8.RuneCountInString(c.Chord) + 1
+	}
+	if next < len(src) {
+		out = append(out, src[next:]...)
+	}
+	return out, cols
+}
+
+// segment is a row of a wrapped line: the columns [start, end) of the
+// lyrics and the chords [first, last).
+type segment struct {
+	start, end  int
+	first, last int
+}
+
+// wrap splits a laid out line into segments no wider than width. It
+// breaks before a word where it can, and mid-word only if a word is wider
+// than a row. A chord goes into the segment of the column it starts at.
+func wrap(lyrics []rune, chords []models.ChordPosition, cols []int, width int) []segment {
+	total := len(lyrics)
+	for i, c := range chords {
+		total = max(total, cols[i]+utf8.RuneCountInString(c.Chord))
+	}
+
+	// fits reports whether the chords starting before b end within the row
+	fits := func(start, first, b int) bool {
+		for i := first; i < len(chords) && cols[i] < b; i++ {
+			if cols[i]+utf8.RuneCountInString(chords[i].Chord) > start+width {
+				return false
+			}
+		}
+		return true
+	}
+	wordStart := func(b int) bool {
+		if b >= len(lyrics) {
+			return true
+		}
+		return lyrics[b-1] == ' ' && lyrics[b] != ' '
+	}
+
+	var segs []segment
+	start, first := 0, 0
+	for {
+		b := -1
+		if total-start <= width && fits(start, first, total) {
+			b = total
+		}
+		for try := start + width; b < 0 && try > start; try-- {
+			if try < total && wordStart(try) && fits(start, first, try) {
+				b = try
+			}
+		}
+		for try := start + width; b < 0 && try > start; try-- {
+			if fits(start, first, try) {
+				b = try
+			}
+		}
+		if b < 0 {
+			// a chord wider than a row
+			b = start + 1
+		}
+		b = min(b, total)
+
+		last := first
+		for last < len(chords) && cols[last] < b {
+			last++
+		}
+		segs = append(segs, segment{start: start, end: b, first: first, last: last})
+		if b >= total {
+			return segs
+		}
+
+		// rows don't start with the spaces between words
+		start, first = b, last
+		for start < len(lyrics) && lyrics[start] == ' ' && (first == len(chords) || cols[first] > start) {
+			start++
+		}
+	}
+}
+
+func isLetter(r rune) bool {
+	return unicode.IsLetter(r) || r == '\''
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
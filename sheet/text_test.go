@@ -0,0 +1,71 @@
+package sheet
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/davidkuda/lyricsapi/models"
+)
+
+func TestLayout(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		width int
+		want  []string
+	}{
+		{"aligned", "[C]If you start me [F]up", 80, []string{"C               F", "If you start me up"}},
+		{"no chords", "I'll never stop", 80, []string{"I'll never stop"}},
+		{"chords only", "[Am]   [G]", 80, []string{"Am G"}},
+		{"crowded in a word", "[Am]N[C#m7b5]ever [D]stop", 80, []string{"Am C#m7b5 D", "N--ever   stop"}},
+		{"crowded between words", "[Cmaj7]a [G]b", 80, []string{"Cmaj7 G", "a     b"}},
+		{
+			"wrapped between words", "[C]If you start me [F]up, if you [C]start me up", 20,
+			[]string{"C               F", "If you start me up,", "       C", "if you start me up"},
+		},
+		{
+			// "[G]start" would fit the first row, but its chord wouldn't
+			"chord kept with its syllable", "I'll never [Gsus4add9]stop", 15,
+			[]string{"I'll never", "Gsus4add9", "stop"},
+		},
+		{"long word", "[A]Supercalifragilistic", 10, []string{"A", "Supercalif", "ragilistic"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Layout(ParseInline(tt.line), tt.width)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Layout() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+			for _, row := range got {
+				if n := len([]rune(row)); n > tt.width {
+					t.Errorf("row %q is %d wide, want at most %d", row, n, tt.width)
+				}
+			}
+		})
+	}
+}
+
+func TestText(t *testing.T) {
+	s := models.Song{
+		Name:      "Start Me Up",
+		Artist:    "The Rolling Stones",
+		Text:      "[Chorus]\n[Bb]Start me [F]up\nI'll never stop",
+		Copyright: "1981 Promopub B.V.",
+	}
+
+	want := `Start Me Up
+The Rolling Stones
+
+[Chorus]
+Bb       F
+Start me up
+I'll never stop
+
+© 1981 Promopub B.V.
+`
+	if got := Text(&s, 80); got != want {
+		t.Errorf("Text() =\n%s\nwant\n%s", got, want)
+	}
+}
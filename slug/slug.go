@@ -0,0 +1,107 @@
+// Package slug turns titles into URL friendly identifiers, e.g.
+// "Für Elise" becomes "fur-elise".
+package slug
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Letters that do not decompose into a base letter and a combining mark.
+var transliterations = map[rune]string{
+	'ß': "ss",
+	'æ': "ae",
+	'Æ': "ae",
+	'œ': "oe",
+	'Œ': "oe",
+	'ø': "o",
+	'Ø': "o",
+	'ł': "l",
+	'Ł': "l",
+	'đ': "d",
+	'Đ': "d",
+	'ð': "d",
+	'Ð': "d",
+	'þ': "th",
+	'Þ': "th",
+	'ı': "i",
+	'&': " and ",
+}
+
+// Make returns the slug of s: lower case ASCII letters and digits, words
+// joined by single hyphens. Accents are stripped and some letters are
+// transliterated. Make returns "" if s has nothing to build a slug from.
+func Make(s string) string {
+	var b strings.Builder
+	hyphen := false
+
+	write := func(r rune) {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			hyphen = false
+			b.WriteRune(unicode.ToLower(r))
+		case r == '\'' || r == '’':
+			// "Don't" becomes "dont", not "don-t"
+		default:
+			hyphen = true
+		}
+	}
+
+	// NFKD splits "ü" into "u" and a combining diaeresis, which is dropped
+	for _, r := range norm.NFKD.String(s) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		if t, ok := transliterations[r]; ok {
+			for _, tr := range t {
+				write(tr)
+			}
+			continue
+		}
+		write(r)
+	}
+
+	return b.String()
+}
+
+// Candidates returns the slugs to try, in order, for a song called name by
+// artist: the name alone, then name and artist, then numbered variants.
+// The same input always yields the same sequence, so collisions resolve
+// deterministically. Names without Latin letters or digits, e.g. in
+// Cyrillic or Chinese, get a short hash of the name and the artist
+// instead, e.g. "song-1a2b3c4d", even if the artist has a Latin name. next
+// is called until it returns true or n candidates have been tried.
+func Candidates(name, artist string, n int, next func(candidate string) bool) {
+	base := Make(name)
+	withArtist := Make(name + " " + artist)
+	if base == "" {
+		sum := sha256.Sum256([]byte(artist + "\n" + name))
+		withArtist = "song-" + hex.EncodeToString(sum[:4])
+	}
+
+	seen := map[string]bool{}
+	try := func(c string) bool {
+		if c == "" || seen[c] {
+			return false
+		}
+		seen[c] = true
+		return next(c)
+	}
+
+	if try(base) || try(withArtist) {
+		return
+	}
+	for i := 2; i < n; i++ {
+		if try(withArtist + "-" + strconv.Itoa(i)) {
+			return
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package slug
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMake(t *testing.T) {
+	tests := map[string]string{
+		"Wish You Were Here":     "wish-you-were-here",
+		"Für Elise":              "fur-elise",
+		"Straße nach Süden":      "strasse-nach-suden",
+		"Don't Stop Me Now":      "dont-stop-me-now",
+		"  Hold On!! ":           "hold-on",
+		"Simon & Garfunkel":      "simon-and-garfunkel",
+		"Sigur Rós – Hoppípolla": "sigur-ros-hoppipolla",
+		"Smørrebrød":             "smorrebrod",
+		"99 Luftballons":         "99-luftballons",
+		"Ｆｕｌｌｗｉｄｔｈ":              "fullwidth",
+		"日本語":                    "",
+	}
+	for in, want := range tests {
+		if got := Make(in); got != want {
+			t.Errorf("Make(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestCandidates(t *testing.T) {
+	taken := map[string]bool{
+		"hallelujah":                 true,
+		"hallelujah-leonard-cohen":   true,
+		"hallelujah-leonard-cohen-2": true,
+	}
+
+	var tried []string
+	var got string
+	Candidates("Hallelujah", "Leonard Cohen", 10, func(c string) bool {
+		tried = append(tried, c)
+		if taken[c] {
+			return false
+		}
+		got = c
+		return true
+	})
+
+	if got != "hallelujah-leonard-cohen-3" {
+		t.Errorf("got %q, want hallelujah-leonard-cohen-3 (tried %v)", got, tried)
+	}
+	if len(tried) != 4 {
+		t.Errorf("tried %v, want 4 candidates", tried)
+	}
+}
+
+func TestCandidatesWithoutLatinLetters(t *testing.T) {
+	var tried []string
+	Candidates("Катюша", "Лидия Русланова", 3, func(c string) bool {
+		tried = append(tried, c)
+		return false
+	})
+
+	if len(tried) != 2 || !strings.HasPrefix(tried[0], "song-") || len(tried[0]) != len("song-")+8 || tried[1] != tried[0]+"-2" {
+		t.Fatalf("tried %v, want a hash and its numbered variant", tried)
+	}
+	Candidates("Катюша", "Лидия Русланова", 1, func(c string) bool {
+		if c != tried[0] {
+			t.Errorf("got %q, then %q for the same song", tried[0], c)
+		}
+		return true
+	})
+}
+
+func TestCandidatesWithLatinArtist(t *testing.T) {
+	var tried []string
+	Candidates("Катюша", "Queen", 2, func(c string) bool {
+		tried = append(tried, c)
+		return false
+	})
+
+	if len(tried) != 1 || !strings.HasPrefix(tried[0], "song-") {
+		t.Errorf("tried %v, want a hash rather than the slug of the artist", tried)
+	}
+}
@@ -0,0 +1,355 @@
+package songxml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/davidkuda/lyricsapi/chord"
+	"github.com/davidkuda/lyricsapi/models"
+)
+
+// MarshalOpenLyrics writes s as an OpenLyrics 0.9 song. Chords are written
+// inline with their names, which all programs that read OpenLyrics
+// understand.
+func MarshalOpenLyrics(s *models.Song) []byte {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	fmt.Fprintf(&b, `<song xmlns="%s" version="0.9" createdIn="lyricsapi" modifiedIn="lyricsapi"`, openLyricsNamespace)
+	if !s.UpdatedAt.IsZero() {
+		fmt.Fprintf(&b, ` modifiedDate="%s"`, s.UpdatedAt.UTC().Format(time.RFC3339))
+	}
+	b.WriteString(">\n")
+
+	ps, order := parts(s)
+
+	b.WriteString("  <properties>\n")
+	if s.Language != "" {
+		fmt.Fprintf(&b, "    <titles><title lang=\"%s\">%s</title></titles>\n", escape(s.Language), escape(s.Name))
+	} else {
+		fmt.Fprintf(&b, "    <titles><title>%s</title></titles>\n", escape(s.Name))
+	}
+	if s.Artist != "" {
+		fmt.Fprintf(&b, "    <authors><author>%s</author></authors>\n", escape(s.Artist))
+	}
+	if s.Copyright != "" {
+		fmt.Fprintf(&b, "    <copyright>%s</copyright>\n", escape(s.Copyright))
+	}
+	if s.CCLI != "" {
+		fmt.Fprintf(&b, "    <ccliNo>%s</ccliNo>\n", escape(s.CCLI))
+	}
+	if s.Key != "" {
+		fmt.Fprintf(&b, "    <key>%s</key>\n", escape(s.Key))
+	}
+	if len(order) > 0 {
+		fmt.Fprintf(&b, "    <verseOrder>%s</verseOrder>\n", strings.Join(order, " "))
+	}
+	if len(s.Tags) > 0 {
+		b.WriteString("    <themes>\n")
+		for _, tag := range s.Tags {
+			fmt.Fprintf(&b, "      <theme>%s</theme>\n", escape(tag))
+		}
+		b.WriteString("    </themes>\n")
+	}
+	b.WriteString("  </properties>\n")
+
+	b.WriteString("  <lyrics>\n")
+	for _, p := range ps {
+		fmt.Fprintf(&b, "    <verse name=\"%s\">\n      <lines>", p.name)
+		for i, line := range p.section.Lines {
+			if i > 0 {
+				b.WriteString("<br/>\n        ")
+			}
+			b.WriteString(openLyricsLine(line))
+		}
+		b.WriteString("</lines>\n    </verse>\n")
+	}
+	b.WriteString("  </lyrics>\n")
+	b.WriteString("</song>\n")
+	return []byte(b.String())
+}
+
+// openLyricsLine returns the lyrics of l with its chords in front of the
+// characters they are played on. Chords past the end of the lyrics follow
+// them.
+func openLyricsLine(l models.Line) string {
+	var b strings.Builder
+	chords := l.Chords
+	writeChord := func() {
+		fmt.Fprintf(&b, `<chord name="%s"/>`, escape(chords[0].Chord))
+		chords = chords[1:]
+	}
+	for i, r := range []rune(l.Lyrics) {
+		for len(chords) > 0 && chords[0].Position <= i {
+			writeChord()
+		}
+		b.WriteString(escape(string(r)))
+	}
+	for len(chords) > 0 {
+		writeChord()
+	}
+	return b.String()
+}
+
+type openLyricsSong struct {
+	Properties struct {
+		Titles     []openLyricsText `xml:"titles>title"`
+		Authors    []openLyricsText `xml:"authors>author"`
+		Copyright  string           `xml:"copyright"`
+		CCLINo     string           `xml:"ccliNo"`
+		Key        string           `xml:"key"`
+		VerseOrder string           `xml:"verseOrder"`
+		Themes     []openLyricsText `xml:"themes>theme"`
+	} `xml:"properties"`
+	Verses []struct {
+		Name  string `xml:"name,attr"`
+		Lang  string `xml:"lang,attr"`
+		Lines []struct {
+			Inner []byte `xml:",innerxml"`
+		} `xml:"lines"`
+	} `xml:"lyrics>verse"`
+}
+
+// openLyricsText is a title, author or theme.
+type openLyricsText struct {
+	Lang     string `xml:"lang,attr"`
+	Original bool   `xml:"original,attr"`
+	Type     string `xml:"type,attr"` // of authors, e.g. "words" or "translation"
+	Text     string `xml:",chardata"`
+}
+
+// UnmarshalOpenLyrics reads an OpenLyrics song, of version 0.8 or 0.9.
+// Songs with translations are read in the language of their first verse.
+func UnmarshalOpenLyrics(data []byte) (models.Song, error) {
+	var ol openLyricsSong
+	if err := xml.Unmarshal(data, &ol); err != nil {
+		return models.Song{}, err
+	}
+	p := &ol.Properties
+
+	var lang string
+	if len(ol.Verses) > 0 {
+		lang = ol.Verses[0].Lang
+	}
+	if lang == "" && len(p.Titles) > 0 {
+		lang = p.Titles[0].Lang
+	}
+
+	s := models.Song{
+		Name:      openLyricsTitle(p.Titles, lang),
+		Copyright: strings.TrimSpace(p.Copyright),
+		CCLI:      strings.TrimSpace(p.CCLINo),
+		Key:       readKey(p.Key),
+		Language:  readLanguage(lang),
+	}
+
+	var authors []string
+	seen := map[string]bool{}
+	for _, a := range p.Authors {
+		name := strings.TrimSpace(a.Text)
+		if a.Type == "translation" || name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		authors = append(authors, name)
+	}
+	s.Artist = strings.Join(authors, ", ")
+
+	var themes []string
+	for _, t := range p.Themes {
+		if t.Lang == "" || lang == "" || t.Lang == lang {
+			themes = append(themes, t.Text)
+		}
+	}
+	s.Tags = tagsOf(themes)
+
+	sb := newSectionBuilder()
+	for _, v := range ol.Verses {
+		if v.Lang != "" && lang != "" && v.Lang != lang {
+			continue
+		}
+		sb.start(v.Name)
+		for _, lines := range v.Lines {
+			if err := readOpenLyricsLines(lines.Inner, sb); err != nil {
+				return models.Song{}, fmt.Errorf("verse %q: %w", v.Name, err)
+			}
+		}
+	}
+	s.Sections, s.Arrangement = sb.finish(strings.Fields(p.VerseOrder))
+	return s, nil
+}
+
+// openLyricsTitle returns the original title, or else the first in lang.
+func openLyricsTitle(titles []openLyricsText, lang string) string {
+	for _, t := range titles {
+		if t.Original {
+			return strings.TrimSpace(t.Text)
+		}
+	}
+	for _, t := range titles {
+		if t.Lang == "" || lang == "" || t.Lang == lang {
+			return strings.TrimSpace(t.Text)
+		}
+	}
+	return ""
+}
+
+// readOpenLyricsLines reads the content of a lines element: text with
+// chords and line breaks. Whitespace is collapsed as in HTML; comments and
+// the formatting of tags are dropped.
+func readOpenLyricsLines(inner []byte, sb *sectionBuilder) error {
+	d := xml.NewDecoder(bytes.NewReader(inner))
+	var lb lineBuilder
+	skip := 0 // depth inside comments
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch {
+			case skip > 0 || t.Name.Local == "comment":
+				skip++
+			case t.Name.Local == "chord":
+				if c := openLyricsChord(t.Attr); c != "" {
+					lb.chord(c)
+				}
+			}
+		case xml.EndElement:
+			switch {
+			case skip > 0:
+				skip--
+			case t.Name.Local == "br" || t.Name.Local == "line":
+				// lines of version 0.8 are elements
+				sb.add(lb.line())
+			}
+		case xml.CharData:
+			if skip == 0 {
+				lb.text(string(t))
+			}
+		}
+	}
+	if !lb.empty() {
+		sb.add(lb.line())
+	}
+	return nil
+}
+
+// The structures of chords of OpenLyrics 0.9 in the notation of chords.
+var openLyricsStructures = map[string]string{
+	"":     "",
+	"maj":  "",
+	"min":  "m",
+	"dom7": "7",
+	"maj7": "maj7",
+	"min7": "m7",
+	"sus2": "sus2",
+	"sus4": "sus4",
+	"dim":  "dim",
+	"aug":  "aug",
+	"6":    "6",
+	"9":    "9",
+	"add9": "add9",
+}
+
+// openLyricsChord returns the chord of a chord element: its name, or in
+// version 0.9 its root, structure and bass.
+func openLyricsChord(attrs []xml.Attr) string {
+	var name, root, structure, bass string
+	for _, a := range attrs {
+		switch a.Name.Local {
+		case "name":
+			name = a.Value
+		case "root":
+			root = a.Value
+		case "structure":
+			structure = a.Value
+		case "bass":
+			bass = a.Value
+		}
+	}
+	if name != "" || root == "" {
+		return strings.Join(strings.Fields(name), "")
+	}
+	suffix, ok := openLyricsStructures[structure]
+	if !ok {
+		suffix = structure
+	}
+	c := root + suffix
+	if bass != "" {
+		c += "/" + bass
+	}
+	return c
+}
+
+// lineBuilder collects the text and chords of a line.
+type lineBuilder struct {
+	lyrics []rune
+	chords []models.ChordPosition
+	space  bool // whitespace was read after the last character
+}
+
+func (b *lineBuilder) text(s string) {
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			b.space = true
+			continue
+		}
+		if b.space && len(b.lyrics) > 0 {
+			b.lyrics = append(b.lyrics, ' ')
+		}
+		b.space = false
+		b.lyrics = append(b.lyrics, r)
+	}
+}
+
+// chord adds c on the next character. Chords without text between them
+// are moved apart.
+func (b *lineBuilder) chord(c string) {
+	pos := len(b.lyrics)
+	if b.space && pos > 0 {
+		pos++
+	}
+	if n := len(b.chords); n > 0 && pos <= b.chords[n-1].Position {
+		pos = b.chords[n-1].Position + 1
+	}
+	b.chords = append(b.chords, models.ChordPosition{Chord: c, Position: pos})
+}
+
+func (b *lineBuilder) empty() bool {
+	return len(b.lyrics) == 0 && len(b.chords) == 0
+}
+
+// line returns the line and starts the next one.
+func (b *lineBuilder) line() models.Line {
+	l := models.Line{Lyrics: string(b.lyrics), Chords: b.chords}
+	*b = lineBuilder{}
+	return l
+}
+
+// readKey returns s if it is a key, see chord.ParseKey.
+func readKey(s string) string {
+	s = strings.TrimSpace(s)
+	if _, err := chord.ParseKey(s); err != nil {
+		return ""
+	}
+	return s
+}
+
+// readLanguage returns the ISO 639-1 code of a language tag such as
+// "en-US", or "" if it is unknown.
+func readLanguage(tag string) string {
+	code := strings.ToLower(strings.SplitN(strings.Replace(tag, "_", "-", 1), "-", 2)[0])
+	if models.Languages[code] == "" {
+		return ""
+	}
+	return code
+}
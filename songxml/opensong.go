@@ -0,0 +1,144 @@
+package songxml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/davidkuda/lyricsapi/models"
+	"github.com/davidkuda/lyricsapi/sheet"
+)
+
+// MarshalOpenSong writes s as an OpenSong song. The lyrics are in the
+// format of OpenSong: "[V1]" starts a section, lines of chords start with
+// "." and lines of lyrics with a space, so that both line up.
+func MarshalOpenSong(s *models.Song) []byte {
+	ps, order := parts(s)
+
+	var lyrics strings.Builder
+	for i, p := range ps {
+		if i > 0 {
+			lyrics.WriteString("\n")
+		}
+		fmt.Fprintf(&lyrics, "[%s]\n", strings.ToUpper(p.name))
+		for _, line := range p.section.Lines {
+			if len(line.Chords) > 0 {
+				lyrics.WriteString("." + line.ChordLine() + "\n")
+			}
+			if line.Lyrics != "" || len(line.Chords) == 0 {
+				lyrics.WriteString(" " + line.Lyrics + "\n")
+			}
+		}
+	}
+	for i := range order {
+		order[i] = strings.ToUpper(order[i])
+	}
+
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString("<song>\n")
+	fmt.Fprintf(&b, "  <title>%s</title>\n", escape(s.Name))
+	fmt.Fprintf(&b, "  <author>%s</author>\n", escape(s.Artist))
+	fmt.Fprintf(&b, "  <copyright>%s</copyright>\n", escape(s.Copyright))
+	fmt.Fprintf(&b, "  <ccli>%s</ccli>\n", escape(s.CCLI))
+	fmt.Fprintf(&b, "  <key>%s</key>\n", escape(s.Key))
+	fmt.Fprintf(&b, "  <presentation>%s</presentation>\n", strings.Join(order, " "))
+	fmt.Fprintf(&b, "  <theme>%s</theme>\n", escape(strings.Join(s.Tags, "; ")))
+	fmt.Fprintf(&b, "  <lyrics>%s</lyrics>\n", escape(lyrics.String()))
+	b.WriteString("</song>\n")
+	return []byte(b.String())
+}
+
+type openSongSong struct {
+	Title        string `xml:"title"`
+	Author       string `xml:"author"`
+	Copyright    string `xml:"copyright"`
+	CCLI         string `xml:"ccli"`
+	Key          string `xml:"key"`
+	Presentation string `xml:"presentation"`
+	Theme        string `xml:"theme"`
+	AltTheme     string `xml:"alttheme"`
+	Lyrics       string `xml:"lyrics"`
+}
+
+// UnmarshalOpenSong reads an OpenSong song. Verses that share their chords
+// are numbered lines of one section, e.g. "1" and "2" under "[V]"; they
+// are read as the sections V1 and V2.
+func UnmarshalOpenSong(data []byte) (models.Song, error) {
+	var song openSongSong
+	if err := xml.Unmarshal(data, &song); err != nil {
+		return models.Song{}, err
+	}
+
+	s := models.Song{
+		Name:      strings.TrimSpace(song.Title),
+		Artist:    strings.TrimSpace(song.Author),
+		Copyright: strings.TrimSpace(song.Copyright),
+		CCLI:      strings.TrimSpace(song.CCLI),
+		Key:       readKey(song.Key),
+		Tags:      tagsOf(strings.Split(song.Theme+";"+song.AltTheme, ";")),
+	}
+
+	sb := newSectionBuilder()
+	header := "V"
+	sb.start(header)
+	var chords []models.ChordPosition
+	chordsUsed := true
+	for _, line := range strings.Split(strings.ReplaceAll(song.Lyrics, "\r\n", "\n"), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || line == "||" || line == "---" {
+			continue
+		}
+		switch line[0] {
+		case '[':
+			if !chordsUsed {
+				sb.add(models.Line{Chords: chords})
+			}
+			header = strings.Trim(line, "[]")
+			sb.start(header)
+			chords, chordsUsed = nil, true
+		case ';':
+			// a comment
+		case '.':
+			if !chordsUsed {
+				sb.add(models.Line{Chords: chords})
+			}
+			chords, chordsUsed = sheet.ParseChordLine(line[1:]), false
+		case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			// the chords above apply to each numbered verse
+			sb.start(header + line[:1])
+			sb.add(openSongLine(line[1:], chords))
+			chordsUsed = true
+		default:
+			if line[0] == ' ' {
+				line = line[1:]
+			}
+			sb.add(openSongLine(line, chords))
+			chords, chordsUsed = nil, true
+		}
+	}
+	if !chordsUsed {
+		sb.add(models.Line{Chords: chords})
+	}
+	s.Sections, s.Arrangement = sb.finish(strings.Fields(song.Presentation))
+	return s, nil
+}
+
+// openSongLine returns a line of lyrics with the chords of the line above.
+// Lyrics that are indented move the chords with them.
+func openSongLine(lyrics string, chords []models.ChordPosition) models.Line {
+	trimmed := strings.TrimLeft(lyrics, " ")
+	indent := len(lyrics) - len(trimmed)
+	l := models.Line{Lyrics: trimmed}
+	for _, c := range chords {
+		pos := c.Position - indent
+		if pos < 0 {
+			pos = 0
+		}
+		if n := len(l.Chords); n > 0 && pos <= l.Chords[n-1].Position {
+			pos = l.Chords[n-1].Position + 1
+		}
+		l.Chords = append(l.Chords, models.ChordPosition{Chord: c.Chord, Position: pos})
+	}
+	return l
+}
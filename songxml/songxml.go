@@ -0,0 +1,278 @@
+// Package songxml reads and writes songs in the XML formats of worship
+// presentation software: OpenLyrics 0.9 (see https://docs.openlyrics.org)
+// and OpenSong. Imported songs come with sections and an arrangement;
+// their lyrics and chords are derived from those as usual.
+package songxml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/davidkuda/lyricsapi/models"
+	"github.com/davidkuda/lyricsapi/sheet"
+	"github.com/davidkuda/lyricsapi/slug"
+)
+
+// Format is a file format for songs.
+type Format string
+
+const (
+	OpenLyrics Format = "openlyrics"
+	OpenSong   Format = "opensong"
+)
+
+// Formats are the names of all formats.
+var Formats = []string{string(OpenLyrics), string(OpenSong)}
+
+// ErrUnknownFormat is returned for files that are neither OpenLyrics nor
+// OpenSong songs.
+var ErrUnknownFormat = errors.New("not an OpenLyrics or OpenSong song")
+
+const openLyricsNamespace = "http://openlyrics.info/namespace/2009/song"
+
+// Detect returns the format of data from its root element: songs in the
+// OpenLyrics namespace are OpenLyrics, songs without a namespace OpenSong.
+func Detect(data []byte) (Format, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return "", ErrUnknownFormat
+		}
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrUnknownFormat, err)
+		}
+		el, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch {
+		case el.Name.Local == "song" && el.Name.Space == openLyricsNamespace:
+			return OpenLyrics, nil
+		case el.Name.Local == "song" && el.Name.Space == "":
+			return OpenSong, nil
+		}
+		return "", ErrUnknownFormat
+	}
+}
+
+// Unmarshal reads a song in either format.
+func Unmarshal(data []byte) (models.Song, Format, error) {
+	f, err := Detect(data)
+	if err != nil {
+		return models.Song{}, "", err
+	}
+	var s models.Song
+	if f == OpenLyrics {
+		s, err = UnmarshalOpenLyrics(data)
+	} else {
+		s, err = UnmarshalOpenSong(data)
+	}
+	return s, f, err
+}
+
+// Marshal writes s in format f.
+func Marshal(s *models.Song, f Format) ([]byte, error) {
+	switch f {
+	case OpenLyrics:
+		return MarshalOpenLyrics(s), nil
+	case OpenSong:
+		return MarshalOpenSong(s), nil
+	}
+	return nil, fmt.Errorf("songxml: unknown format %q", f)
+}
+
+// The letters that name the kinds of sections in both formats, e.g. "v1"
+// for the first verse. "o" is "other" in OpenLyrics.
+var (
+	kindLetters = map[string]string{
+		models.SectionIntro:        "i",
+		models.SectionVerse:        "v",
+		models.SectionPreChorus:    "p",
+		models.SectionChorus:       "c",
+		models.SectionBridge:       "b",
+		models.SectionInstrumental: "o",
+		models.SectionOutro:        "e",
+		models.SectionTag:          "t",
+	}
+	letterKinds = map[byte]string{}
+)
+
+func init() {
+	for kind, letter := range kindLetters {
+		letterKinds[letter[0]] = kind
+	}
+}
+
+// kindOf returns the kind of the section named name, e.g. "chorus" for
+// "C2". Unknown names are verses.
+func kindOf(name string) string {
+	if name == "" {
+		return models.SectionVerse
+	}
+	if kind, ok := letterKinds[strings.ToLower(name)[0]]; ok {
+		return kind
+	}
+	return models.SectionVerse
+}
+
+// part is a section to export with the name it gets in the file.
+type part struct {
+	name    string // e.g. "v1"
+	section models.Section
+}
+
+// parts returns the sections of s named by kind, e.g. "v1", "v2" and "c1",
+// and the names in the order the sections are played. Songs without
+// sections are split into blocks, see sheet.Blocks, which are played in
+// their order.
+func parts(s *models.Song) ([]part, []string) {
+	sections := s.Sections
+	if len(sections) == 0 {
+		for _, b := range sheet.Blocks(s) {
+			sections = append(sections, models.Section{Kind: sheet.SectionKind(b.Title), Lines: b.Lines})
+		}
+	}
+
+	counts := map[string]int{}
+	names := map[string]string{}
+	ps := make([]part, len(sections))
+	for i, sec := range sections {
+		letter := kindLetters[sec.Kind]
+		if letter == "" {
+			letter = "o"
+		}
+		counts[letter]++
+		ps[i] = part{name: fmt.Sprintf("%s%d", letter, counts[letter]), section: sec}
+		names[sec.Label] = ps[i].name
+	}
+
+	if len(s.Sections) == 0 {
+		return ps, nil
+	}
+	var order []string
+	for _, label := range s.ArrangementLabels() {
+		order = append(order, names[label])
+	}
+	return ps, order
+}
+
+// maxLabelChars is the length of section labels, see models.Section.
+const maxLabelChars = 10
+
+// labeler turns the names of sections in files into unique labels.
+type labeler map[string]string
+
+func (l labeler) label(name string) string {
+	if label, ok := l[name]; ok {
+		return label
+	}
+	label := strings.ToUpper(strings.Join(strings.Fields(name), ""))
+	if label == "" {
+		label = "V"
+	}
+	if r := []rune(label); len(r) > maxLabelChars {
+		label = string(r[:maxLabelChars])
+	}
+	for taken, n := l.taken(label), 2; taken; n++ {
+		suffix := fmt.Sprintf("-%d", n)
+		base := []rune(label)
+		if len(base)+len(suffix) > maxLabelChars {
+			base = base[:maxLabelChars-len(suffix)]
+		}
+		candidate := string(base) + suffix
+		if taken = l.taken(candidate); !taken {
+			label = candidate
+		}
+	}
+	l[name] = label
+	return label
+}
+
+func (l labeler) taken(label string) bool {
+	for _, used := range l {
+		if used == label {
+			return true
+		}
+	}
+	return false
+}
+
+// tagsOf turns themes into tags, see models.Song.Tags.
+func tagsOf(themes []string) []string {
+	var tags []string
+	seen := map[string]bool{}
+	for _, theme := range themes {
+		tag := slug.Make(theme)
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// escaper escapes text for XML text and attributes. Unlike xml.EscapeText
+// it keeps line breaks, for the lyrics of OpenSong.
+var escaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;")
+
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+// sectionBuilder collects the sections of an imported song.
+type sectionBuilder struct {
+	labels   labeler
+	sections []models.Section
+	current  int
+}
+
+func newSectionBuilder() *sectionBuilder {
+	return &sectionBuilder{labels: labeler{}, current: -1}
+}
+
+// start continues the section named name, e.g. "v1" or "C", creating it
+// unless it exists.
+func (b *sectionBuilder) start(name string) {
+	label := b.labels.label(strings.ToLower(name))
+	for i := range b.sections {
+		if b.sections[i].Label == label {
+			b.current = i
+			return
+		}
+	}
+	b.sections = append(b.sections, models.Section{Label: label, Kind: kindOf(name)})
+	b.current = len(b.sections) - 1
+}
+
+// add adds l to the current section, or to a first verse.
+func (b *sectionBuilder) add(l models.Line) {
+	if b.current < 0 {
+		b.start("v1")
+	}
+	b.sections[b.current].Lines = append(b.sections[b.current].Lines, l)
+}
+
+// finish returns the sections that have lines and the arrangement of the
+// names in order. Names of unknown sections are dropped.
+func (b *sectionBuilder) finish(order []string) ([]models.Section, string) {
+	var sections []models.Section
+	kept := map[string]bool{}
+	for _, sec := range b.sections {
+		if len(sec.Lines) > 0 {
+			sections = append(sections, sec)
+			kept[sec.Label] = true
+		}
+	}
+	var labels []string
+	for _, name := range order {
+		if label, ok := b.labels[strings.ToLower(name)]; ok && kept[label] {
+			labels = append(labels, label)
+		}
+	}
+	return sections, strings.Join(labels, " ")
+}
@@ -0,0 +1,208 @@
+package songxml
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/davidkuda/lyricsapi/models"
+)
+
+func testSong() models.Song {
+	return models.Song{
+		Name:        "Amazing Grace",
+		Artist:      "John Newton",
+		Language:    "en",
+		Key:         "G",
+		Tags:        []string{"grace", "hymn"},
+		Copyright:   "Public Domain",
+		CCLI:        "22025",
+		Arrangement: "V1 C V2 C",
+		Sections: []models.Section{
+			{Label: "V1", Kind: models.SectionVerse, Lines: []models.Line{
+				{Lyrics: "Amazing grace, how sweet the sound", Chords: []models.ChordPosition{{Chord: "G", Position: 0}, {Chord: "C", Position: 15}, {Chord: "D7", Position: 34}}},
+				{Lyrics: "That saved a wretch like me & you"},
+			}},
+			{Label: "C", Kind: models.SectionChorus, Lines: []models.Line{
+				{Chords: []models.ChordPosition{{Chord: "G", Position: 0}, {Chord: "D", Position: 2}}},
+				{Lyrics: "I once was lost", Chords: []models.ChordPosition{{Chord: "Em", Position: 2}}},
+			}},
+			{Label: "V2", Kind: models.SectionVerse, Lines: []models.Line{
+				{Lyrics: "'Twas grace that taught my heart to fear", Chords: []models.ChordPosition{{Chord: "G/B", Position: 6}}},
+			}},
+		},
+		UpdatedAt: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+// The labels of sections are renamed by their kind.
+func wantSections(s models.Song) []models.Section {
+	want := append([]models.Section(nil), s.Sections...)
+	want[1].Label = "C1"
+	return want
+}
+
+func TestOpenLyricsRoundTrip(t *testing.T) {
+	s := testSong()
+	data, err := Marshal(&s, OpenLyrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<song xmlns="http://openlyrics.info/namespace/2009/song" version="0.9"`,
+		`modifiedDate="2024-03-01T12:00:00Z"`,
+		"<ccliNo>22025</ccliNo>",
+		"<verseOrder>v1 c1 v2 c1</verseOrder>",
+		`<chord name="G"/>Amazing grace, <chord name="C"/>how sweet the sound<chord name="D7"/><br/>`,
+		"like me &amp; you",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("OpenLyrics does not contain %q:\n%s", want, data)
+		}
+	}
+
+	got, f, err := Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f != OpenLyrics {
+		t.Errorf("format = %q, want %q", f, OpenLyrics)
+	}
+	if got.Name != s.Name || got.Artist != s.Artist || got.Copyright != s.Copyright || got.CCLI != s.CCLI || got.Key != s.Key || got.Language != s.Language {
+		t.Errorf("properties = %+v, want those of %+v", got, s)
+	}
+	if !reflect.DeepEqual(got.Tags, s.Tags) {
+		t.Errorf("tags = %v, want %v", got.Tags, s.Tags)
+	}
+	if got.Arrangement != "V1 C1 V2 C1" {
+		t.Errorf("arrangement = %q, want %q", got.Arrangement, "V1 C1 V2 C1")
+	}
+	want := wantSections(s)
+	// chords without lyrics between them are moved together
+	want[1].Lines[0].Chords = []models.ChordPosition{{Chord: "G", Position: 0}, {Chord: "D", Position: 1}}
+	if !reflect.DeepEqual(got.Sections, want) {
+		t.Errorf("sections =\n%+v\nwant\n%+v", got.Sections, want)
+	}
+}
+
+func TestOpenSongRoundTrip(t *testing.T) {
+	s := testSong()
+	data, err := Marshal(&s, OpenSong)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"<ccli>22025</ccli>",
+		"<presentation>V1 C1 V2 C1</presentation>",
+		"<theme>grace; hymn</theme>",
+		"[V1]\n.G              C                  D7\n Amazing grace, how sweet the sound\n",
+		"[C1]\n.G D\n.  Em\n I once was lost\n",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("OpenSong does not contain %q:\n%s", want, data)
+		}
+	}
+
+	got, f, err := Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f != OpenSong {
+		t.Errorf("format = %q, want %q", f, OpenSong)
+	}
+	if got.Name != s.Name || got.Artist != s.Artist || got.CCLI != s.CCLI || got.Key != s.Key {
+		t.Errorf("properties = %+v, want those of %+v", got, s)
+	}
+	if got.Arrangement != "V1 C1 V2 C1" {
+		t.Errorf("arrangement = %q, want %q", got.Arrangement, "V1 C1 V2 C1")
+	}
+	if want := wantSections(s); !reflect.DeepEqual(got.Sections, want) {
+		t.Errorf("sections =\n%+v\nwant\n%+v", got.Sections, want)
+	}
+}
+
+func TestUnmarshalOpenLyrics(t *testing.T) {
+	data := `<?xml version="1.0" encoding="UTF-8"?>
+<song xmlns="http://openlyrics.info/namespace/2009/song" version="0.8">
+  <properties>
+    <titles><title lang="de">Großer Gott</title><title lang="en" original="true">Holy God</title></titles>
+    <authors><author type="words">Ignaz Franz</author><author type="translation" lang="en">Clarence Walworth</author></authors>
+    <key>H</key>
+  </properties>
+  <lyrics>
+    <verse name="v1" lang="de-CH">
+      <lines>
+        <line><chord root="C" structure="min7" bass="G"/>Großer   Gott, <comment>leise</comment>wir <tag name="it">loben</tag> dich</line>
+        <line><chord name="F"/><chord name="G"/></line>
+      </lines>
+    </verse>
+    <verse name="v1" lang="en"><lines>Holy God</lines></verse>
+  </lyrics>
+</song>`
+	s, _, err := Unmarshal([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Name != "Holy God" || s.Artist != "Ignaz Franz" || s.Language != "de" || s.Key != "" {
+		t.Errorf("properties = %+v", s)
+	}
+	want := []models.Section{{Label: "V1", Kind: models.SectionVerse, Lines: []models.Line{
+		{Lyrics: "Großer Gott, wir loben dich", Chords: []models.ChordPosition{{Chord: "Cm7/G", Position: 0}}},
+		{Chords: []models.ChordPosition{{Chord: "F", Position: 0}, {Chord: "G", Position: 1}}},
+	}}}
+	if !reflect.DeepEqual(s.Sections, want) {
+		t.Errorf("sections =\n%+v\nwant\n%+v", s.Sections, want)
+	}
+}
+
+func TestUnmarshalOpenSong(t *testing.T) {
+	data := `<song>
+  <title>Amazing Grace</title>
+  <presentation>V1 C V2 X</presentation>
+  <lyrics>[V]
+. G       C
+1 Amazing grace
+2 'Twas grace
+;a comment
+[C]
+ I once was lost</lyrics>
+</song>`
+	s, _, err := Unmarshal([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.Section{
+		{Label: "V1", Kind: models.SectionVerse, Lines: []models.Line{{Lyrics: "Amazing grace", Chords: []models.ChordPosition{{Chord: "G", Position: 0}, {Chord: "C", Position: 8}}}}},
+		{Label: "V2", Kind: models.SectionVerse, Lines: []models.Line{{Lyrics: "'Twas grace", Chords: []models.ChordPosition{{Chord: "G", Position: 0}, {Chord: "C", Position: 8}}}}},
+		{Label: "C", Kind: models.SectionChorus, Lines: []models.Line{{Lyrics: "I once was lost"}}},
+	}
+	if !reflect.DeepEqual(s.Sections, want) {
+		t.Errorf("sections =\n%+v\nwant\n%+v", s.Sections, want)
+	}
+	if s.Arrangement != "V1 C V2" {
+		t.Errorf("arrangement = %q, want %q", s.Arrangement, "V1 C V2")
+	}
+}
+
+func TestDetect(t *testing.T) {
+	for _, data := range []string{"", "not xml", `<html></html>`, `<song xmlns="urn:other"></song>`} {
+		if _, err := Detect([]byte(data)); err != ErrUnknownFormat && !strings.HasPrefix(err.Error(), ErrUnknownFormat.Error()) {
+			t.Errorf("Detect(%q) = %v, want ErrUnknownFormat", data, err)
+		}
+	}
+}
+
+func TestMarshalWithoutSections(t *testing.T) {
+	s := models.Song{Name: "Start Me Up", Text: "[Chorus]\n[C]If you start me [F]up"}
+	data, err := Marshal(&s, OpenLyrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<verse name="c1">`; !strings.Contains(string(data), want) {
+		t.Errorf("OpenLyrics does not contain %q:\n%s", want, data)
+	}
+	if strings.Contains(string(data), "verseOrder") {
+		t.Errorf("OpenLyrics has a verse order:\n%s", data)
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/davidkuda/lyricsapi/dbio"
+	"github.com/davidkuda/lyricsapi/handlers"
+)
+
+// purgeTrash permanently deletes the songs that have been in the trash for
+// longer than retention. It runs once at start and then every interval.
+func purgeTrash(app *handlers.Application, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := dbio.PurgeDeletedSongs(context.Background(), time.Now().Add(-retention), app.DB)
+		if err != nil {
+			app.Logger.Printf("purge trash: %v", err)
+		} else if n > 0 {
+			app.Logger.Printf("purge trash: deleted %d songs", n)
+		}
+		<-ticker.C
+	}
+}
@@ -0,0 +1,95 @@
+// Package validator collects validation errors, so that every invalid field
+// of a request can be reported at once instead of failing on the first.
+//
+// Rules are declared with Check, e.g.:
+//
+//	v := validator.New()
+//	v.Check(validator.NotBlank(s.Name), "name", "must be provided")
+//	v.Check(validator.MaxChars(s.Name, 200), "name", "must not be more than 200 characters long")
+//	if !v.Valid() {
+//		// report v.Errors
+//	}
+package validator
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// SlugRX matches lower case words of letters and digits joined by single
+// hyphens, e.g. "wish-you-were-here".
+var SlugRX = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+type Validator struct {
+	// Errors maps the name of a field to the first problem found with it.
+	Errors map[string]string
+}
+
+func New() *Validator {
+	return &Validator{Errors: make(map[string]string)}
+}
+
+// Valid returns true if no errors have been added.
+func (v *Validator) Valid() bool {
+	return len(v.Errors) == 0
+}
+
+// AddError adds an error for key, unless key already has one.
+func (v *Validator) AddError(key, message string) {
+	if _, exists := v.Errors[key]; !exists {
+		v.Errors[key] = message
+	}
+}
+
+// Check adds an error for key if ok is false.
+func (v *Validator) Check(ok bool, key, message string) {
+	if !ok {
+		v.AddError(key, message)
+	}
+}
+
+// NotBlank returns true if value contains more than whitespace.
+func NotBlank(value string) bool {
+	return strings.TrimSpace(value) != ""
+}
+
+// MaxChars returns true if value has at most n characters (runes).
+func MaxChars(value string, n int) bool {
+	return utf8.RuneCountInString(value) <= n
+}
+
+// MinChars returns true if value has at least n characters (runes).
+func MinChars(value string, n int) bool {
+	return utf8.RuneCountInString(value) >= n
+}
+
+// MaxBytes returns true if value is at most n bytes long.
+func MaxBytes(value string, n int) bool {
+	return len(value) <= n
+}
+
+// Matches returns true if value matches rx.
+func Matches(value string, rx *regexp.Regexp) bool {
+	return rx.MatchString(value)
+}
+
+// PermittedValue returns true if value is one of permitted.
+func PermittedValue(value string, permitted ...string) bool {
+	for _, p := range permitted {
+		if value == p {
+			return true
+		}
+	}
+	return false
+}
+
+// IsURL returns true if value is an absolute http or https URL.
+func IsURL(value string) bool {
+	u, err := url.Parse(value)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
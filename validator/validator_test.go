@@ -0,0 +1,53 @@
+package validator
+
+import "testing"
+
+func TestValidatorCollectsAllErrors(t *testing.T) {
+	v := New()
+	v.Check(NotBlank("  "), "name", "must be provided")
+	v.Check(MaxChars("too long", 3), "name", "must not be more than 3 characters long")
+	v.Check(Matches("Not A Slug", SlugRX), "id", "must be a slug")
+	v.Check(IsURL("https://youtu.be/abc"), "covers[0]", "must be a valid URL")
+
+	if v.Valid() {
+		t.Fatal("expected validator to be invalid")
+	}
+	if len(v.Errors) != 2 {
+		t.Errorf("got %d errors, want 2: %v", len(v.Errors), v.Errors)
+	}
+	if v.Errors["name"] != "must be provided" {
+		t.Errorf("name error = %q, want the first error for the field", v.Errors["name"])
+	}
+}
+
+func TestIsURL(t *testing.T) {
+	tests := map[string]bool{
+		"https://www.youtube.com/watch?v=IXdNnw99-Ic": true,
+		"http://example.com":                          true,
+		"ftp://example.com":                           false,
+		"www.youtube.com":                             false,
+		"https://":                                    false,
+		"":                                            false,
+	}
+	for in, want := range tests {
+		if got := IsURL(in); got != want {
+			t.Errorf("IsURL(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestSlugRX(t *testing.T) {
+	tests := map[string]bool{
+		"wish-you-were-here": true,
+		"99-problems":        true,
+		"Wish-You":           false,
+		"wish--you":          false,
+		"-wish":              false,
+		"wish you":           false,
+	}
+	for in, want := range tests {
+		if got := Matches(in, SlugRX); got != want {
+			t.Errorf("Matches(%q, SlugRX) = %v, want %v", in, got, want)
+		}
+	}
+}